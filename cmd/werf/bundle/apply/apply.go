@@ -48,7 +48,7 @@ func NewCmd() *cobra.Command {
 		Long:                  common.GetLongCommandDescription(`Take latest bundle from the specified container registry using specified version tag or version mask and apply it as a helm chart into Kubernetes cluster.`),
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfDebugAnsibleArgs, common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfDebugAnsibleArgs, common.WerfSecretKey, common.WerfSecretKeys, common.WerfSecretIdentity, common.WerfSecretStrictFormat),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			defer global_warnings.PrintGlobalWarnings(common.BackgroundContext())
@@ -152,7 +152,8 @@ func runApply() error {
 			ConfigPath:       *commonCmdData.KubeConfig,
 			ConfigDataBase64: *commonCmdData.KubeConfigBase64,
 		},
-		ReleasesHistoryMax: *commonCmdData.ReleasesHistoryMax,
+		ReleasesHistoryMax:   *commonCmdData.ReleasesHistoryMax,
+		ResourcesWaitTimeout: time.Duration(cmdData.Timeout) * time.Second,
 	}); err != nil {
 		return err
 	}
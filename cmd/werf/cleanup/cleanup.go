@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
 
 	"github.com/werf/logboek"
 
@@ -69,6 +70,7 @@ It is safe to run this command periodically (daily is enough) by automated clean
 
 	common.SetupScanContextNamespaceOnly(&commonCmdData, cmd)
 	common.SetupDryRun(&commonCmdData, cmd)
+	common.SetupReportPath(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 	common.SetupLogProjectDir(&commonCmdData, cmd)
@@ -78,7 +80,10 @@ It is safe to run this command periodically (daily is enough) by automated clean
 	common.SetupKubeConfigBase64(&commonCmdData, cmd)
 	common.SetupKubeContext(&commonCmdData, cmd)
 	common.SetupWithoutKube(&commonCmdData, cmd)
+	common.SetupAllowListOptions(&commonCmdData, cmd)
 	common.SetupKeepStagesBuiltWithinLastNHours(&commonCmdData, cmd)
+	common.SetupMaxRepoSize(&commonCmdData, cmd)
+	common.SetupQuarantineGracePeriodDays(&commonCmdData, cmd)
 
 	return cmd
 }
@@ -219,14 +224,37 @@ func runCleanup() error {
 		return fmt.Errorf("unable to get Kubernetes clusters connections: %s", err)
 	}
 
+	var kubernetesDynamicClientByContext map[string]dynamic.Interface
+	if !*commonCmdData.WithoutKube && len(werfConfig.Meta.Cleanup.KubernetesResources) != 0 {
+		kubernetesDynamicClientByContext, err = common.GetKubernetesDynamicClientByContext(&commonCmdData, kubernetesContextClients)
+		if err != nil {
+			return err
+		}
+	}
+
+	allowListProviders, err := common.GetAllowListProviders(&commonCmdData, stagesStorage)
+	if err != nil {
+		return err
+	}
+
+	maxRepoSize, err := common.GetMaxRepoSize(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	cleanupOptions := cleaning.CleanupOptions{
 		ImageNameList:                           imagesNames,
 		LocalGit:                                &localGitRepo,
 		KubernetesContextClients:                kubernetesContextClients,
 		KubernetesNamespaceRestrictionByContext: common.GetKubernetesNamespaceRestrictionByContext(&commonCmdData, kubernetesContextClients),
+		KubernetesDynamicClientByContext:        kubernetesDynamicClientByContext,
 		WithoutKube:                             *commonCmdData.WithoutKube,
+		AllowListProviders:                      allowListProviders,
 		GitHistoryBasedCleanupOptions:           werfConfig.Meta.Cleanup,
 		KeepStagesBuiltWithinLastNHours:         *commonCmdData.KeepStagesBuiltWithinLastNHours,
+		MaxRepoSize:                             maxRepoSize,
+		QuarantineGracePeriodDays:               *commonCmdData.QuarantineGracePeriodDays,
+		ReportPath:                              *commonCmdData.ReportPath,
 		DryRun:                                  *commonCmdData.DryRun,
 	}
 
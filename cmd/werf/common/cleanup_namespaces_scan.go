@@ -6,6 +6,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/werf/kubedog/pkg/kube"
 	"github.com/werf/logboek"
+	"k8s.io/client-go/dynamic"
 )
 
 func SetupScanContextNamespaceOnly(cmdData *CmdData, cmd *cobra.Command) {
@@ -41,6 +42,28 @@ func GetKubernetesContextClients(cmdData *CmdData) ([]*kube.ContextClient, error
 	return res, nil
 }
 
+// GetKubernetesDynamicClientByContext returns dynamic clients to scan the custom resources configured in werf.yaml
+func GetKubernetesDynamicClientByContext(cmdData *CmdData, contextClients []*kube.ContextClient) (map[string]dynamic.Interface, error) {
+	res := map[string]dynamic.Interface{}
+	for _, contextClient := range contextClients {
+		kubeConfig, err := kube.GetKubeConfig(kube.KubeConfigOptions{Context: contextClient.ContextName, ConfigPath: *cmdData.KubeConfig})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get kube config for context %q: %s", contextClient.ContextName, err)
+		} else if kubeConfig == nil {
+			return nil, fmt.Errorf("unable to get kube config for context %q", contextClient.ContextName)
+		}
+
+		dynamicClient, err := dynamic.NewForConfig(kubeConfig.Config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create dynamic client for context %q: %s", contextClient.ContextName, err)
+		}
+
+		res[contextClient.ContextName] = dynamicClient
+	}
+
+	return res, nil
+}
+
 func GetKubernetesNamespaceRestrictionByContext(cmdData *CmdData, contextClients []*kube.ContextClient) map[string]string {
 	res := map[string]string{}
 	for _, contextClient := range contextClients {
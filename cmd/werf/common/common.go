@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/spf13/cobra"
 
 	"github.com/werf/logboek"
@@ -18,8 +19,11 @@ import (
 
 	"github.com/werf/werf/pkg/build"
 	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/cleaning/allow_list"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/deploy/secret_provider"
 	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/giterminism"
@@ -60,6 +64,9 @@ type CmdData struct {
 	SecretValues    *[]string
 	IgnoreSecretKey *bool
 
+	SecretValueProvider     *string
+	SecretValueProviderFile *string
+
 	CommonRepoData         *RepoData
 	StagesStorage          *string
 	SecondaryStagesStorage *[]string
@@ -74,9 +81,21 @@ type CmdData struct {
 	DockerConfig                    *string
 	InsecureRegistry                *bool
 	SkipTlsVerifyRegistry           *bool
+	RegistryRateLimit               *int64
+	RegistryMaxRetries              *int64
+	RegistryCredentialsFile         *string
+	RepoLayerCompression            *string
 	DryRun                          *bool
 	KeepStagesBuiltWithinLastNHours *uint64
+	MaxRepoSize                     *string
+	AllowedDockerStorageVolumeUsage *string
+	GitCacheMaxAge                  *string
+	GitCacheMaxSize                 *string
+	QuarantineGracePeriodDays       *uint64
 	WithoutKube                     *bool
+	AllowListFiles                  *[]string
+	AllowListURLs                   *[]string
+	AllowListBundles                *bool
 
 	LooseGiterminism               *bool
 	NonStrictGiterminismInspection *bool
@@ -103,6 +122,8 @@ type CmdData struct {
 	VirtualMergeFromCommit *string
 	VirtualMergeIntoCommit *string
 
+	RegistryMirrors *[]string
+
 	ScanContextNamespaceOnly *bool
 
 	Tag *string
@@ -223,6 +244,46 @@ func SetupWithoutKube(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.WithoutKube, "without-kube", "", GetBoolEnvironmentDefaultFalse("WERF_WITHOUT_KUBE"), "Do not skip deployed Kubernetes images (default $WERF_WITHOUT_KUBE)")
 }
 
+func SetupAllowListOptions(cmdData *CmdData, cmd *cobra.Command) {
+	allowListFiles := predefinedValuesByEnvNamePrefix("WERF_ALLOW_LIST_FILE")
+	cmdData.AllowListFiles = &allowListFiles
+	cmd.Flags().StringArrayVarP(cmdData.AllowListFiles, "allow-list-file", "", allowListFiles, `Do not delete images listed in the file: one image reference per line, empty lines and lines starting with # are ignored.
+Can be specified multiple times and with $WERF_ALLOW_LIST_FILE* (e.g. $WERF_ALLOW_LIST_FILE_NOMAD=nomad-images.txt)`)
+
+	allowListURLs := predefinedValuesByEnvNamePrefix("WERF_ALLOW_LIST_URL")
+	cmdData.AllowListURLs = &allowListURLs
+	cmd.Flags().StringArrayVarP(cmdData.AllowListURLs, "allow-list-url", "", allowListURLs, `Do not delete images returned by the HTTP endpoint as JSON array of image references.
+Can be specified multiple times and with $WERF_ALLOW_LIST_URL* (e.g. $WERF_ALLOW_LIST_URL_ECS=https://inventory.example.com/ecs/images)`)
+
+	cmdData.AllowListBundles = new(bool)
+	cmd.Flags().BoolVarP(cmdData.AllowListBundles, "allow-list-bundles", "", GetBoolEnvironmentDefaultFalse("WERF_ALLOW_LIST_BUNDLES"), "Do not delete images referenced by werf bundles published into the repo (default $WERF_ALLOW_LIST_BUNDLES)")
+}
+
+func GetAllowListProviders(cmdData *CmdData, stagesStorage storage.StagesStorage) ([]allow_list.Provider, error) {
+	var providers []allow_list.Provider
+	for _, path := range *cmdData.AllowListFiles {
+		providers = append(providers, &allow_list.FileProvider{Path: path})
+	}
+
+	for _, url := range *cmdData.AllowListURLs {
+		providers = append(providers, &allow_list.HTTPProvider{URL: url})
+	}
+
+	if *cmdData.AllowListBundles {
+		repoStagesStorage, ok := stagesStorage.(*storage.RepoStagesStorage)
+		if !ok {
+			return nil, fmt.Errorf("--allow-list-bundles is not supported for the stages storage %s: specify --repo", stagesStorage.String())
+		}
+
+		providers = append(providers, &allow_list.BundlesProvider{
+			DockerRegistry: repoStagesStorage.DockerRegistry,
+			RepoAddress:    repoStagesStorage.RepoAddress,
+		})
+	}
+
+	return providers, nil
+}
+
 func SetupKeepStagesBuiltWithinLastNHours(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.KeepStagesBuiltWithinLastNHours = new(uint64)
 
@@ -241,6 +302,95 @@ func SetupKeepStagesBuiltWithinLastNHours(cmdData *CmdData, cmd *cobra.Command)
 	cmd.Flags().Uint64VarP(cmdData.KeepStagesBuiltWithinLastNHours, "keep-stages-built-within-last-n-hours", "", defaultValue, "Keep stages that were built within last hours (default $WERF_KEEP_STAGES_BUILT_WITHIN_LAST_N_HOURS or 2)")
 }
 
+func SetupQuarantineGracePeriodDays(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.QuarantineGracePeriodDays = new(uint64)
+
+	envValue, err := getUint64EnvVar("WERF_QUARANTINE_GRACE_PERIOD_DAYS")
+	if err != nil {
+		TerminateWithError(err.Error(), 1)
+	}
+
+	var defaultValue uint64
+	if envValue != nil {
+		defaultValue = *envValue
+	}
+
+	cmd.Flags().Uint64VarP(cmdData.QuarantineGracePeriodDays, "quarantine-grace-period-days", "", defaultValue, `Move unused stages into the quarantine (quarantine-<date>-<stageID> tags) instead of deletion and delete them after the specified number of days on a later cleanup run.
+Quarantined stages can be restored with werf stage restore command. Quarantine is disabled if 0 (default $WERF_QUARANTINE_GRACE_PERIOD_DAYS or 0)`)
+}
+
+func SetupMaxRepoSize(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.MaxRepoSize = new(string)
+	cmd.Flags().StringVarP(cmdData.MaxRepoSize, "max-repo-size", "", os.Getenv("WERF_MAX_REPO_SIZE"), `Keep the repo under the size budget (e.g. 200GiB): stages that are not protected by cleanup policies and Kubernetes are deleted from the oldest only until the budget is met.
+The repo size is the size of the unique stages layers, the layers shared by the stages are counted once (default $WERF_MAX_REPO_SIZE)`)
+}
+
+func GetMaxRepoSize(cmdData *CmdData) (int64, error) {
+	if *cmdData.MaxRepoSize == "" {
+		return 0, nil
+	}
+
+	maxRepoSize, err := units.RAMInBytes(*cmdData.MaxRepoSize)
+	if err != nil {
+		return 0, fmt.Errorf("bad --max-repo-size value %q: %s", *cmdData.MaxRepoSize, err)
+	}
+
+	return maxRepoSize, nil
+}
+
+func SetupAllowedDockerStorageVolumeUsage(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.AllowedDockerStorageVolumeUsage = new(string)
+	cmd.Flags().StringVarP(cmdData.AllowedDockerStorageVolumeUsage, "allowed-docker-storage-volume-usage", "", os.Getenv("WERF_ALLOWED_DOCKER_STORAGE_VOLUME_USAGE"), `Keep the usage of the volume with docker storage under the percentage (e.g. 70%): local stages are deleted in the least recently used order until the usage is below the limit.
+Docker server should be running on the same host (default $WERF_ALLOWED_DOCKER_STORAGE_VOLUME_USAGE)`)
+}
+
+func GetAllowedDockerStorageVolumeUsagePercentage(cmdData *CmdData) (*float64, error) {
+	if *cmdData.AllowedDockerStorageVolumeUsage == "" {
+		return nil, nil
+	}
+
+	percentage, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(*cmdData.AllowedDockerStorageVolumeUsage), "%"), 64)
+	if err != nil || percentage < 0 || percentage > 100 {
+		return nil, fmt.Errorf("bad --allowed-docker-storage-volume-usage value %q: expected percentage from 0%% to 100%%", *cmdData.AllowedDockerStorageVolumeUsage)
+	}
+
+	return &percentage, nil
+}
+
+func SetupGitCacheLimits(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.GitCacheMaxAge = new(string)
+	cmd.Flags().StringVarP(cmdData.GitCacheMaxAge, "git-cache-max-age", "", os.Getenv("WERF_GIT_CACHE_MAX_AGE"), "Remove remote git clones and git worktree caches that were not accessed within the duration (e.g. 336h, default $WERF_GIT_CACHE_MAX_AGE)")
+
+	cmdData.GitCacheMaxSize = new(string)
+	cmd.Flags().StringVarP(cmdData.GitCacheMaxSize, "git-cache-max-size", "", os.Getenv("WERF_GIT_CACHE_MAX_SIZE"), "Remove least recently accessed remote git clones and git worktree caches until the total size fits the limit (e.g. 50GiB, default $WERF_GIT_CACHE_MAX_SIZE)")
+}
+
+func GetGitCacheMaxAge(cmdData *CmdData) (time.Duration, error) {
+	if *cmdData.GitCacheMaxAge == "" {
+		return 0, nil
+	}
+
+	maxAge, err := time.ParseDuration(*cmdData.GitCacheMaxAge)
+	if err != nil {
+		return 0, fmt.Errorf("bad --git-cache-max-age value %q: %s", *cmdData.GitCacheMaxAge, err)
+	}
+
+	return maxAge, nil
+}
+
+func GetGitCacheMaxSize(cmdData *CmdData) (int64, error) {
+	if *cmdData.GitCacheMaxSize == "" {
+		return 0, nil
+	}
+
+	maxSize, err := units.RAMInBytes(*cmdData.GitCacheMaxSize)
+	if err != nil {
+		return 0, fmt.Errorf("bad --git-cache-max-size value %q: %s", *cmdData.GitCacheMaxSize, err)
+	}
+
+	return maxSize, nil
+}
+
 func predefinedValuesByEnvNamePrefix(envNamePrefix string, envNamePrefixesToExcept ...string) []string {
 	var result []string
 
@@ -323,12 +473,17 @@ func SetupCommonRepoData(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.CommonRepoData = &RepoData{IsCommon: true}
 
 	SetupImplementationForRepoData(cmdData.CommonRepoData, cmd, "repo-implementation", []string{"WERF_REPO_IMPLEMENTATION"})
+	SetupArtifactoryUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-artifactory-username", []string{"WERF_REPO_ARTIFACTORY_USERNAME"})
+	SetupArtifactoryPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-artifactory-password", []string{"WERF_REPO_ARTIFACTORY_PASSWORD"})
 	SetupDockerHubUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-docker-hub-username", []string{"WERF_REPO_DOCKER_HUB_USERNAME"})
 	SetupDockerHubPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-docker-hub-password", []string{"WERF_REPO_DOCKER_HUB_PASSWORD"})
 	SetupDockerHubTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-docker-hub-token", []string{"WERF_REPO_DOCKER_HUB_TOKEN"})
 	SetupGithubTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-github-token", []string{"WERF_REPO_GITHUB_TOKEN"})
 	SetupHarborUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-harbor-username", []string{"WERF_REPO_HARBOR_USERNAME"})
 	SetupHarborPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-harbor-password", []string{"WERF_REPO_HARBOR_PASSWORD"})
+	SetupNexusUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-nexus-username", []string{"WERF_REPO_NEXUS_USERNAME"})
+	SetupNexusPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-nexus-password", []string{"WERF_REPO_NEXUS_PASSWORD"})
+	SetupNexusRepositoryForRepoData(cmdData.CommonRepoData, cmd, "repo-nexus-repository", []string{"WERF_REPO_NEXUS_REPOSITORY"})
 	SetupQuayTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-quay-token", []string{"WERF_REPO_QUAY_TOKEN"})
 }
 
@@ -341,8 +496,11 @@ func SetupSecondaryStagesStorageOptions(cmdData *CmdData, cmd *cobra.Command) {
 func SetupStagesStorageOptions(cmdData *CmdData, cmd *cobra.Command) {
 	SetupInsecureRegistry(cmdData, cmd)
 	SetupSkipTlsVerifyRegistry(cmdData, cmd)
+	SetupRegistryRequestsPolicy(cmdData, cmd)
+	SetupRegistryCredentialsFile(cmdData, cmd)
 	SetupCommonRepoData(cmdData, cmd)
 	setupStagesStorage(cmdData, cmd)
+	SetupRepoLayerCompression(cmdData, cmd)
 }
 
 func setupStagesStorage(cmdData *CmdData, cmd *cobra.Command) {
@@ -441,6 +599,62 @@ func SetupInsecureRegistry(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.InsecureRegistry, "insecure-registry", "", GetBoolEnvironmentDefaultFalse("WERF_INSECURE_REGISTRY"), "Use plain HTTP requests when accessing a registry (default $WERF_INSECURE_REGISTRY)")
 }
 
+func SetupRegistryRequestsPolicy(cmdData *CmdData, cmd *cobra.Command) {
+	if cmdData.RegistryRateLimit != nil {
+		return
+	}
+
+	rateLimitEnvValue, err := getInt64EnvVar("WERF_REGISTRY_RATE_LIMIT")
+	if err != nil {
+		TerminateWithError(err.Error(), 1)
+	}
+
+	var rateLimitDefaultValue int64
+	if rateLimitEnvValue != nil {
+		rateLimitDefaultValue = *rateLimitEnvValue
+	}
+
+	maxRetriesEnvValue, err := getInt64EnvVar("WERF_REGISTRY_MAX_RETRIES")
+	if err != nil {
+		TerminateWithError(err.Error(), 1)
+	}
+
+	maxRetriesDefaultValue := int64(docker_registry.DefaultRequestsMaxRetries)
+	if maxRetriesEnvValue != nil {
+		maxRetriesDefaultValue = *maxRetriesEnvValue
+	}
+
+	cmdData.RegistryRateLimit = new(int64)
+	cmd.Flags().Int64VarP(cmdData.RegistryRateLimit, "registry-rate-limit", "", rateLimitDefaultValue, "Limit requests per second to each registry host, 0 to remove the limitation (default $WERF_REGISTRY_RATE_LIMIT or 0)")
+
+	cmdData.RegistryMaxRetries = new(int64)
+	cmd.Flags().Int64VarP(cmdData.RegistryMaxRetries, "registry-max-retries", "", maxRetriesDefaultValue, fmt.Sprintf("Retry idempotent registry requests (GET, HEAD and PUT of blobs and manifests) failed with 429, 5xx codes or temporary network errors with exponential backoff, honouring Retry-After header up to 1 minute (default $WERF_REGISTRY_MAX_RETRIES or %d)", docker_registry.DefaultRequestsMaxRetries))
+}
+
+func SetupRegistryCredentialsFile(cmdData *CmdData, cmd *cobra.Command) {
+	if cmdData.RegistryCredentialsFile != nil {
+		return
+	}
+
+	cmdData.RegistryCredentialsFile = new(string)
+	cmd.Flags().StringVarP(cmdData.RegistryCredentialsFile, "registry-credentials-file", "", os.Getenv("WERF_REGISTRY_CREDENTIALS_FILE"), `YAML file with credentials by registry host (username, password, registryToken, identityToken and token fields).
+The file takes precedence over $WERF_REGISTRY_AUTH_<HOST>_{USERNAME,PASSWORD,REGISTRY_TOKEN,TOKEN} variables and docker config with credential helpers,
+ECR and GCR tokens are obtained automatically if there are no credentials for the registry (default $WERF_REGISTRY_CREDENTIALS_FILE)`)
+}
+
+func SetupRepoLayerCompression(cmdData *CmdData, cmd *cobra.Command) {
+	if cmdData.RepoLayerCompression != nil {
+		return
+	}
+
+	cmdData.RepoLayerCompression = new(string)
+	cmd.Flags().StringVarP(cmdData.RepoLayerCompression, "repo-layer-compression", "", os.Getenv("WERF_REPO_LAYER_COMPRESSION"), `Compression of the stages layers pushed to the repo: gzip, zstd or estargz.
+zstd and estargz images are pushed with OCI media types, werf falls back to gzip if the registry rejects them.
+estargz layers are compatible with gzip and can be lazily pulled with stargz snapshotter, zstd layers require docker 23.0+ to pull.
+estargz changes the layers content, so diff ids and image id of the pushed stage differ from the local image.
+Recompressed layers are cached in ~/.werf/local_cache and removed after a week without use (default $WERF_REPO_LAYER_COMPRESSION or gzip)`)
+}
+
 func SetupSkipTlsVerifyRegistry(cmdData *CmdData, cmd *cobra.Command) {
 	if cmdData.SkipTlsVerifyRegistry != nil {
 		return
@@ -669,6 +883,36 @@ func SetupSecretValues(cmdData *CmdData, cmd *cobra.Command) {
 Also, can be defined with $WERF_SECRET_VALUES* (e.g. $WERF_SECRET_VALUES_ENV=.helm/secret_values_test.yaml, $WERF_SECRET_VALUES=.helm/secret_values_db.yaml)`)
 }
 
+func SetupSecretValueProvider(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.SecretValueProvider = new(string)
+	cmd.Flags().StringVarP(cmdData.SecretValueProvider, "secret-value-provider", "", os.Getenv("WERF_SECRET_VALUE_PROVIDER"), fmt.Sprintf(`Use specified provider to resolve werf_secret_value template function references: %s, %s or %s (default $WERF_SECRET_VALUE_PROVIDER).
+The vault provider uses $VAULT_ADDR, $VAULT_TOKEN and $VAULT_NAMESPACE to access the Vault server`, secret_provider.VaultProviderName, secret_provider.FileProviderName, secret_provider.EnvProviderName))
+
+	cmdData.SecretValueProviderFile = new(string)
+	cmd.Flags().StringVarP(cmdData.SecretValueProviderFile, "secret-value-provider-file", "", os.Getenv("WERF_SECRET_VALUE_PROVIDER_FILE"), "Use specified secret values file encrypted with the werf secret key for the file secret value provider (default $WERF_SECRET_VALUE_PROVIDER_FILE)")
+}
+
+// GetSecretValueProvider returns nil when the provider is not specified.
+func GetSecretValueProvider(cmdData *CmdData, projectDir, env string) (secret_provider.Provider, error) {
+	switch *cmdData.SecretValueProvider {
+	case "":
+		return nil, nil
+	case secret_provider.VaultProviderName:
+		return secret_provider.NewVaultProvider(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"), os.Getenv("VAULT_NAMESPACE"))
+	case secret_provider.FileProviderName:
+		m, err := secret.GetEnvManager(projectDir, env)
+		if err != nil {
+			return nil, err
+		}
+
+		return secret_provider.NewFileProvider(*cmdData.SecretValueProviderFile, m)
+	case secret_provider.EnvProviderName:
+		return secret_provider.NewEnvProvider(), nil
+	default:
+		return nil, fmt.Errorf("bad --secret-value-provider=%q: expected %s, %s or %s", *cmdData.SecretValueProvider, secret_provider.VaultProviderName, secret_provider.FileProviderName, secret_provider.EnvProviderName)
+	}
+}
+
 func SetupIgnoreSecretKey(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.IgnoreSecretKey = new(bool)
 	cmd.Flags().BoolVarP(cmdData.IgnoreSecretKey, "ignore-secret-key", "", GetBoolEnvironmentDefaultFalse("WERF_IGNORE_SECRET_KEY"), "Disable secrets decryption (default $WERF_IGNORE_SECRET_KEY)")
@@ -842,21 +1086,35 @@ func GetStagesStorage(stagesStorageAddress string, containerRuntime container_ru
 		return nil, err
 	}
 
+	var layerCompression docker_registry.LayerCompression
+	if cmdData.RepoLayerCompression != nil {
+		var err error
+		if layerCompression, err = docker_registry.ParseLayerCompression(*cmdData.RepoLayerCompression); err != nil {
+			return nil, fmt.Errorf("bad --repo-layer-compression: %s", err)
+		}
+	}
+
 	return storage.NewStagesStorage(
 		stagesStorageAddress,
 		containerRuntime,
 		storage.StagesStorageOptions{
 			RepoStagesStorageOptions: storage.RepoStagesStorageOptions{
-				Implementation: *cmdData.CommonRepoData.Implementation,
+				Implementation:   *cmdData.CommonRepoData.Implementation,
+				LayerCompression: layerCompression,
 				DockerRegistryOptions: docker_registry.DockerRegistryOptions{
 					InsecureRegistry:      *cmdData.InsecureRegistry,
 					SkipTlsVerifyRegistry: *cmdData.SkipTlsVerifyRegistry,
+					ArtifactoryUsername:   *cmdData.CommonRepoData.ArtifactoryUsername,
+					ArtifactoryPassword:   *cmdData.CommonRepoData.ArtifactoryPassword,
 					DockerHubUsername:     *cmdData.CommonRepoData.DockerHubUsername,
 					DockerHubPassword:     *cmdData.CommonRepoData.DockerHubPassword,
 					DockerHubToken:        *cmdData.CommonRepoData.DockerHubToken,
 					GitHubToken:           *cmdData.CommonRepoData.GitHubToken,
 					HarborUsername:        *cmdData.CommonRepoData.HarborUsername,
 					HarborPassword:        *cmdData.CommonRepoData.HarborPassword,
+					NexusUsername:         *cmdData.CommonRepoData.NexusUsername,
+					NexusPassword:         *cmdData.CommonRepoData.NexusPassword,
+					NexusRepository:       *cmdData.CommonRepoData.NexusRepository,
 					QuayToken:             *cmdData.CommonRepoData.QuayToken,
 				},
 			},
@@ -1194,7 +1452,12 @@ func ProcessLogTerminalWidth(cmdData *CmdData) error {
 }
 
 func DockerRegistryInit(cmdData *CmdData) error {
-	return docker_registry.Init(BackgroundContext(), *cmdData.InsecureRegistry, *cmdData.SkipTlsVerifyRegistry)
+	return docker_registry.Init(BackgroundContext(), *cmdData.InsecureRegistry, *cmdData.SkipTlsVerifyRegistry, docker_registry.RequestsPolicyOptions{
+		RateLimit:  int(*cmdData.RegistryRateLimit),
+		MaxRetries: int(*cmdData.RegistryMaxRetries),
+	}, docker_registry.CredentialProviderOptions{
+		CredentialsFile: *cmdData.RegistryCredentialsFile,
+	})
 }
 
 func ValidateRepoImplementation(implementation string) error {
@@ -1270,6 +1533,16 @@ func SetupVirtualMergeIntoCommit(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().StringVarP(cmdData.VirtualMergeIntoCommit, "virtual-merge-into-commit", "", os.Getenv("WERF_VIRTUAL_MERGE_INTO_COMMIT"), "Commit hash for virtual/ephemeral merge commit which is base for changes introduced in the pull request ($WERF_VIRTUAL_MERGE_INTO_COMMIT by default)")
 }
 
+func SetupRegistryMirrors(cmdData *CmdData, cmd *cobra.Command) {
+	registryMirrors := predefinedValuesByEnvNamePrefix("WERF_REGISTRY_MIRROR")
+
+	cmdData.RegistryMirrors = &registryMirrors
+	cmd.Flags().StringArrayVarP(cmdData.RegistryMirrors, "registry-mirror", "", registryMirrors, `Use mirror to pull base images from the registry (can specify multiple).
+Format: registry=mirror, e.g. docker.io=mirror.example.com/dockerhub.
+Mirrors override registryMirrors from werf.yaml, base images keep canonical names in stages.
+Also, can be specified with $WERF_REGISTRY_MIRROR* (e.g. $WERF_REGISTRY_MIRROR_DOCKERHUB=docker.io=mirror.example.com/dockerhub)`)
+}
+
 func OpenLocalGitRepo(projectDir string) (git_repo.Local, error) {
 	return git_repo.OpenLocalRepo("own", projectDir, giterminism_inspector.DevMode)
 }
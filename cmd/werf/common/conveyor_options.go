@@ -7,20 +7,31 @@ import (
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker_registry"
 )
 
-func GetConveyorOptions(commonCmdData *CmdData) build.ConveyorOptions {
+func GetConveyorOptions(commonCmdData *CmdData) (build.ConveyorOptions, error) {
+	registryMirrors, err := docker_registry.ParseRegistryMirrors(*commonCmdData.RegistryMirrors)
+	if err != nil {
+		return build.ConveyorOptions{}, fmt.Errorf("bad --registry-mirror specified: %s", err)
+	}
+
 	return build.ConveyorOptions{
 		LocalGitRepoVirtualMergeOptions: stage.VirtualMergeOptions{
 			VirtualMerge:           *commonCmdData.VirtualMerge,
 			VirtualMergeFromCommit: *commonCmdData.VirtualMergeFromCommit,
 			VirtualMergeIntoCommit: *commonCmdData.VirtualMergeIntoCommit,
 		},
-	}
+		RegistryMirrors: registryMirrors,
+	}, nil
 }
 
 func GetConveyorOptionsWithParallel(commonCmdData *CmdData, buildStagesOptions build.BuildOptions) (build.ConveyorOptions, error) {
-	conveyorOptions := GetConveyorOptions(commonCmdData)
+	conveyorOptions, err := GetConveyorOptions(commonCmdData)
+	if err != nil {
+		return conveyorOptions, err
+	}
+
 	conveyorOptions.Parallel = !(buildStagesOptions.ImageBuildOptions.IntrospectAfterError || buildStagesOptions.ImageBuildOptions.IntrospectBeforeError || len(buildStagesOptions.Targets) != 0) && *commonCmdData.Parallel
 
 	parallelTasksLimit, err := GetParallelTasksLimit(commonCmdData)
@@ -16,7 +16,12 @@ const (
 
 	WerfDebugAnsibleArgs Env = "WERF_DEBUG_ANSIBLE_ARGS"
 	WerfSecretKey        Env = "WERF_SECRET_KEY"
+	WerfSecretKeys       Env = "WERF_SECRET_KEYS"
 	WerfOldSecretKey     Env = "WERF_OLD_SECRET_KEY"
+	WerfSecretIdentity   Env = "WERF_SECRET_IDENTITY"
+
+	WerfSecretAuthenticatedFormat Env = "WERF_SECRET_AUTHENTICATED_FORMAT"
+	WerfSecretStrictFormat        Env = "WERF_SECRET_STRICT_FORMAT"
 )
 
 var envDescription = map[Env]string{
@@ -26,7 +31,23 @@ var envDescription = map[Env]string{
 Secret key also can be defined in files:
 * ~/.werf/global_secret_key (globally),
 * .werf_secret_key (per project)`,
+	WerfSecretKeys: `Use specified comma separated key ring instead of the single secret key: the first key is used to encrypt secrets, all keys are tried to extract secrets.
+
+IMPORTANT: secrets in the legacy format (default format of werf helm secret encrypt) are extracted only with the last key of the ring, migrate secrets with werf helm secret migrate command before the rotation.
+
+Allows to switch to the new secret key without downtime, list secrets that are still encrypted with the old keys with werf helm secret check-key-usage command`,
 	WerfOldSecretKey: "Use specified old secret key to rotate secrets",
+	WerfSecretIdentity: `Use specified age identities (private keys) to extract secrets encrypted to the recipients from the .werf_secret_recipients file.
+
+Identities also can be defined in files:
+* ~/.werf/global_secret_identity (globally),
+* .werf_secret_identity (per project)`,
+	WerfSecretAuthenticatedFormat: `Encrypt secrets with the authenticated AES-GCM format instead of the legacy AES-CBC format, if set to 1.
+
+Secrets in the authenticated format cannot be decrypted by werf versions that do not support it`,
+	WerfSecretStrictFormat: `Reject secrets in the legacy unauthenticated AES-CBC format and encrypt secrets with the authenticated AES-GCM format, if set to 1.
+
+Migrate existing secrets with werf helm secret migrate command first`,
 }
 
 func EnvsDescription(envs ...Env) string {
@@ -14,14 +14,19 @@ type RepoData struct {
 	IsCommon               bool
 	DesignationStorageName string
 
-	Implementation    *string
-	DockerHubUsername *string
-	DockerHubPassword *string
-	DockerHubToken    *string
-	GitHubToken       *string
-	HarborUsername    *string
-	HarborPassword    *string
-	QuayToken         *string
+	Implementation      *string
+	ArtifactoryUsername *string
+	ArtifactoryPassword *string
+	DockerHubUsername   *string
+	DockerHubPassword   *string
+	DockerHubToken      *string
+	GitHubToken         *string
+	HarborUsername      *string
+	HarborPassword      *string
+	NexusUsername       *string
+	NexusPassword       *string
+	NexusRepository     *string
+	QuayToken           *string
 }
 
 func MergeRepoData(repoDataArr ...*RepoData) *RepoData {
@@ -31,6 +36,12 @@ func MergeRepoData(repoDataArr ...*RepoData) *RepoData {
 		if res.Implementation == nil || *res.Implementation == "" {
 			res.Implementation = repoData.Implementation
 		}
+		if res.ArtifactoryUsername == nil || *res.ArtifactoryUsername == "" {
+			res.ArtifactoryUsername = repoData.ArtifactoryUsername
+		}
+		if res.ArtifactoryPassword == nil || *res.ArtifactoryPassword == "" {
+			res.ArtifactoryPassword = repoData.ArtifactoryPassword
+		}
 		if res.DockerHubUsername == nil || *res.DockerHubUsername == "" {
 			res.DockerHubUsername = repoData.DockerHubUsername
 		}
@@ -49,6 +60,15 @@ func MergeRepoData(repoDataArr ...*RepoData) *RepoData {
 		if res.HarborPassword == nil || *res.HarborPassword == "" {
 			res.HarborPassword = repoData.HarborPassword
 		}
+		if res.NexusUsername == nil || *res.NexusUsername == "" {
+			res.NexusUsername = repoData.NexusUsername
+		}
+		if res.NexusPassword == nil || *res.NexusPassword == "" {
+			res.NexusPassword = repoData.NexusPassword
+		}
+		if res.NexusRepository == nil || *res.NexusRepository == "" {
+			res.NexusRepository = repoData.NexusRepository
+		}
 		if res.QuayToken == nil || *res.QuayToken == "" {
 			res.QuayToken = repoData.QuayToken
 		}
@@ -81,6 +101,42 @@ Default %s or auto mode (detect implementation by a registry).`,
 	)
 }
 
+func SetupArtifactoryUsernameForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Artifactory username (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Artifactory username for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.ArtifactoryUsername = new(string)
+	cmd.Flags().StringVarP(
+		repoData.ArtifactoryUsername,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+}
+
+func SetupArtifactoryPasswordForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Artifactory password or API key (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Artifactory password or API key for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.ArtifactoryPassword = new(string)
+	cmd.Flags().StringVarP(
+		repoData.ArtifactoryPassword,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+}
+
 func SetupDockerHubUsernameForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
 	var usage string
 	if repoData.IsCommon {
@@ -189,6 +245,60 @@ func SetupHarborPasswordForRepoData(repoData *RepoData, cmd *cobra.Command, para
 	)
 }
 
+func SetupNexusUsernameForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Nexus username (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Nexus username for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.NexusUsername = new(string)
+	cmd.Flags().StringVarP(
+		repoData.NexusUsername,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+}
+
+func SetupNexusPasswordForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Nexus password (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Nexus password for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.NexusPassword = new(string)
+	cmd.Flags().StringVarP(
+		repoData.NexusPassword,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+}
+
+func SetupNexusRepositoryForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Nexus docker repository name (required if the same image is stored in several Nexus repositories) (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Nexus docker repository name (required if the same image is stored in several Nexus repositories) for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.NexusRepository = new(string)
+	cmd.Flags().StringVarP(
+		repoData.NexusRepository,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+}
+
 func SetupQuayTokenForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
 	var usage string
 	if repoData.IsCommon {
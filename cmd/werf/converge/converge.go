@@ -1,6 +1,7 @@
 package converge
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"time"
@@ -24,6 +25,7 @@ import (
 	"github.com/werf/werf/pkg/deploy"
 	"github.com/werf/werf/pkg/deploy/helm"
 	"github.com/werf/werf/pkg/deploy/lock_manager"
+	"github.com/werf/werf/pkg/deploy/release_diff"
 	"github.com/werf/werf/pkg/deploy/secret"
 	"github.com/werf/werf/pkg/deploy/werf_chart"
 	"github.com/werf/werf/pkg/docker"
@@ -40,6 +42,7 @@ import (
 var cmdData struct {
 	Timeout      int
 	AutoRollback bool
+	Diff         bool
 }
 
 var commonCmdData common.CmdData
@@ -59,7 +62,7 @@ Read more info about Helm chart structure, Helm Release name, Kubernetes Namespa
 werf converge --repo registry.mydomain.com/web --env production`,
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfDebugAnsibleArgs, common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfDebugAnsibleArgs, common.WerfSecretKey, common.WerfSecretKeys, common.WerfSecretIdentity, common.WerfSecretStrictFormat),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := common.BackgroundContext()
@@ -124,6 +127,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
+	common.SetupSecretValueProvider(&commonCmdData, cmd)
 
 	common.SetupReportPath(&commonCmdData, cmd)
 	common.SetupReportFormat(&commonCmdData, cmd)
@@ -131,6 +135,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	common.SetupVirtualMerge(&commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
+	common.SetupRegistryMirrors(&commonCmdData, cmd)
 
 	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
 
@@ -141,6 +146,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	cmd.Flags().IntVarP(&cmdData.Timeout, "timeout", "t", 0, "Resources tracking timeout in seconds")
 	cmd.Flags().BoolVarP(&cmdData.AutoRollback, "auto-rollback", "R", common.GetBoolEnvironmentDefaultFalse("WERF_AUTO_ROLLBACK"), "Enable auto rollback of the failed release to the previous deployed release version when current deploy process have failed ($WERF_AUTO_ROLLBACK by default)")
 	cmd.Flags().BoolVarP(&cmdData.AutoRollback, "atomic", "", common.GetBoolEnvironmentDefaultFalse("WERF_ATOMIC"), "Enable auto rollback of the failed release to the previous deployed release version when current deploy process have failed ($WERF_ATOMIC by default)")
+	cmd.Flags().BoolVarP(&cmdData.Diff, "diff", "", common.GetBoolEnvironmentDefaultFalse("WERF_DIFF"), "Show the diff of the release objects with the live objects in the cluster instead of deploying, implies --skip-build: images should be built and published beforehand ($WERF_DIFF by default)")
 
 	return cmd
 }
@@ -291,7 +297,8 @@ func run(ctx context.Context, projectDir string) error {
 		defer conveyorWithRetry.Terminate()
 
 		if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
-			if *commonCmdData.SkipBuild {
+			// NOTE: diff should not publish anything, so images should be built and published beforehand
+			if *commonCmdData.SkipBuild || cmdData.Diff {
 				if err := c.ShouldBeBuilt(ctx); err != nil {
 					return err
 				}
@@ -312,12 +319,17 @@ func run(ctx context.Context, projectDir string) error {
 	}
 
 	var secretsManager secret.Manager
-	if m, err := deploy.GetSafeSecretManager(ctx, projectDir, chartDir, *commonCmdData.SecretValues, &localGitRepo, *commonCmdData.IgnoreSecretKey); err != nil {
+	if m, err := deploy.GetSafeSecretManager(ctx, projectDir, chartDir, *commonCmdData.Environment, *commonCmdData.SecretValues, &localGitRepo, *commonCmdData.IgnoreSecretKey); err != nil {
 		return err
 	} else {
 		secretsManager = m
 	}
 
+	secretValueProvider, err := common.GetSecretValueProvider(&commonCmdData, projectDir, *commonCmdData.Environment)
+	if err != nil {
+		return err
+	}
+
 	releaseName, err := common.GetHelmRelease(*commonCmdData.Release, *commonCmdData.Environment, werfConfig)
 	if err != nil {
 		return err
@@ -338,11 +350,14 @@ func run(ctx context.Context, projectDir string) error {
 		return err
 	}
 
+	// NOTE: lock manager creates the namespace, diff should not change anything in the cluster and does not need the release lock
 	var lockManager *lock_manager.LockManager
-	if m, err := lock_manager.NewLockManager(namespace); err != nil {
-		return fmt.Errorf("unable to create lock manager: %s", err)
-	} else {
-		lockManager = m
+	if !cmdData.Diff {
+		if m, err := lock_manager.NewLockManager(namespace); err != nil {
+			return fmt.Errorf("unable to create lock manager: %s", err)
+		} else {
+			lockManager = m
+		}
 	}
 
 	wc := werf_chart.NewWerfChart(ctx, &localGitRepo, projectDir, werf_chart.WerfChartOptions{
@@ -353,8 +368,9 @@ func run(ctx context.Context, projectDir string) error {
 		ExtraAnnotations: userExtraAnnotations,
 		ExtraLabels:      userExtraLabels,
 
-		LockManager:    lockManager,
-		SecretsManager: secretsManager,
+		LockManager:         lockManager,
+		SecretsManager:      secretsManager,
+		SecretValueProvider: secretValueProvider,
 	})
 	if err := wc.SetEnv(*commonCmdData.Environment); err != nil {
 		return err
@@ -377,7 +393,8 @@ func run(ctx context.Context, projectDir string) error {
 			ConfigPath:       *commonCmdData.KubeConfig,
 			ConfigDataBase64: *commonCmdData.KubeConfigBase64,
 		},
-		ReleasesHistoryMax: *commonCmdData.ReleasesHistoryMax,
+		ReleasesHistoryMax:   *commonCmdData.ReleasesHistoryMax,
+		ResourcesWaitTimeout: time.Duration(cmdData.Timeout) * time.Second,
 	}); err != nil {
 		return err
 	}
@@ -392,14 +409,20 @@ func run(ctx context.Context, projectDir string) error {
 		ReadFileFunc:    common.MakeHelmReadFileFunc(ctx, localGitRepo, projectDir),
 	}
 
+	valueOpts := &values.Options{
+		ValueFiles:   *commonCmdData.Values,
+		StringValues: *commonCmdData.SetString,
+		Values:       *commonCmdData.Set,
+		FileValues:   *commonCmdData.SetFile,
+	}
+
+	if cmdData.Diff {
+		return runDiff(ctx, wc, actionConfig, releaseName, chartDir, valueOpts)
+	}
+
 	helmUpgradeCmd, _ := cmd_helm.NewUpgradeCmd(actionConfig, logboek.ProxyOutStream(), cmd_helm.UpgradeCmdOptions{
-		PostRenderer: wc.ExtraAnnotationsAndLabelsPostRenderer,
-		ValueOpts: &values.Options{
-			ValueFiles:   *commonCmdData.Values,
-			StringValues: *commonCmdData.SetString,
-			Values:       *commonCmdData.Set,
-			FileValues:   *commonCmdData.SetFile,
-		},
+		PostRenderer:    wc.GetPostRenderer(),
+		ValueOpts:       valueOpts,
 		CreateNamespace: common.NewBool(true),
 		Install:         common.NewBool(true),
 		Wait:            common.NewBool(true),
@@ -410,3 +433,33 @@ func run(ctx context.Context, projectDir string) error {
 		return helmUpgradeCmd.RunE(helmUpgradeCmd, []string{releaseName, chartDir})
 	})
 }
+
+func runDiff(ctx context.Context, wc *werf_chart.WerfChart, actionConfig *action.Configuration, releaseName, chartDir string, valueOpts *values.Options) error {
+	// NOTE: helm template replaces kube client and releases storage of the action config with the fake ones
+	templateActionConfig := *actionConfig
+
+	var manifests bytes.Buffer
+	helmTemplateCmd, helmTemplateAction := cmd_helm.NewTemplateCmd(&templateActionConfig, &manifests, cmd_helm.TemplateCmdOptions{
+		PostRenderer: wc.GetPostRenderer(),
+		ValueOpts:    valueOpts,
+	})
+	// Hooks are not release objects: they are recreated by their delete policies and never pruned
+	helmTemplateAction.DisableHooks = true
+
+	if err := wc.WrapTemplate(ctx, func() error {
+		return helmTemplateCmd.RunE(helmTemplateCmd, []string{releaseName, chartDir})
+	}); err != nil {
+		return err
+	}
+
+	releaseDiff, err := release_diff.Calculate(actionConfig, releaseName, manifests.String(), release_diff.Options{
+		SecretValuesToMask: wc.GetSecretValuesToMask(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to calculate release diff: %s", err)
+	}
+
+	releaseDiff.Print(logboek.ProxyOutStream())
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/werf/werf/pkg/deploy/release_diff"
+	"github.com/werf/werf/pkg/deploy/werf_chart"
+
+	"github.com/spf13/cobra"
+	"github.com/werf/werf/cmd/werf/common"
+	cmd_werf_common "github.com/werf/werf/cmd/werf/common"
+
+	"helm.sh/helm/v3/pkg/action"
+
+	cmd_helm "helm.sh/helm/v3/cmd/helm"
+)
+
+var diffCmdData cmd_werf_common.CmdData
+
+func NewDiffCmd(actionConfig *action.Configuration, wc *werf_chart.WerfChart) *cobra.Command {
+	// NOTE: helm template replaces kube client and releases storage of the action config with the fake ones
+	templateActionConfig := new(action.Configuration)
+
+	var manifests bytes.Buffer
+	cmd, helmAction := cmd_helm.NewTemplateCmd(templateActionConfig, &manifests, cmd_helm.TemplateCmdOptions{
+		PostRenderer: wc.GetPostRenderer(),
+	})
+	cmd.Use = "diff [NAME] [CHART]"
+	cmd.Short = "Show changes of the release objects in the cluster"
+	cmd.Long = `Render the chart the same way as template command does and show the unified diff of every release object with the live object in the cluster.
+
+Changes of the existing objects are calculated with the server-side dry-run, so defaults and fields populated by the server are not shown. Objects which will be created, updated or pruned by the upgrade are marked with +, ~ and -. Hooks are not shown since they are not release objects. The data of Secret objects and secret values are masked.`
+	SetupRenderRelatedWerfChartParams(cmd, &diffCmdData)
+
+	if err := cmd.Flags().MarkHidden("no-hooks"); err != nil {
+		panic(err)
+	}
+
+	oldRunE := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := common.BackgroundContext()
+
+		if err := common.GetOndemandKubeInitializer().Init(ctx); err != nil {
+			return err
+		}
+
+		if releaseName, chartDir, err := helmAction.NameAndChart(args); err != nil {
+			return err
+		} else {
+			wc.ReleaseName = releaseName
+			wc.ChartDir = chartDir
+		}
+
+		if err := InitRenderRelatedWerfChartParams(ctx, &diffCmdData, wc, wc.ChartDir); err != nil {
+			return fmt.Errorf("unable to init werf chart: %s", err)
+		}
+
+		*templateActionConfig = *actionConfig
+
+		// Hooks are not release objects: they are recreated by their delete policies and never pruned
+		helmAction.DisableHooks = true
+
+		if err := wc.WrapTemplate(ctx, func() error {
+			return oldRunE(cmd, args)
+		}); err != nil {
+			return err
+		}
+
+		releaseDiff, err := release_diff.Calculate(actionConfig, wc.ReleaseName, manifests.String(), release_diff.Options{
+			SecretValuesToMask: wc.GetSecretValuesToMask(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to calculate release diff: %s", err)
+		}
+
+		releaseDiff.Print(os.Stdout)
+
+		return nil
+	}
+
+	return cmd
+}
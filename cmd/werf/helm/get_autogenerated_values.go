@@ -34,7 +34,7 @@ func NewGetAutogeneratedValuesCmd() *cobra.Command {
 These values includes project name, docker images ids and other`),
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeys, common.WerfSecretIdentity, common.WerfSecretStrictFormat),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := common.ProcessLogOptions(&getAutogeneratedValuedCmdData); err != nil {
@@ -67,6 +67,7 @@ These values includes project name, docker images ids and other`),
 	common.SetupVirtualMerge(&getAutogeneratedValuedCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&getAutogeneratedValuedCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(&getAutogeneratedValuedCmdData, cmd)
+	common.SetupRegistryMirrors(&getAutogeneratedValuedCmdData, cmd)
 
 	common.SetupNamespace(&getAutogeneratedValuedCmdData, cmd)
 
@@ -198,7 +199,12 @@ func runGetServiceValues() error {
 
 		storageManager := manager.NewStorageManager(projectName, stagesStorage, secondaryStagesStorageList, storageLockManager, stagesStorageCache)
 
-		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, localGitRepo, []string{}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, common.GetConveyorOptions(&getAutogeneratedValuedCmdData))
+		conveyorOptions, err := common.GetConveyorOptions(&getAutogeneratedValuedCmdData)
+		if err != nil {
+			return err
+		}
+
+		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, localGitRepo, []string{}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, storageManager, storageLockManager, conveyorOptions)
 		defer conveyorWithRetry.Terminate()
 
 		if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
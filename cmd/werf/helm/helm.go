@@ -10,12 +10,15 @@ import (
 
 	"github.com/werf/werf/pkg/deploy/werf_chart"
 
+	helm_secret_check_key_usage "github.com/werf/werf/cmd/werf/helm/secret/check_key_usage"
 	helm_secret_decrypt "github.com/werf/werf/cmd/werf/helm/secret/decrypt"
+	helm_secret_diff "github.com/werf/werf/cmd/werf/helm/secret/diff"
 	helm_secret_encrypt "github.com/werf/werf/cmd/werf/helm/secret/encrypt"
 	helm_secret_file_decrypt "github.com/werf/werf/cmd/werf/helm/secret/file/decrypt"
 	helm_secret_file_edit "github.com/werf/werf/cmd/werf/helm/secret/file/edit"
 	helm_secret_file_encrypt "github.com/werf/werf/cmd/werf/helm/secret/file/encrypt"
 	helm_secret_generate_secret_key "github.com/werf/werf/cmd/werf/helm/secret/generate_secret_key"
+	helm_secret_migrate "github.com/werf/werf/cmd/werf/helm/secret/migrate"
 	helm_secret_rotate_secret_key "github.com/werf/werf/cmd/werf/helm/secret/rotate_secret_key"
 	helm_secret_values_decrypt "github.com/werf/werf/cmd/werf/helm/secret/values/decrypt"
 	helm_secret_values_edit "github.com/werf/werf/cmd/werf/helm/secret/values/edit"
@@ -79,6 +82,7 @@ func NewCmd() *cobra.Command {
 		cmd_helm.NewLintCmd(os.Stdout),
 		cmd_helm.NewListCmd(actionConfig, os.Stdout),
 		NewTemplateCmd(actionConfig, wc),
+		NewDiffCmd(actionConfig, wc),
 		cmd_helm.NewRepoCmd(os.Stdout),
 		cmd_helm.NewRollbackCmd(actionConfig, os.Stdout),
 		NewInstallCmd(actionConfig, wc),
@@ -140,6 +144,14 @@ func NewCmd() *cobra.Command {
 
 				common.SetupOndemandKubeInitializer(*_commonCmdData.KubeContext, *_commonCmdData.KubeConfig, *_commonCmdData.KubeConfigBase64)
 
+				// NOTE: --timeout of install, upgrade and rollback commands limits waiting for each deploy stage as well
+				var resourcesWaitTimeout time.Duration
+				if timeoutFlag := cmd.Flags().Lookup("timeout"); timeoutFlag != nil {
+					if timeout, err := time.ParseDuration(timeoutFlag.Value.String()); err == nil {
+						resourcesWaitTimeout = timeout
+					}
+				}
+
 				helm.InitActionConfig(ctx, common.GetOndemandKubeInitializer(), namespace, cmd_helm.Settings, actionConfig, helm.InitActionConfigOptions{
 					StatusProgressPeriod:      time.Duration(*_commonCmdData.StatusProgressPeriodSeconds) * time.Second,
 					HooksStatusProgressPeriod: time.Duration(*_commonCmdData.HooksStatusProgressPeriodSeconds) * time.Second,
@@ -148,7 +160,8 @@ func NewCmd() *cobra.Command {
 						ConfigPath:       *_commonCmdData.KubeConfig,
 						ConfigDataBase64: *_commonCmdData.KubeConfigBase64,
 					},
-					ReleasesHistoryMax: *_commonCmdData.ReleasesHistoryMax,
+					ReleasesHistoryMax:   *_commonCmdData.ReleasesHistoryMax,
+					ResourcesWaitTimeout: resourcesWaitTimeout,
 				})
 
 				if oldRun != nil {
@@ -213,6 +226,9 @@ func secretCmd() *cobra.Command {
 		helm_secret_encrypt.NewCmd(),
 		helm_secret_decrypt.NewCmd(),
 		helm_secret_rotate_secret_key.NewCmd(),
+		helm_secret_migrate.NewCmd(),
+		helm_secret_check_key_usage.NewCmd(),
+		helm_secret_diff.NewCmd(),
 	)
 
 	return cmd
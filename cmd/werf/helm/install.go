@@ -21,7 +21,7 @@ var installCmdData cmd_werf_common.CmdData
 
 func NewInstallCmd(actionConfig *action.Configuration, wc *werf_chart.WerfChart) *cobra.Command {
 	cmd, helmAction := cmd_helm.NewInstallCmd(actionConfig, os.Stdout, cmd_helm.InstallCmdOptions{
-		PostRenderer: wc.ExtraAnnotationsAndLabelsPostRenderer,
+		PostRenderer: wc.GetPostRenderer(),
 	})
 	SetupRenderRelatedWerfChartParams(cmd, &installCmdData)
 
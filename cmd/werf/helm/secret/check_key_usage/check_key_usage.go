@@ -0,0 +1,175 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	secret_common "github.com/werf/werf/cmd/werf/helm/secret/common"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/git_repo"
+	pkg_secret "github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "check-key-usage [EXTRA_SECRET_VALUES_FILE_PATH...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "List secret files that are still encrypted with old keys of the key ring",
+		Long: common.GetLongCommandDescription(`List secret files that are still encrypted with old keys of the key ring.
+
+Key ring should be specified in the $WERF_SECRET_KEYS as comma separated keys, the first key is the current one that is used to encrypt data, other keys are old ones that are only used to decrypt data.
+
+IMPORTANT: only the authenticated format allows to detect the key that data is encrypted with. Data in the legacy format (default format of werf helm secret encrypt) is decrypted and reported only with the last key of the ring. The rotation steps:
+1. migrate secrets to the authenticated format with the old key using werf helm secret migrate command;
+2. add the new key to the beginning of the ring;
+3. re-encrypt secrets with werf helm secret rotate-secret-key command until this command reports no files;
+4. remove the old keys from the ring.
+
+Command checks:
+* standard raw secret files in the .helm/secret folder;
+* standard secret values yaml file .helm/secret-values.yaml;
+* additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params.
+
+Files encrypted with old keys can be re-encrypted with the current key using werf helm secret rotate-secret-key command.`),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeys, common.WerfSecretStrictFormat),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runCheckKeyUsage(args...)
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runCheckKeyUsage(secretValuesPaths ...string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfig, err := common.GetRequiredWerfConfig(context.Background(), projectDir, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	helmChartDir, err := common.GetHelmChartDir(projectDir, &commonCmdData, werfConfig)
+	if err != nil {
+		return fmt.Errorf("getting helm chart dir failed: %s", err)
+	}
+
+	keys, err := secret.GetSecretKeys(projectDir)
+	if err != nil {
+		return err
+	}
+
+	checker, err := secret.NewKeyUsageChecker(keys)
+	if err != nil {
+		return fmt.Errorf("check encryption key: %s", err)
+	}
+
+	chartSecretValuesPaths, secretFilesPaths, err := secret_common.GetChartSecretFilesPaths(helmChartDir)
+	if err != nil {
+		return err
+	}
+	secretValuesPaths = append(secretValuesPaths, chartSecretValuesPaths...)
+
+	var oldKeysUsages []string
+
+	checkFiles := func(filePaths []string, checkFunc func([]byte) ([]int, error)) error {
+		for _, filePath := range filePaths {
+			fileData, err := ioutil.ReadFile(filePath)
+			if err != nil {
+				return err
+			}
+
+			keyIndexes, err := checkFunc(bytes.TrimSpace(fileData))
+			if err != nil {
+				return fmt.Errorf("unable to check file '%s': %s", filePath, err)
+			}
+
+			var oldKeys []string
+			for _, keyIndex := range keyIndexes {
+				if keyIndex > 0 {
+					oldKeys = append(oldKeys, fmt.Sprintf("#%d (%s)", keyIndex+1, pkg_secret.KeyFingerprint(keys[keyIndex])))
+				}
+			}
+
+			if len(oldKeys) != 0 {
+				oldKeysUsages = append(oldKeysUsages, fmt.Sprintf("%s: %s", filePath, strings.Join(oldKeys, ", ")))
+			}
+		}
+
+		return nil
+	}
+
+	if err := checkFiles(secretFilesPaths, checker.CheckData); err != nil {
+		return err
+	}
+
+	if err := checkFiles(secretValuesPaths, checker.CheckYamlData); err != nil {
+		return err
+	}
+
+	if len(oldKeysUsages) == 0 {
+		logboek.LogLn("All secret files are encrypted with the current key")
+		return nil
+	}
+
+	logboek.LogLn("Secret files encrypted with old keys:")
+	for _, usage := range oldKeysUsages {
+		logboek.LogF("  %s\n", usage)
+	}
+
+	return nil
+}
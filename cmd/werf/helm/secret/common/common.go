@@ -84,3 +84,52 @@ func SaveGeneratedData(filePath string, data []byte) error {
 
 	return nil
 }
+
+// GetChartSecretFilesPaths returns the default secret values file and the raw secret files of the chart.
+func GetChartSecretFilesPaths(helmChartDir string) ([]string, []string, error) {
+	var secretValuesPaths, secretFilesPaths []string
+
+	isHelmChartDirExist, err := util.FileExists(helmChartDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !isHelmChartDirExist {
+		return nil, nil, nil
+	}
+
+	defaultSecretValuesPath := filepath.Join(helmChartDir, "secret-values.yaml")
+	isDefaultSecretValuesExist, err := util.FileExists(defaultSecretValuesPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isDefaultSecretValuesExist {
+		secretValuesPaths = append(secretValuesPaths, defaultSecretValuesPath)
+	}
+
+	secretDirectory := filepath.Join(helmChartDir, "secret")
+	isSecretDirectoryExist, err := util.FileExists(secretDirectory)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isSecretDirectoryExist {
+		err = filepath.Walk(secretDirectory, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() {
+				secretFilesPaths = append(secretFilesPaths, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return secretValuesPaths, secretFilesPaths, nil
+}
@@ -0,0 +1,189 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/deploy/werf_chart"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var cmdData struct {
+	FromCommit string
+	ToCommit   string
+	MaskValues bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "diff",
+		DisableFlagsInUseLine: true,
+		Short:                 "Show decrypted secret values and secret files changes between git revisions",
+		Long: common.GetLongCommandDescription(`Show decrypted secret values and secret files changes between git revisions.
+
+Command decrypts the chart secret values files (.helm/secret-values.yaml, .helm/secret-values.<env>.yaml with --env option and additional secret values files specified with --secret-values option) and secret files from the .helm/secret folder at both revisions and prints changed keys of the secret values and changed secret files:
+* + for added key or file;
+* - for removed key or file;
+* ~ for changed key or file.
+
+Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file, it should be able to decrypt secrets of both revisions.`),
+		Example: `  # Show secrets changes of the last commit
+  $ werf helm secret diff
+
+  # Show secrets changes of the merge request without values
+  $ werf helm secret diff --from-commit origin/main --to-commit HEAD --mask-values
+  Secret values:
+  ~ mysql.password
+  + redis.password`,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeys, common.WerfSecretIdentity, common.WerfSecretStrictFormat),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runDiff()
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+	common.SetupSecretValues(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.FromCommit, "from-commit", "", "HEAD~1", "Compare secrets of the specified commit, branch or tag")
+	cmd.Flags().StringVarP(&cmdData.ToCommit, "to-commit", "", "HEAD", "Compare with secrets of the specified commit, branch or tag")
+	cmd.Flags().BoolVarP(&cmdData.MaskValues, "mask-values", "", false, "Print only changed keys and files without values")
+
+	return cmd
+}
+
+func runDiff() error {
+	ctx := common.BackgroundContext()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	localGitRepo, err := common.OpenLocalGitRepo(projectDir)
+	if err != nil {
+		return fmt.Errorf("unable to open local repo %s: %s", projectDir, err)
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfig, err := common.GetRequiredWerfConfig(context.Background(), projectDir, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	helmChartDir, err := common.GetHelmChartDir(projectDir, &commonCmdData, werfConfig)
+	if err != nil {
+		return fmt.Errorf("getting helm chart dir failed: %s", err)
+	}
+
+	m, err := secret.GetEnvManager(projectDir, *commonCmdData.Environment)
+	if err != nil {
+		return err
+	}
+
+	loadSecrets := func(revision string) (*werf_chart.WerfChart, error) {
+		commit, err := localGitRepo.ResolveCommit(ctx, revision)
+		if err != nil {
+			return nil, err
+		}
+
+		wc := werf_chart.NewWerfChart(ctx, &localGitRepo, projectDir, werf_chart.WerfChartOptions{
+			ChartDir:         helmChartDir,
+			SecretValueFiles: *commonCmdData.SecretValues,
+			SecretsManager:   m,
+		})
+		if err := wc.SetEnv(*commonCmdData.Environment); err != nil {
+			return nil, err
+		}
+
+		if err := wc.LoadSecretsFromLocalGitRepoCommit(commit); err != nil {
+			return nil, fmt.Errorf("unable to load secrets of the revision %q: %s", revision, err)
+		}
+
+		return wc, nil
+	}
+
+	fromChart, err := loadSecrets(cmdData.FromCommit)
+	if err != nil {
+		return err
+	}
+
+	toChart, err := loadSecrets(cmdData.ToCommit)
+	if err != nil {
+		return err
+	}
+
+	valuesDiff := secret.DiffValues(fromChart.GetDecodedSecretValues(), toChart.GetDecodedSecretValues())
+	filesDiff := secret.DiffFilesData(fromChart.GetDecodedSecretFilesData(), toChart.GetDecodedSecretFilesData())
+
+	if len(valuesDiff) == 0 && len(filesDiff) == 0 {
+		logboek.Context(ctx).LogLn("No secrets changes")
+		return nil
+	}
+
+	if len(valuesDiff) != 0 {
+		fmt.Println("Secret values:")
+		for _, entry := range valuesDiff {
+			fmt.Println(secret.FormatDiffEntry(entry, cmdData.MaskValues))
+		}
+	}
+
+	if len(filesDiff) != 0 {
+		if len(valuesDiff) != 0 {
+			fmt.Println()
+		}
+
+		fmt.Println("Secret files:")
+		for _, entry := range filesDiff {
+			fmt.Println(secret.FormatDiffEntry(entry, cmdData.MaskValues))
+		}
+	}
+
+	return nil
+}
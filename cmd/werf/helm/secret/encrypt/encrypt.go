@@ -27,7 +27,8 @@ func NewCmd() *cobra.Command {
 		DisableFlagsInUseLine: true,
 		Short:                 "Encrypt data",
 		Long: common.GetLongCommandDescription(`Encrypt data from standard input.
-Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
+Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file.
+With --env option the key of the environment is used when it is defined in $WERF_SECRET_KEY_<ENV> or .werf_secret_key.<env> file`),
 		Example: `  # Encrypt data in interactive mode
   $ werf helm secret encrypt
   Enter secret:
@@ -36,7 +37,7 @@ Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
   # Encrypt from a pipe and save result in file
   $ date | werf helm secret encrypt -o .helm/secret/date`,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeys, common.WerfSecretAuthenticatedFormat, common.WerfSecretStrictFormat),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
@@ -51,6 +52,7 @@ Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file`),
 	common.SetupDir(&commonCmdData, cmd)
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
 
 	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
 
@@ -79,7 +81,7 @@ func runSecretEncrypt() error {
 		return fmt.Errorf("getting project dir failed: %s", err)
 	}
 
-	m, err := secret.GetManager(projectDir)
+	m, err := secret.GetEnvManager(projectDir, *commonCmdData.Environment)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,181 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	secret_common "github.com/werf/werf/cmd/werf/helm/secret/common"
+	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "migrate [EXTRA_SECRET_VALUES_FILE_PATH...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Re-encrypt secret files with the current encryption format",
+		Long: common.GetLongCommandDescription(`Re-encrypt secret files with the current authenticated encryption format.
+
+Secret key should reside either in the $WERF_SECRET_KEY or .werf_secret_key file, or the key ring should be specified in the $WERF_SECRET_KEYS.
+With the key ring the legacy data is extracted with the last key of the ring and encrypted with the first key.
+
+Command will extract data encrypted with the legacy format, encrypt it with the current format and rewrite files in place:
+* standard raw secret files in the .helm/secret folder;
+* standard secret values yaml file .helm/secret-values.yaml;
+* additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params.
+
+Data already encrypted with the current format (including data of the environments encrypted with $WERF_SECRET_KEY_<ENV> keys) is left as is, files without legacy data are not rewritten.
+
+The legacy format does not allow to detect a wrong key reliably, so the key is checked with the data already encrypted with the current format, the padding of the legacy data is checked strictly and every migrated value is decrypted back and compared with the source value. All files are checked before rewriting: no files are changed if any value cannot be migrated.`),
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeys),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runMigrate(args...)
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runMigrate(secretValuesPaths ...string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfig, err := common.GetRequiredWerfConfig(context.Background(), projectDir, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	helmChartDir, err := common.GetHelmChartDir(projectDir, &commonCmdData, werfConfig)
+	if err != nil {
+		return fmt.Errorf("getting helm chart dir failed: %s", err)
+	}
+
+	keys, err := secret.GetSecretKeys(projectDir)
+	if err != nil {
+		return err
+	}
+
+	migrator, err := secret.NewMigrator(keys)
+	if err != nil {
+		return err
+	}
+
+	return secretsMigrate(migrator, helmChartDir, secretValuesPaths...)
+}
+
+func secretsMigrate(migrator *secret.Migrator, helmChartDir string, secretValuesPaths ...string) error {
+	chartSecretValuesPaths, secretFilesPaths, err := secret_common.GetChartSecretFilesPaths(helmChartDir)
+	if err != nil {
+		return err
+	}
+	secretValuesPaths = append(secretValuesPaths, chartSecretValuesPaths...)
+
+	migratedFilesData := map[string][]byte{}
+
+	if err := migrateFiles(secretFilesPaths, migratedFilesData, migrator.MigrateData); err != nil {
+		return fmt.Errorf("no files have been changed: %s", err)
+	}
+
+	if err := migrateFiles(secretValuesPaths, migratedFilesData, migrator.MigrateYamlData); err != nil {
+		return fmt.Errorf("no files have been changed: %s", err)
+	}
+
+	if len(migratedFilesData) == 0 {
+		logboek.LogLn("All secret files are already encrypted with the current format")
+		return nil
+	}
+
+	for filePath, fileData := range migratedFilesData {
+		err := logboek.LogProcess(fmt.Sprintf("Saving file '%s'", filePath)).DoError(func() error {
+			fileData = append(bytes.TrimSpace(fileData), []byte("\n")...)
+			return ioutil.WriteFile(filePath, fileData, 0644)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func migrateFiles(filePaths []string, migratedFilesData map[string][]byte, migrateFunc func([]byte) ([]byte, bool, error)) error {
+	for _, filePath := range filePaths {
+		err := logboek.LogProcess(fmt.Sprintf("Migrating file '%s'", filePath)).DoError(func() error {
+			fileData, err := ioutil.ReadFile(filePath)
+			if err != nil {
+				return err
+			}
+
+			resultData, changed, err := migrateFunc(bytes.TrimSpace(fileData))
+			if err != nil {
+				return err
+			}
+
+			if changed {
+				migratedFilesData[filePath] = resultData
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
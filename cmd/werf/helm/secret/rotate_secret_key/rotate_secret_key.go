@@ -32,12 +32,18 @@ func NewCmd() *cobra.Command {
 Old key should be specified in the $WERF_OLD_SECRET_KEY.
 New key should reside either in the $WERF_SECRET_KEY or .werf_secret_key file.
 
+When age recipients are listed in the .werf_secret_recipients file, secrets are encrypted to these recipients. In that case $WERF_OLD_SECRET_KEY can be omitted to re-encrypt secrets to the current recipients list (e.g. after a recipient has been removed), the data is extracted with the identity from the $WERF_SECRET_IDENTITY or .werf_secret_identity file.
+
+When the key ring is specified in the $WERF_SECRET_KEYS, $WERF_OLD_SECRET_KEY can be omitted as well: the data is extracted with any key of the ring and encrypted with the first key.
+
 Command will extract data with the old key, generate new secret data and rewrite files:
 * standard raw secret files in the .helm/secret folder;
 * standard secret values yaml file .helm/secret-values.yaml;
-* additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params`),
+* additional secret values yaml files specified with EXTRA_SECRET_VALUES_FILE_PATH params.
+
+Files encrypted with environment keys ($WERF_SECRET_KEY_<ENV>) are not regenerated`),
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfOldSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeys, common.WerfOldSecretKey, common.WerfSecretIdentity, common.WerfSecretAuthenticatedFormat, common.WerfSecretStrictFormat),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
@@ -106,17 +112,25 @@ func runRotateSecretKey(cmd *cobra.Command, secretValuesPaths ...string) error {
 		return err
 	}
 
-	oldSecretKey := os.Getenv("WERF_OLD_SECRET_KEY")
-	if oldSecretKey == "" {
+	var oldSecret secret.Manager
+	if oldSecretKey := os.Getenv("WERF_OLD_SECRET_KEY"); oldSecretKey != "" {
+		oldSecret, err = secret.NewManager([]byte(oldSecretKey))
+		if err != nil {
+			return err
+		}
+	} else if recipients, err := secret.GetSecretRecipients(projectDir); err != nil {
+		return err
+	} else if len(recipients) != 0 {
+		// re-encrypt secrets to the current recipients list, data is extracted with the same key or identity
+		oldSecret = newSecret
+	} else if keys, err := secret.GetSecretKeys(projectDir); err == nil && len(keys) > 1 {
+		// re-encrypt secrets with the first key of the ring, data is extracted with any key of the ring
+		oldSecret = newSecret
+	} else {
 		common.PrintHelp(cmd)
 		return fmt.Errorf("WERF_OLD_SECRET_KEY environment required")
 	}
 
-	oldSecret, err := secret.NewManager([]byte(oldSecretKey))
-	if err != nil {
-		return err
-	}
-
 	return secretsRegenerate(newSecret, oldSecret, helmChartDir, secretValuesPaths...)
 }
 
@@ -182,6 +196,14 @@ func secretsRegenerate(newManager, oldManager secret.Manager, helmChartDir strin
 		return err
 	}
 
+	// only the common key is rotated, files encrypted with environment keys are left as is
+	for filePath, fileData := range secretFilesData {
+		if env := secret.GetEncryptedDataEnv(fileData); env != "" {
+			logboek.LogF("Skipping file '%s' encrypted with the %q environment key\n", filePath, env)
+			delete(secretFilesData, filePath)
+		}
+	}
+
 	secretValuesFilesData, err = readFilesToDecode(secretValuesPaths, pwd)
 	if err != nil {
 		return err
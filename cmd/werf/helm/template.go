@@ -19,7 +19,7 @@ var templateCmdData cmd_werf_common.CmdData
 
 func NewTemplateCmd(actionConfig *action.Configuration, wc *werf_chart.WerfChart) *cobra.Command {
 	cmd, helmAction := cmd_helm.NewTemplateCmd(actionConfig, os.Stdout, cmd_helm.TemplateCmdOptions{
-		PostRenderer: wc.ExtraAnnotationsAndLabelsPostRenderer,
+		PostRenderer: wc.GetPostRenderer(),
 	})
 	SetupRenderRelatedWerfChartParams(cmd, &templateCmdData)
 
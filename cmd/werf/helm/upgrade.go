@@ -18,7 +18,7 @@ var upgradeCmdData cmd_werf_common.CmdData
 
 func NewUpgradeCmd(actionConfig *action.Configuration, wc *werf_chart.WerfChart) *cobra.Command {
 	cmd, helmAction := cmd_helm.NewUpgradeCmd(actionConfig, os.Stdout, cmd_helm.UpgradeCmdOptions{
-		PostRenderer: wc.ExtraAnnotationsAndLabelsPostRenderer,
+		PostRenderer: wc.GetPostRenderer(),
 	})
 	SetupRenderRelatedWerfChartParams(cmd, &upgradeCmdData)
 
@@ -16,6 +16,7 @@ func SetupRenderRelatedWerfChartParams(cmd *cobra.Command, commonCmdData *cmd_we
 
 	cmd_werf_common.SetupSecretValues(commonCmdData, cmd)
 	cmd_werf_common.SetupIgnoreSecretKey(commonCmdData, cmd)
+	cmd_werf_common.SetupSecretValueProvider(commonCmdData, cmd)
 }
 
 func InitRenderRelatedWerfChartParams(ctx context.Context, commonCmdData *cmd_werf_common.CmdData, wc *werf_chart.WerfChart, chartDir string) error {
@@ -34,11 +35,17 @@ func InitRenderRelatedWerfChartParams(ctx context.Context, commonCmdData *cmd_we
 	wc.SecretValueFiles = *commonCmdData.SecretValues
 	// NOTE: project-dir is the same as chart-dir for werf helm install/upgrade commands
 	// NOTE: project-dir is werf-project dir only for werf converge/dismiss commands
-	if m, err := deploy.GetSafeSecretManager(ctx, chartDir, chartDir, *commonCmdData.SecretValues, wc.LocalGitRepo, *commonCmdData.IgnoreSecretKey); err != nil {
+	if m, err := deploy.GetSafeSecretManager(ctx, chartDir, chartDir, "", *commonCmdData.SecretValues, wc.LocalGitRepo, *commonCmdData.IgnoreSecretKey); err != nil {
 		return err
 	} else {
 		wc.SecretsManager = m
 	}
 
+	if p, err := cmd_werf_common.GetSecretValueProvider(commonCmdData, chartDir, ""); err != nil {
+		return err
+	} else {
+		wc.SecretValueProvider = p
+	}
+
 	return nil
 }
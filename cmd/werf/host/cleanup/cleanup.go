@@ -27,10 +27,17 @@ func NewCmd() *cobra.Command {
 
 The data include:
 * Lost docker containers and images from interrupted builds.
+* Least recently used local stages.
 * Old service tmp dirs, which werf creates during every build, converge and other commands.
 * Local cache:
   * Remote git clones cache.
   * Git worktree cache.
+  * Manifest cache.
+
+The least recently used local stages and local cache records are removed when the limits are exceeded:
+* local stages: --allowed-docker-storage-volume-usage;
+* git cache: --git-cache-max-age and --git-cache-max-size;
+* manifest cache: $WERF_MANIFEST_CACHE_MAX_SIZE (e.g. 500MiB) and $WERF_MANIFEST_CACHE_MAX_ENTRIES.
 
 It is safe to run this command periodically by automated cleanup job in parallel with other werf commands such as build, converge and cleanup.`),
 		DisableFlagsInUseLine: true,
@@ -58,6 +65,8 @@ It is safe to run this command periodically by automated cleanup job in parallel
 	common.SetupLogOptions(&commonCmdData, cmd)
 
 	common.SetupDryRun(&commonCmdData, cmd)
+	common.SetupAllowedDockerStorageVolumeUsage(&commonCmdData, cmd)
+	common.SetupGitCacheLimits(&commonCmdData, cmd)
 
 	return cmd
 }
@@ -95,8 +104,28 @@ func runGC() error {
 	}
 	ctx = ctxWithDockerCli
 
+	allowedDockerStorageVolumeUsagePercentage, err := common.GetAllowedDockerStorageVolumeUsagePercentage(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	gitCacheMaxAge, err := common.GetGitCacheMaxAge(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	gitCacheMaxSize, err := common.GetGitCacheMaxSize(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	logboek.LogOptionalLn()
-	hostCleanupOptions := host_cleaning.HostCleanupOptions{DryRun: *commonCmdData.DryRun}
+	hostCleanupOptions := host_cleaning.HostCleanupOptions{
+		AllowedDockerStorageVolumeUsagePercentage: allowedDockerStorageVolumeUsagePercentage,
+		GitCacheMaxAge:  gitCacheMaxAge,
+		GitCacheMaxSize: gitCacheMaxSize,
+		DryRun:          *commonCmdData.DryRun,
+	}
 	if err := host_cleaning.HostCleanup(ctx, hostCleanupOptions); err != nil {
 		return err
 	}
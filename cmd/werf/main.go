@@ -46,6 +46,7 @@ import (
 	"github.com/werf/werf/cmd/werf/version"
 
 	stage_image "github.com/werf/werf/cmd/werf/stage/image"
+	stage_restore "github.com/werf/werf/cmd/werf/stage/restore"
 
 	"github.com/werf/werf/cmd/werf/common"
 	"github.com/werf/werf/cmd/werf/common/templates"
@@ -197,11 +198,12 @@ func managedImagesCmd() *cobra.Command {
 
 func stageCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:    "stage",
-		Hidden: true,
+		Use:   "stage",
+		Short: "Work with project stages",
 	}
 	cmd.AddCommand(
 		stage_image.NewCmd(),
+		stage_restore.NewCmd(),
 	)
 
 	return cmd
@@ -77,6 +77,7 @@ WARNING: Do not run this command during any other werf command is working on the
 	common.SetupKubeContext(&commonCmdData, cmd)
 
 	common.SetupDryRun(&commonCmdData, cmd)
+	common.SetupReportPath(&commonCmdData, cmd)
 	cmd.Flags().BoolVarP(&cmdData.Force, "force", "", false, common.CleaningCommandsForceOptionDescription)
 
 	return cmd
@@ -180,6 +181,7 @@ func runPurge() error {
 
 	purgeOptions := cleaning.PurgeOptions{
 		RmContainersThatUseWerfImages: cmdData.Force,
+		ReportPath:                    *commonCmdData.ReportPath,
 		DryRun:                        *commonCmdData.DryRun,
 	}
 
@@ -0,0 +1,165 @@
+package restore
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "restore STAGE_ID",
+		DisableFlagsInUseLine: true,
+		Short:                 "Restore the stage from the quarantine",
+		Long: common.GetLongCommandDescription(`Restore the stage that was moved into the quarantine by werf cleanup with --quarantine-grace-period-days option.
+
+STAGE_ID is the stage tag in the repo (<digest>-<timestamp>), which is listed in the cleanup log and report.
+The restored stage has the same image ID, but the manifest digest may differ from the original one.
+The restored stage is marked with werf.io/restored-at annotation and is never deleted by werf cleanup, only werf purge deletes it.`),
+		Example: `  $ werf stage restore --repo registry.mydomain.com/myproject/werf 3b0d9c2e0f6a8a6f7d7d5c4e2b2a9b3c0d1e2f3a4b5c6d7e8f9a0b1c-1603210000000`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if err := common.ValidateArgumentCount(1, args, cmd); err != nil {
+				return err
+			}
+
+			return run(args[0])
+		},
+	}
+
+	common.SetupProjectName(&commonCmdData, cmd)
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismInspectorOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, push and delete images in the specified repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func run(stageID string) error {
+	ctx := common.BackgroundContext()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := common.InitGiterminismInspector(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := git_repo.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = ctxWithDockerCli
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	giterminismManager, err := common.GetGiterminismManager(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfig, err := common.GetOptionalWerfConfig(ctx, projectDir, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	var projectName string
+	if werfConfig != nil {
+		projectName = werfConfig.Meta.Project
+	} else if *commonCmdData.ProjectName != "" {
+		projectName = *commonCmdData.ProjectName
+	} else {
+		return fmt.Errorf("run command in the project directory with werf.yaml or specify --project-name=PROJECT_NAME param")
+	}
+
+	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+	stagesStorage, err := common.GetStagesStorage(common.GetOptionalStagesStorageAddress(&commonCmdData), containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	repoStagesStorage, ok := stagesStorage.(*storage.RepoStagesStorage)
+	if !ok {
+		return fmt.Errorf("quarantine is not supported for the stages storage %s: specify --repo", stagesStorage.String())
+	}
+
+	var stageDesc *image.StageDescription
+	if err := logboek.Default().LogProcess("Restoring stage %s", stageID).DoError(func() error {
+		stageDesc, err = repoStagesStorage.RestoreStage(ctx, projectName, stageID)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// the manifest cache may keep the original stage digest
+	if err := image.CommonManifestCache.StoreImageInfo(ctx, repoStagesStorage.String(), stageDesc.Info); err != nil {
+		return fmt.Errorf("unable to store image %s info into the manifest cache: %s", stageDesc.Info.Name, err)
+	}
+
+	logboek.Default().LogF("Restored %s\n", stageDesc.Info.Name)
+
+	return nil
+}
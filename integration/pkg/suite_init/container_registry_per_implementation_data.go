@@ -94,6 +94,16 @@ func ContainerRegistryImplementationDockerRegistryOptionsAndSetEnvs(implementati
 	)
 
 	switch implementationName {
+	case docker_registry.ArtifactoryImplementationName:
+		username := utils.GetRequiredEnv(usernameEnvName)
+		password := utils.GetRequiredEnv(passwordEnvName)
+
+		return docker_registry.DockerRegistryOptions{
+			InsecureRegistry:      false,
+			SkipTlsVerifyRegistry: false,
+			ArtifactoryUsername:   username,
+			ArtifactoryPassword:   password,
+		}
 	case docker_registry.DockerHubImplementationName:
 		username := utils.GetRequiredEnv(usernameEnvName)
 		password := utils.GetRequiredEnv(passwordEnvName)
@@ -122,6 +132,16 @@ func ContainerRegistryImplementationDockerRegistryOptionsAndSetEnvs(implementati
 			HarborUsername:        username,
 			HarborPassword:        password,
 		}
+	case docker_registry.NexusImplementationName:
+		username := utils.GetRequiredEnv(usernameEnvName)
+		password := utils.GetRequiredEnv(passwordEnvName)
+
+		return docker_registry.DockerRegistryOptions{
+			InsecureRegistry:      false,
+			SkipTlsVerifyRegistry: false,
+			NexusUsername:         username,
+			NexusPassword:         password,
+		}
 	case docker_registry.QuayImplementationName:
 		tokenEnvName := fmt.Sprintf(
 			"WERF_TEST_%s_TOKEN",
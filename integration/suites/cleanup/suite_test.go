@@ -253,6 +253,19 @@ func implementationDockerRegistryOptionsAndSetEnvs(implementationName string) do
 	)
 
 	switch implementationName {
+	case docker_registry.ArtifactoryImplementationName:
+		username := utils.GetRequiredEnv(usernameEnvName)
+		password := utils.GetRequiredEnv(passwordEnvName)
+
+		SuiteData.Stubs.SetEnv("WERF_REPO_ARTIFACTORY_USERNAME", username)
+		SuiteData.Stubs.SetEnv("WERF_REPO_ARTIFACTORY_PASSWORD", password)
+
+		return docker_registry.DockerRegistryOptions{
+			InsecureRegistry:      false,
+			SkipTlsVerifyRegistry: false,
+			ArtifactoryUsername:   username,
+			ArtifactoryPassword:   password,
+		}
 	case docker_registry.DockerHubImplementationName:
 		username := utils.GetRequiredEnv(usernameEnvName)
 		password := utils.GetRequiredEnv(passwordEnvName)
@@ -286,6 +299,19 @@ func implementationDockerRegistryOptionsAndSetEnvs(implementationName string) do
 			HarborUsername:        username,
 			HarborPassword:        password,
 		}
+	case docker_registry.NexusImplementationName:
+		username := utils.GetRequiredEnv(usernameEnvName)
+		password := utils.GetRequiredEnv(passwordEnvName)
+
+		SuiteData.Stubs.SetEnv("WERF_REPO_NEXUS_USERNAME", username)
+		SuiteData.Stubs.SetEnv("WERF_REPO_NEXUS_PASSWORD", password)
+
+		return docker_registry.DockerRegistryOptions{
+			InsecureRegistry:      false,
+			SkipTlsVerifyRegistry: false,
+			NexusUsername:         username,
+			NexusPassword:         password,
+		}
 	case docker_registry.QuayImplementationName:
 		tokenEnvName := fmt.Sprintf(
 			"WERF_TEST_%s_TOKEN",
@@ -318,10 +344,10 @@ func implementationBeforeEach(implementationName string) {
 
 func implementationAfterEach(implementationName string) {
 	switch implementationName {
-	case docker_registry.AzureCrImplementationName, docker_registry.AwsEcrImplementationName, docker_registry.DockerHubImplementationName, docker_registry.GitHubPackagesImplementationName, docker_registry.HarborImplementationName, docker_registry.QuayImplementationName:
+	case docker_registry.ArtifactoryImplementationName, docker_registry.AzureCrImplementationName, docker_registry.AwsEcrImplementationName, docker_registry.DockerHubImplementationName, docker_registry.GitHubPackagesImplementationName, docker_registry.HarborImplementationName, docker_registry.NexusImplementationName, docker_registry.QuayImplementationName:
 		err := SuiteData.StagesStorage.DeleteRepo(context.Background())
 		switch err := err.(type) {
-		case nil, docker_registry.AzureCrNotFoundError, docker_registry.DockerHubNotFoundError, docker_registry.HarborNotFoundError, docker_registry.QuayNotFoundError:
+		case nil, docker_registry.ArtifactoryNotFoundError, docker_registry.AzureCrNotFoundError, docker_registry.DockerHubNotFoundError, docker_registry.HarborNotFoundError, docker_registry.NexusNotFoundError, docker_registry.QuayNotFoundError:
 		default:
 			Ω(err).Should(Succeed())
 		}
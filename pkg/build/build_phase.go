@@ -317,6 +317,8 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 		logboek.Context(ctx).Default().LogFHighlight("Use cache image for %s\n", stg.LogDetailedName())
 		logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), true)
 
+		touchStageImageLastUse(ctx, stg, stg.GetImage().Name())
+
 		logboek.Context(ctx).LogOptionalLn()
 
 		if phase.IntrospectOptions.ImageStageShouldBeIntrospected(img.GetName(), string(stg.Name())) {
@@ -664,11 +666,14 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 				} else {
 					stageImageObj.SetStageDescription(desc)
 				}
+
 				return nil
 			}); err != nil {
 				return err
 			}
 
+			touchStageImageLastUse(ctx, stg, stageImageObj.Name())
+
 			var stageIDs []image.StageID
 			for _, stageDesc := range stages {
 				stageIDs = append(stageIDs, *stageDesc.StageID)
@@ -680,6 +685,13 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 	}
 }
 
+// touchStageImageLastUse updates the last use of the local stage image for the host cleanup, the failure does not affect the build
+func touchStageImageLastUse(ctx context.Context, stg stage.Interface, imageName string) {
+	if err := imagePkg.CommonLastUseCache.Touch(ctx, imageName); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: unable to update last use of stage %s image %s: %s\n", stg.LogDetailedName(), imageName, err)
+	}
+}
+
 func introspectStage(ctx context.Context, s stage.Interface) error {
 	return logboek.Context(ctx).Info().LogProcess("Introspecting stage %s", s.Name()).
 		Options(func(options types.LogProcessOptionsInterface) {
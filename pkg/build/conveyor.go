@@ -27,6 +27,7 @@ import (
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/giterminism_inspector"
 	"github.com/werf/werf/pkg/image"
@@ -81,6 +82,7 @@ type ConveyorOptions struct {
 	Parallel                        bool
 	ParallelTasksLimit              int64
 	LocalGitRepoVirtualMergeOptions stage.VirtualMergeOptions
+	RegistryMirrors                 docker_registry.RegistryMirrors
 }
 
 func NewConveyor(werfConfig *config.WerfConfig, localGitRepo git_repo.Local, imageNamesToProcess []string, projectDir, baseTmpDir, sshAuthSock string, containerRuntime container_runtime.ContainerRuntime, storageManager *manager.StorageManager, storageLockManager storage.LockManager, opts ConveyorOptions) *Conveyor {
@@ -191,6 +193,12 @@ func (c *Conveyor) GetLocalGitRepoVirtualMergeOptions() stage.VirtualMergeOption
 	return c.ConveyorOptions.LocalGitRepoVirtualMergeOptions
 }
 
+// GetRegistryMirrorReference returns the reference in the registry mirror or an empty string if there is no mirror.
+// The mirrors from options take precedence over the mirrors from werf.yaml.
+func (c *Conveyor) GetRegistryMirrorReference(reference string) string {
+	return c.werfConfig.Meta.RegistryMirrors.Merge(c.ConveyorOptions.RegistryMirrors).MirrorReference(reference)
+}
+
 func (c *Conveyor) GetImportServer(ctx context.Context, imageName, stageName string) (import_server.ImportServer, error) {
 	c.getServiceRWMutex("ImportServer").Lock()
 	defer c.getServiceRWMutex("ImportServer").Unlock()
@@ -178,6 +178,14 @@ func (i *Image) FetchBaseImage(ctx context.Context, c *Conveyor) error {
 				options.Style(style.Highlight())
 			}).
 			DoError(func() error {
+				if mirrorReference := c.GetRegistryMirrorReference(i.baseImage.Name()); mirrorReference != "" {
+					if err := containerRuntime.PullImageThroughMirror(ctx, i.baseImage.Name(), mirrorReference); err != nil {
+						return err
+					}
+
+					return c.ContainerRuntime.RefreshImageObject(ctx, &container_runtime.DockerImage{Image: i.baseImage})
+				}
+
 				return c.ContainerRuntime.PullImageFromRegistry(ctx, &container_runtime.DockerImage{Image: i.baseImage})
 			}); err != nil {
 			return err
@@ -224,7 +232,7 @@ func (i *Image) getFromBaseImageIdFromRegistry(ctx context.Context, c *Conveyor,
 	processMsg := fmt.Sprintf("Trying to get from base image id from registry (%s)", baseImageName)
 	if err := logboek.Context(ctx).Info().LogProcessInline(processMsg).DoError(func() error {
 		var fetchImageIdErr error
-		fetchedBaseRepoImage, fetchImageIdErr = docker_registry.API().GetRepoImage(ctx, baseImageName)
+		fetchedBaseRepoImage, fetchImageIdErr = getBaseRepoImage(ctx, c, baseImageName)
 		if fetchImageIdErr != nil {
 			c.SetBaseImagesRepoErrCache(baseImageName, fetchImageIdErr)
 			return fmt.Errorf("can not get base image id from registry (%s): %s", baseImageName, fetchImageIdErr)
@@ -240,3 +248,18 @@ func (i *Image) getFromBaseImageIdFromRegistry(ctx context.Context, c *Conveyor,
 
 	return i.baseImageRepoId, nil
 }
+
+// getBaseRepoImage gets the image info from the registry mirror if there is one and from the registry otherwise or if the mirror fails.
+// The image id is the same in the mirror and in the registry, so the stages digests do not depend on mirrors.
+func getBaseRepoImage(ctx context.Context, c *Conveyor, baseImageName string) (*image.Info, error) {
+	if mirrorReference := c.GetRegistryMirrorReference(baseImageName); mirrorReference != "" {
+		info, err := docker_registry.API().GetRepoImage(ctx, mirrorReference)
+		if err == nil {
+			return info, nil
+		}
+
+		logboek.Context(ctx).Warn().LogF("WARNING: cannot get base image %s from mirror %s: %s\n", baseImageName, mirrorReference, err)
+	}
+
+	return docker_registry.API().GetRepoImage(ctx, baseImageName)
+}
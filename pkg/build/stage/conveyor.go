@@ -23,6 +23,7 @@ type Conveyor interface {
 
 	GetImportServer(ctx context.Context, imageName, stageName string) (import_server.ImportServer, error)
 	GetLocalGitRepoVirtualMergeOptions() VirtualMergeOptions
+	GetRegistryMirrorReference(reference string) string
 
 	GetProjectRepoCommit(ctx context.Context) (string, error)
 }
@@ -261,7 +261,7 @@ type dockerfileInstructionInterface interface {
 	Name() string
 }
 
-func (s *DockerfileStage) FetchDependencies(ctx context.Context, _ Conveyor, cr container_runtime.ContainerRuntime) error {
+func (s *DockerfileStage) FetchDependencies(ctx context.Context, c Conveyor, cr container_runtime.ContainerRuntime) error {
 	containerRuntime := cr.(*container_runtime.LocalDockerServerRuntime)
 
 outerLoop:
@@ -300,6 +300,15 @@ outerLoop:
 		}
 
 		getBaseImageOnBuildRemotely := func() ([]string, error) {
+			if mirrorReference := c.GetRegistryMirrorReference(resolvedBaseName); mirrorReference != "" {
+				configFile, err := docker_registry.API().GetRepoImageConfigFile(ctx, mirrorReference)
+				if err == nil {
+					return configFile.Config.OnBuild, nil
+				}
+
+				logboek.Context(ctx).Warn().LogF("WARNING: cannot get base image %s config file from mirror %s: %s\n", resolvedBaseName, mirrorReference, err)
+			}
+
 			configFile, err := docker_registry.API().GetRepoImageConfigFile(ctx, resolvedBaseName)
 			if err != nil {
 				return nil, fmt.Errorf("get repo image %s config file failed: %s", resolvedBaseName, err)
@@ -318,6 +327,10 @@ outerLoop:
 					logboek.Context(ctx).Warn().LogF("WARNING: Could not get base image manifest from local docker and from docker registry: %s\n", getRemotelyErr)
 					logboek.Context(ctx).Warn().LogLn("WARNING: The base image pulling is necessary for calculating digest of image correctly\n")
 					if err := logboek.Context(ctx).Default().LogProcess("Pulling base image %s", resolvedBaseName).DoError(func() error {
+						if mirrorReference := c.GetRegistryMirrorReference(resolvedBaseName); mirrorReference != "" {
+							return containerRuntime.PullImageThroughMirror(ctx, resolvedBaseName, mirrorReference)
+						}
+
 						return containerRuntime.PullImage(ctx, resolvedBaseName)
 					}); err != nil {
 						return err
@@ -614,6 +627,12 @@ func (s *DockerfileStage) PrepareImage(ctx context.Context, c Conveyor, _, img c
 	img.DockerfileImageBuilder().AppendBuildArgs(fmt.Sprintf("--label=%s=%s", image.WerfProjectRepoCommitLabel, commit))
 	img.DockerfileImageBuilder().SetFilePathToStdin(archivePath)
 
+	for resolvedBaseName := range s.imageOnBuildInstructions {
+		if mirrorReference := c.GetRegistryMirrorReference(resolvedBaseName); mirrorReference != "" {
+			img.DockerfileImageBuilder().AddBaseImageMirror(resolvedBaseName, mirrorReference)
+		}
+	}
+
 	if giterminism_inspector.DevMode {
 		img.DockerfileImageBuilder().AppendBuildArgs(fmt.Sprintf("--label=%s=true", image.WerfDevLabel))
 	}
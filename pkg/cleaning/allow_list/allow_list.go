@@ -0,0 +1,15 @@
+package allow_list
+
+import "context"
+
+// DeployedDockerImage is the docker image used outside of the repo, Location describes where the image is used (e.g. the Kubernetes namespace) and may be empty
+type DeployedDockerImage struct {
+	Name     string
+	Location string
+}
+
+// Provider is the source of images that must not be deleted by cleanup
+type Provider interface {
+	DeployedDockerImages(ctx context.Context) ([]DeployedDockerImage, error)
+	String() string
+}
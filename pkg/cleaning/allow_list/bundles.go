@@ -0,0 +1,154 @@
+package allow_list
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/storage"
+)
+
+// helm chart media types as pushed by werf bundle publish (helm registry package is internal)
+const (
+	HelmChartConfigMediaType       = "application/vnd.cncf.helm.config.v1+json"
+	HelmChartContentLayerMediaType = "application/tar+gzip"
+)
+
+// BundlesProvider allows images referenced by the werf bundles published into the repo
+type BundlesProvider struct {
+	DockerRegistry docker_registry.DockerRegistry
+	RepoAddress    string
+}
+
+func (p *BundlesProvider) DeployedDockerImages(ctx context.Context) ([]DeployedDockerImage, error) {
+	tags, err := p.DockerRegistry.Tags(ctx, p.RepoAddress)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch tags for repo %q: %s", p.RepoAddress, err)
+	}
+
+	var images []DeployedDockerImage
+	for _, tag := range tags {
+		if storage.IsRepoServiceTag(tag) {
+			continue
+		}
+
+		bundleRef := fmt.Sprintf("%s:%s", p.RepoAddress, tag)
+		names, err := p.bundleImages(ctx, bundleRef)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get images of bundle %s: %s", bundleRef, err)
+		}
+
+		for _, name := range names {
+			images = append(images, DeployedDockerImage{Name: name, Location: fmt.Sprintf("bundle %s", bundleRef)})
+		}
+	}
+
+	return images, nil
+}
+
+func (p *BundlesProvider) String() string {
+	return fmt.Sprintf("werf bundles (repo %s)", p.RepoAddress)
+}
+
+// bundleImages returns images from the werf service values of the bundle or nothing if the reference is not a helm chart
+func (p *BundlesProvider) bundleImages(ctx context.Context, bundleRef string) ([]string, error) {
+	manifest, err := p.DockerRegistry.GetRepoImageManifest(ctx, bundleRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(manifest.Config.MediaType) != HelmChartConfigMediaType {
+		return nil, nil
+	}
+
+	for _, layer := range manifest.Layers {
+		if string(layer.MediaType) != HelmChartContentLayerMediaType {
+			continue
+		}
+
+		logboek.Context(ctx).Debug().LogF("Reading bundle %s chart layer %s\n", bundleRef, layer.Digest.String())
+
+		rc, err := p.DockerRegistry.GetRepoImageLayer(ctx, bundleRef, layer.Digest.String())
+		if err != nil {
+			return nil, err
+		}
+
+		images, err := chartArchiveWerfImages(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		return images, nil
+	}
+
+	return nil, nil
+}
+
+func chartArchiveWerfImages(archive io.Reader) ([]string, error) {
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read chart archive: %s", err)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read chart archive: %s", err)
+		}
+
+		// only top level values.yaml (<chart>/values.yaml) contains werf service values
+		parts := strings.Split(header.Name, "/")
+		if len(parts) != 2 || parts[1] != "values.yaml" {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %s", header.Name, err)
+		}
+
+		return valuesWerfImages(data)
+	}
+}
+
+// valuesWerfImages returns werf.image service value, which is either the image or the map of werf.yaml image names to images
+func valuesWerfImages(data []byte) ([]string, error) {
+	var values struct {
+		Werf struct {
+			Image interface{} `json:"image"`
+		} `json:"werf"`
+	}
+
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("unable to parse values: %s", err)
+	}
+
+	switch image := values.Werf.Image.(type) {
+	case string:
+		return []string{image}, nil
+	case map[string]interface{}:
+		var images []string
+		for _, value := range image {
+			if name, ok := value.(string); ok {
+				images = append(images, name)
+			}
+		}
+		return images, nil
+	default:
+		return nil, nil
+	}
+}
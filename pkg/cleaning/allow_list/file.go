@@ -0,0 +1,44 @@
+package allow_list
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileProvider allows images listed in the file, one image reference per line.
+// Empty lines and lines starting with # are ignored.
+type FileProvider struct {
+	Path string
+}
+
+func (p *FileProvider) DeployedDockerImages(_ context.Context) ([]DeployedDockerImage, error) {
+	f, err := os.Open(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %s", p.Path, err)
+	}
+	defer f.Close()
+
+	var images []DeployedDockerImage
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		images = append(images, DeployedDockerImage{Name: line})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %s", p.Path, err)
+	}
+
+	return images, nil
+}
+
+func (p *FileProvider) String() string {
+	return fmt.Sprintf("allow list file %s", p.Path)
+}
@@ -0,0 +1,67 @@
+package allow_list
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const HTTPProviderTimeout = 30 * time.Second
+
+// HTTPProvider allows images returned by the endpoint as JSON array of image references (e.g. ["registry.example.com/app:tag"]).
+// The client with HTTPProviderTimeout is used if Client is not set.
+type HTTPProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+func (p *HTTPProvider) DeployedDockerImages(ctx context.Context) ([]DeployedDockerImage, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: HTTPProviderTimeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare request %s: %s", p.URL, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %s failed: %s", p.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request %s failed: unexpected status %s", p.URL, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response %s: %s", p.URL, err)
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("unable to parse response %s: expected JSON array of image references: %s", p.URL, err)
+	}
+
+	var images []DeployedDockerImage
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		images = append(images, DeployedDockerImage{Name: name})
+	}
+
+	return images, nil
+}
+
+func (p *HTTPProvider) String() string {
+	return fmt.Sprintf("allow list url %s", p.URL)
+}
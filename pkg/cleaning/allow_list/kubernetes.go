@@ -4,12 +4,56 @@ import (
 	"context"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/werf/pkg/config"
 )
 
-func DeployedDockerImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var deployedDockerImages []string
+// KubernetesProvider allows images used by the Kubernetes resources in the context.
+// DynamicClient is required only to scan the additional Resources.
+type KubernetesProvider struct {
+	ContextName   string
+	Client        kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Namespace     string
+	Resources     []*config.MetaCleanupKubernetesResource
+}
+
+func (p *KubernetesProvider) DeployedDockerImages(ctx context.Context) ([]DeployedDockerImage, error) {
+	deployedDockerImages, err := DeployedDockerImages(p.Client, p.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.Resources) != 0 && p.DynamicClient == nil {
+		return nil, fmt.Errorf("dynamic client is required to scan the Kubernetes resources")
+	}
+
+	for _, resource := range p.Resources {
+		images, err := getKubernetesResourcesImages(ctx, p.Client, p.DynamicClient, p.Namespace, resource)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get %s images: %s", resource.String(), err)
+		}
+
+		deployedDockerImages = append(deployedDockerImages, images...)
+	}
+
+	return deployedDockerImages, nil
+}
+
+func (p *KubernetesProvider) String() string {
+	return fmt.Sprintf("Kubernetes (context %s)", p.ContextName)
+}
+
+func namespaceLocation(namespace string) string {
+	return fmt.Sprintf("namespace %q", namespace)
+}
+
+func DeployedDockerImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var deployedDockerImages []DeployedDockerImage
 
 	images, err := getPodsImages(kubernetesClient, kubernetesNamespace)
 	if err != nil {
@@ -70,130 +114,148 @@ func DeployedDockerImages(kubernetesClient kubernetes.Interface, kubernetesNames
 	return deployedDockerImages, nil
 }
 
-func getPodsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var images []string
+func getPodsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var images []DeployedDockerImage
 	list, err := kubernetesClient.CoreV1().Pods(kubernetesNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, pod := range list.Items {
-		for _, container := range pod.Spec.Containers {
-			images = append(images, container.Image)
+		for _, name := range podSpecImages(pod.Spec) {
+			images = append(images, DeployedDockerImage{Name: name, Location: namespaceLocation(pod.Namespace)})
 		}
 	}
 
 	return images, nil
 }
 
-func getReplicationControllersImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var images []string
+func getReplicationControllersImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var images []DeployedDockerImage
 	list, err := kubernetesClient.CoreV1().ReplicationControllers(kubernetesNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, replicationController := range list.Items {
-		for _, container := range replicationController.Spec.Template.Spec.Containers {
-			images = append(images, container.Image)
+		for _, name := range podSpecImages(replicationController.Spec.Template.Spec) {
+			images = append(images, DeployedDockerImage{Name: name, Location: namespaceLocation(replicationController.Namespace)})
 		}
 	}
 
 	return images, nil
 }
 
-func getDeploymentsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var images []string
+func getDeploymentsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var images []DeployedDockerImage
 	list, err := kubernetesClient.AppsV1().Deployments(kubernetesNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, deployment := range list.Items {
-		for _, container := range deployment.Spec.Template.Spec.Containers {
-			images = append(images, container.Image)
+		for _, name := range podSpecImages(deployment.Spec.Template.Spec) {
+			images = append(images, DeployedDockerImage{Name: name, Location: namespaceLocation(deployment.Namespace)})
 		}
 	}
 
 	return images, nil
 }
 
-func getStatefulSetsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var images []string
+func getStatefulSetsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var images []DeployedDockerImage
 	list, err := kubernetesClient.AppsV1().StatefulSets(kubernetesNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, statefulSet := range list.Items {
-		for _, container := range statefulSet.Spec.Template.Spec.Containers {
-			images = append(images, container.Image)
+		for _, name := range podSpecImages(statefulSet.Spec.Template.Spec) {
+			images = append(images, DeployedDockerImage{Name: name, Location: namespaceLocation(statefulSet.Namespace)})
 		}
 	}
 
 	return images, nil
 }
 
-func getDaemonSetsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var images []string
+func getDaemonSetsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var images []DeployedDockerImage
 	list, err := kubernetesClient.AppsV1().DaemonSets(kubernetesNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, daemonSets := range list.Items {
-		for _, container := range daemonSets.Spec.Template.Spec.Containers {
-			images = append(images, container.Image)
+		for _, name := range podSpecImages(daemonSets.Spec.Template.Spec) {
+			images = append(images, DeployedDockerImage{Name: name, Location: namespaceLocation(daemonSets.Namespace)})
 		}
 	}
 
 	return images, nil
 }
 
-func getReplicaSetsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var images []string
+func getReplicaSetsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var images []DeployedDockerImage
 	list, err := kubernetesClient.AppsV1().ReplicaSets(kubernetesNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, replicaSet := range list.Items {
-		for _, container := range replicaSet.Spec.Template.Spec.Containers {
-			images = append(images, container.Image)
+		for _, name := range podSpecImages(replicaSet.Spec.Template.Spec) {
+			images = append(images, DeployedDockerImage{Name: name, Location: namespaceLocation(replicaSet.Namespace)})
 		}
 	}
 
 	return images, nil
 }
 
-func getCronJobsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var images []string
+func getCronJobsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var images []DeployedDockerImage
 	list, err := kubernetesClient.BatchV1beta1().CronJobs(kubernetesNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, cronJob := range list.Items {
-		for _, container := range cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers {
-			images = append(images, container.Image)
+		for _, name := range podSpecImages(cronJob.Spec.JobTemplate.Spec.Template.Spec) {
+			images = append(images, DeployedDockerImage{Name: name, Location: namespaceLocation(cronJob.Namespace)})
 		}
 	}
 
 	return images, nil
 }
 
-func getJobsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]string, error) {
-	var images []string
+func getJobsImages(kubernetesClient kubernetes.Interface, kubernetesNamespace string) ([]DeployedDockerImage, error) {
+	var images []DeployedDockerImage
 	list, err := kubernetesClient.BatchV1().Jobs(kubernetesNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, job := range list.Items {
-		for _, container := range job.Spec.Template.Spec.Containers {
-			images = append(images, container.Image)
+		for _, name := range podSpecImages(job.Spec.Template.Spec) {
+			images = append(images, DeployedDockerImage{Name: name, Location: namespaceLocation(job.Namespace)})
 		}
 	}
 
 	return images, nil
 }
+
+// podSpecImages returns images of containers, init containers and ephemeral containers
+func podSpecImages(spec corev1.PodSpec) []string {
+	var images []string
+	for _, container := range spec.InitContainers {
+		images = append(images, container.Image)
+	}
+
+	for _, container := range spec.Containers {
+		images = append(images, container.Image)
+	}
+
+	for _, container := range spec.EphemeralContainers {
+		images = append(images, container.Image)
+	}
+
+	return images
+}
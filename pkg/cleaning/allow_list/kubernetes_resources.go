@@ -0,0 +1,98 @@
+package allow_list
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/config"
+)
+
+// getKubernetesResourcesImages returns images of the configured resources (e.g. Argo Rollouts) selected by jsonpath expressions.
+// The resource is skipped if it is not served by the cluster.
+func getKubernetesResourcesImages(ctx context.Context, kubernetesClient kubernetes.Interface, dynamicClient dynamic.Interface, kubernetesNamespace string, resource *config.MetaCleanupKubernetesResource) ([]DeployedDockerImage, error) {
+	groupVersionResource, namespaced, err := groupVersionResourceByApiVersionAndKind(kubernetesClient, resource.ApiVersion, resource.Kind)
+	if err != nil {
+		return nil, err
+	} else if groupVersionResource == nil {
+		logboek.Context(ctx).Info().LogF("Kubernetes resource %s is not served by the cluster: skipping\n", resource.String())
+		return nil, nil
+	}
+
+	var resourceInterface dynamic.ResourceInterface
+	if namespaced {
+		resourceInterface = dynamicClient.Resource(*groupVersionResource).Namespace(kubernetesNamespace)
+	} else {
+		resourceInterface = dynamicClient.Resource(*groupVersionResource)
+	}
+
+	list, err := resourceInterface.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var images []DeployedDockerImage
+	for _, imagesJsonPath := range resource.ImagesJsonPaths {
+		j := jsonpath.New(resource.String())
+		j.AllowMissingKeys(true)
+		if err := j.Parse(imagesJsonPath); err != nil {
+			return nil, fmt.Errorf("invalid jsonpath %q: %s", imagesJsonPath, err)
+		}
+
+		for _, item := range list.Items {
+			results, err := j.FindResults(item.UnstructuredContent())
+			if err != nil {
+				return nil, fmt.Errorf("unable to find %q in %s %s: %s", imagesJsonPath, item.GetKind(), item.GetName(), err)
+			}
+
+			var location string
+			if namespaced {
+				location = namespaceLocation(item.GetNamespace())
+			}
+
+			for _, result := range results {
+				for _, value := range result {
+					if name, ok := value.Interface().(string); ok && name != "" {
+						images = append(images, DeployedDockerImage{Name: name, Location: location})
+					}
+				}
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// groupVersionResourceByApiVersionAndKind returns nil if the kind is not served by the cluster
+func groupVersionResourceByApiVersionAndKind(kubernetesClient kubernetes.Interface, apiVersion, kind string) (*schema.GroupVersionResource, bool, error) {
+	groupVersion, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid apiVersion %q: %s", apiVersion, err)
+	}
+
+	list, err := kubernetesClient.Discovery().ServerResourcesForGroupVersion(apiVersion)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("unable to discover resources for %s: %s", apiVersion, err)
+	}
+
+	for _, apiResource := range list.APIResources {
+		// skip subresources (e.g. rollouts/status) of the same kind
+		if apiResource.Kind == kind && !strings.Contains(apiResource.Name, "/") && len(apiResource.Verbs) != 0 {
+			return &schema.GroupVersionResource{Group: groupVersion.Group, Version: groupVersion.Version, Resource: apiResource.Name}, apiResource.Namespaced, nil
+		}
+	}
+
+	return nil, false, nil
+}
@@ -0,0 +1,166 @@
+package allow_list
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/werf/werf/pkg/config"
+)
+
+func TestPodSpecImages(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		spec     corev1.PodSpec
+		expected []string
+	}{
+		{
+			name:     "containers",
+			spec:     corev1.PodSpec{Containers: []corev1.Container{{Image: "app"}, {Image: "sidecar"}}},
+			expected: []string{"app", "sidecar"},
+		},
+		{
+			name: "init containers",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Image: "migrations"}},
+				Containers:     []corev1.Container{{Image: "app"}},
+			},
+			expected: []string{"migrations", "app"},
+		},
+		{
+			name: "ephemeral containers",
+			spec: corev1.PodSpec{
+				Containers:          []corev1.Container{{Image: "app"}},
+				EphemeralContainers: []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Image: "debug"}}},
+			},
+			expected: []string{"app", "debug"},
+		},
+		{
+			name: "empty spec",
+			spec: corev1.PodSpec{},
+		},
+	} {
+		if images := podSpecImages(tt.spec); !reflect.DeepEqual(images, tt.expected) {
+			t.Errorf("%s:\n[EXPECTED]: %v\n[GOT]: %v", tt.name, tt.expected, images)
+		}
+	}
+}
+
+func TestDeployedDockerImages(t *testing.T) {
+	objectMeta := metav1.ObjectMeta{Name: "app", Namespace: "default"}
+	podTemplateSpec := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Image: "init"}},
+			Containers:     []corev1.Container{{Image: "app"}},
+		},
+	}
+
+	for _, tt := range []struct {
+		kind   string
+		object runtime.Object
+	}{
+		{kind: "Pod", object: &corev1.Pod{ObjectMeta: objectMeta, Spec: podTemplateSpec.Spec}},
+		{kind: "ReplicationController", object: &corev1.ReplicationController{ObjectMeta: objectMeta, Spec: corev1.ReplicationControllerSpec{Template: &podTemplateSpec}}},
+		{kind: "Deployment", object: &appsv1.Deployment{ObjectMeta: objectMeta, Spec: appsv1.DeploymentSpec{Template: podTemplateSpec}}},
+		{kind: "StatefulSet", object: &appsv1.StatefulSet{ObjectMeta: objectMeta, Spec: appsv1.StatefulSetSpec{Template: podTemplateSpec}}},
+		{kind: "DaemonSet", object: &appsv1.DaemonSet{ObjectMeta: objectMeta, Spec: appsv1.DaemonSetSpec{Template: podTemplateSpec}}},
+		{kind: "ReplicaSet", object: &appsv1.ReplicaSet{ObjectMeta: objectMeta, Spec: appsv1.ReplicaSetSpec{Template: podTemplateSpec}}},
+		{kind: "CronJob", object: &batchv1beta1.CronJob{ObjectMeta: objectMeta, Spec: batchv1beta1.CronJobSpec{JobTemplate: batchv1beta1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: podTemplateSpec}}}}},
+		{kind: "Job", object: &batchv1.Job{ObjectMeta: objectMeta, Spec: batchv1.JobSpec{Template: podTemplateSpec}}},
+	} {
+		images, err := DeployedDockerImages(fake.NewSimpleClientset(tt.object), "default")
+		if err != nil {
+			t.Fatalf("%s: %s", tt.kind, err)
+		}
+
+		expected := []DeployedDockerImage{
+			{Name: "init", Location: `namespace "default"`},
+			{Name: "app", Location: `namespace "default"`},
+		}
+		if !reflect.DeepEqual(images, expected) {
+			t.Errorf("%s:\n[EXPECTED]: %v\n[GOT]: %v", tt.kind, expected, images)
+		}
+	}
+}
+
+func TestKubernetesResourcesImages(t *testing.T) {
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]interface{}{"name": "app", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "app", "image": "app"},
+						map[string]interface{}{"name": "sidecar", "image": "sidecar"},
+					},
+				},
+			},
+		},
+	}}
+
+	kubernetesClient := fake.NewSimpleClientset()
+	kubernetesClient.Fake.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "argoproj.io/v1alpha1",
+		APIResources: []metav1.APIResource{
+			{Name: "rollouts/status", Kind: "Rollout", Namespaced: true, Verbs: []string{"get"}},
+			{Name: "rollouts", Kind: "Rollout", Namespaced: true, Verbs: []string{"get", "list"}},
+		},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), rollout)
+
+	for _, tt := range []struct {
+		name     string
+		resource *config.MetaCleanupKubernetesResource
+		expected []DeployedDockerImage
+	}{
+		{
+			name: "containers images",
+			resource: &config.MetaCleanupKubernetesResource{
+				ApiVersion:      "argoproj.io/v1alpha1",
+				Kind:            "Rollout",
+				ImagesJsonPaths: []string{"{.spec.template.spec.containers[*].image}"},
+			},
+			expected: []DeployedDockerImage{
+				{Name: "app", Location: `namespace "default"`},
+				{Name: "sidecar", Location: `namespace "default"`},
+			},
+		},
+		{
+			name: "missing keys",
+			resource: &config.MetaCleanupKubernetesResource{
+				ApiVersion:      "argoproj.io/v1alpha1",
+				Kind:            "Rollout",
+				ImagesJsonPaths: []string{"{.spec.template.spec.initContainers[*].image}"},
+			},
+		},
+		{
+			name: "kind is not served",
+			resource: &config.MetaCleanupKubernetesResource{
+				ApiVersion:      "argoproj.io/v1alpha1",
+				Kind:            "Experiment",
+				ImagesJsonPaths: []string{"{.spec.template.spec.containers[*].image}"},
+			},
+		},
+	} {
+		images, err := getKubernetesResourcesImages(context.Background(), kubernetesClient, dynamicClient, "default", tt.resource)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+
+		if !reflect.DeepEqual(images, tt.expected) {
+			t.Errorf("%s:\n[EXPECTED]: %v\n[GOT]: %v", tt.name, tt.expected, images)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package allow_list
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+func deployedDockerImageNames(images []DeployedDockerImage) []string {
+	var names []string
+	for _, image := range images {
+		names = append(names, image.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestFileProvider(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-allow-list-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "allow_list")
+	if err := ioutil.WriteFile(path, []byte("# deployed manually\nregistry.example.com/app:v1\n\n  registry.example.com/worker:v1  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := (&FileProvider{Path: path}).DeployedDockerImages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"registry.example.com/app:v1", "registry.example.com/worker:v1"}
+	if names := deployedDockerImageNames(images); !reflect.DeepEqual(names, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, names)
+	}
+
+	if _, err := (&FileProvider{Path: filepath.Join(tmpDir, "nonexistent")}).DeployedDockerImages(context.Background()); err == nil {
+		t.Errorf("Expected error for nonexistent file")
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images":
+			_, _ = w.Write([]byte(`["registry.example.com/app:v1", "", "registry.example.com/worker:v1"]`))
+		case "/invalid":
+			_, _ = w.Write([]byte(`{"images":[]}`))
+		case "/slow":
+			time.Sleep(time.Second)
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	images, err := (&HTTPProvider{URL: server.URL + "/images"}).DeployedDockerImages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"registry.example.com/app:v1", "registry.example.com/worker:v1"}
+	if names := deployedDockerImageNames(images); !reflect.DeepEqual(names, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, names)
+	}
+
+	for _, tt := range []struct {
+		provider      *HTTPProvider
+		expectedError string
+	}{
+		{provider: &HTTPProvider{URL: server.URL + "/unknown"}, expectedError: "unexpected status 404"},
+		{provider: &HTTPProvider{URL: server.URL + "/invalid"}, expectedError: "expected JSON array of image references"},
+		{provider: &HTTPProvider{URL: server.URL + "/slow", Client: &http.Client{Timeout: 100 * time.Millisecond}}, expectedError: "Client.Timeout exceeded"},
+	} {
+		_, err := tt.provider.DeployedDockerImages(context.Background())
+		if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+			t.Errorf("%s:\n[EXPECTED]: error containing %q\n[GOT]: %v", tt.provider.URL, tt.expectedError, err)
+		}
+	}
+}
+
+// newBundlesRegistryServer serves the manifests and blobs by the tags, werf only reads the repo in the bundles provider
+func newBundlesRegistryServer(t *testing.T, manifests map[string]*v1.Manifest, blobs map[v1.Hash][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/repo/tags/list":
+			tags := []string{}
+			for tag := range manifests {
+				tags = append(tags, tag)
+			}
+			sort.Strings(tags)
+
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "repo", "tags": tags})
+		case strings.HasPrefix(r.URL.Path, "/v2/repo/manifests/"):
+			manifest, ok := manifests[strings.TrimPrefix(r.URL.Path, "/v2/repo/manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+				return
+			}
+
+			data, err := json.Marshal(manifest)
+			if err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", string(manifest.MediaType))
+			_, _ = w.Write(data)
+		case strings.HasPrefix(r.URL.Path, "/v2/repo/blobs/"):
+			hash, err := v1.NewHash(strings.TrimPrefix(r.URL.Path, "/v2/repo/blobs/"))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			data, ok := blobs[hash]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			_, _ = w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newChartArchive(t *testing.T, files map[string]string) []byte {
+	buf := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for name, content := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func newManifest(blobs map[v1.Hash][]byte, configMediaType, layerMediaType types.MediaType, layer []byte) *v1.Manifest {
+	newDescriptor := func(mediaType types.MediaType, data []byte) v1.Descriptor {
+		hash, size, _ := v1.SHA256(bytes.NewReader(data))
+		blobs[hash] = data
+		return v1.Descriptor{MediaType: mediaType, Digest: hash, Size: size}
+	}
+
+	return &v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        newDescriptor(configMediaType, []byte("{}")),
+		Layers:        []v1.Descriptor{newDescriptor(layerMediaType, layer)},
+	}
+}
+
+func TestBundlesProvider(t *testing.T) {
+	blobs := map[v1.Hash][]byte{}
+	manifests := map[string]*v1.Manifest{
+		"v1.0.0": newManifest(blobs, HelmChartConfigMediaType, HelmChartContentLayerMediaType, newChartArchive(t, map[string]string{
+			"app/values.yaml":              "werf:\n  image:\n    backend: registry.example.com/app:backend\n    frontend: registry.example.com/app:frontend\n",
+			"app/charts/redis/values.yaml": "werf:\n  image: registry.example.com/app:redis\n",
+		})),
+		"v1.1.0": newManifest(blobs, HelmChartConfigMediaType, HelmChartContentLayerMediaType, newChartArchive(t, map[string]string{
+			"app/values.yaml": "werf:\n  image: registry.example.com/app:single\n",
+		})),
+		"7e5bd2af-1611111111111": newManifest(blobs, types.OCIConfigJSON, types.OCILayer, []byte("stage")),
+		// the service tags are not bundles and must not be requested
+		"managed-image-app": nil,
+	}
+
+	server := newBundlesRegistryServer(t, manifests, blobs)
+	defer server.Close()
+
+	repoAddress := strings.TrimPrefix(server.URL, "http://") + "/repo"
+	dockerRegistry, err := docker_registry.NewDockerRegistry(repoAddress, docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &BundlesProvider{DockerRegistry: dockerRegistry, RepoAddress: repoAddress}
+
+	images, err := p.DeployedDockerImages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"registry.example.com/app:backend", "registry.example.com/app:frontend", "registry.example.com/app:single"}
+	if names := deployedDockerImageNames(images); !reflect.DeepEqual(names, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, names)
+	}
+
+	for _, image := range images {
+		if image.Name == "registry.example.com/app:single" && image.Location != fmt.Sprintf("bundle %s:v1.1.0", repoAddress) {
+			t.Errorf("\n[EXPECTED]: bundle %s:v1.1.0\n[GOT]: %s", repoAddress, image.Location)
+		}
+	}
+}
@@ -3,13 +3,16 @@ package cleaning
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/fatih/color"
 	"github.com/go-git/go-git/v5"
 	"github.com/rodaine/table"
+	"k8s.io/client-go/dynamic"
 
 	"github.com/werf/kubedog/pkg/kube"
 	"github.com/werf/logboek"
@@ -30,13 +33,24 @@ type CleanupOptions struct {
 	LocalGit                                GitRepo
 	KubernetesContextClients                []*kube.ContextClient
 	KubernetesNamespaceRestrictionByContext map[string]string
+	KubernetesDynamicClientByContext        map[string]dynamic.Interface
 	WithoutKube                             bool
+	AllowListProviders                      []allow_list.Provider
 	GitHistoryBasedCleanupOptions           config.MetaCleanup
 	KeepStagesBuiltWithinLastNHours         uint64
+	MaxRepoSize                             int64
+	QuarantineGracePeriodDays               uint64
+	ReportPath                              string
 	DryRun                                  bool
 }
 
 func Cleanup(ctx context.Context, projectName string, storageManager *manager.StorageManager, storageLockManager storage.LockManager, options CleanupOptions) error {
+	if options.QuarantineGracePeriodDays != 0 {
+		if _, ok := storageManager.StagesStorage.(*storage.RepoStagesStorage); !ok {
+			return fmt.Errorf("quarantine is not supported for the stages storage %s: specify --repo", storageManager.StagesStorage.String())
+		}
+	}
+
 	return newCleanupManager(projectName, storageManager, options).run(ctx)
 }
 
@@ -49,9 +63,16 @@ func newCleanupManager(projectName string, storageManager *manager.StorageManage
 		LocalGit:                                options.LocalGit,
 		KubernetesContextClients:                options.KubernetesContextClients,
 		KubernetesNamespaceRestrictionByContext: options.KubernetesNamespaceRestrictionByContext,
+		KubernetesDynamicClientByContext:        options.KubernetesDynamicClientByContext,
 		WithoutKube:                             options.WithoutKube,
+		AllowListProviders:                      options.AllowListProviders,
 		GitHistoryBasedCleanupOptions:           options.GitHistoryBasedCleanupOptions,
 		KeepStagesBuiltWithinLastNHours:         options.KeepStagesBuiltWithinLastNHours,
+		MaxRepoSize:                             options.MaxRepoSize,
+		QuarantineGracePeriodDays:               options.QuarantineGracePeriodDays,
+		ReportPath:                              options.ReportPath,
+		report:                                  NewReport(options.DryRun),
+		stageIDKeepReasons:                      map[string]string{},
 	}
 }
 
@@ -67,15 +88,23 @@ type cleanupManager struct {
 	checksumSourceImageIDs       map[string][]string
 	nonexistentImportMetadataIDs []string
 
+	report             *Report
+	stageIDKeepReasons map[string]string
+
 	ProjectName                             string
 	StorageManager                          *manager.StorageManager
 	ImageNameList                           []string
 	LocalGit                                GitRepo
 	KubernetesContextClients                []*kube.ContextClient
 	KubernetesNamespaceRestrictionByContext map[string]string
+	KubernetesDynamicClientByContext        map[string]dynamic.Interface
 	WithoutKube                             bool
+	AllowListProviders                      []allow_list.Provider
 	GitHistoryBasedCleanupOptions           config.MetaCleanup
 	KeepStagesBuiltWithinLastNHours         uint64
+	MaxRepoSize                             int64
+	QuarantineGracePeriodDays               uint64
+	ReportPath                              string
 	DryRun                                  bool
 }
 
@@ -189,8 +218,20 @@ func (m *cleanupManager) initImagesMetadata(ctx context.Context) error {
 	return nil
 }
 
-func (m *cleanupManager) keepImageNameStageID(imageName string, stageID string) {
+func (m *cleanupManager) keepImageNameStageID(imageName string, stageID string, reason string) {
 	delete(m.imageNameStageIDCommitListToCleanup[imageName], stageID)
+
+	if _, ok := m.stageIDKeepReasons[stageID]; !ok {
+		m.stageIDKeepReasons[stageID] = reason
+	}
+}
+
+func (m *cleanupManager) stageIDKeepReason(stageID string) string {
+	if reason, ok := m.stageIDKeepReasons[stageID]; ok {
+		return reason
+	}
+
+	return "git history-based cleanup skipped"
 }
 
 func (m *cleanupManager) deleteImageMetadataFromCache(imageName string, stageIDCommitListToDelete map[string][]string) {
@@ -218,7 +259,11 @@ func (m *cleanupManager) deleteImageMetadataFromCache(imageName string, stageIDC
 	}
 }
 
-func (m *cleanupManager) run(ctx context.Context) error {
+func (m *cleanupManager) run(ctx context.Context) (err error) {
+	defer func() {
+		err = writeReport(ctx, m.report, m.ReportPath, err)
+	}()
+
 	if err := logboek.Context(ctx).LogProcess("Fetching manifests and metadata").DoError(func() error {
 		return m.init(ctx)
 	}); err != nil {
@@ -226,9 +271,9 @@ func (m *cleanupManager) run(ctx context.Context) error {
 	}
 
 	if m.LocalGit != nil {
-		if !m.WithoutKube {
-			if err := logboek.Context(ctx).LogProcess("Skipping tags that are being used in Kubernetes").DoError(func() error {
-				return m.skipStageIDsThatAreUsedInKubernetes(ctx)
+		if providers := m.allowListProviders(); len(providers) != 0 {
+			if err := logboek.Context(ctx).LogProcess("Skipping tags that are in allow lists").DoError(func() error {
+				return m.skipStageIDsThatAreInAllowLists(ctx, providers)
 			}); err != nil {
 				return err
 			}
@@ -244,38 +289,80 @@ func (m *cleanupManager) run(ctx context.Context) error {
 		logboek.Context(ctx).Default().LogOptionalLn()
 	}
 
+	m.reportKeptImagesMetadata()
+
 	if err := logboek.Context(ctx).LogProcess("Cleanup unused stages").DoError(func() error {
 		return m.cleanupUnusedStages(ctx)
 	}); err != nil {
 		return err
 	}
 
+	if m.QuarantineGracePeriodDays != 0 {
+		if err := logboek.Context(ctx).LogProcess("Cleanup quarantined stages").DoError(func() error {
+			return m.cleanupQuarantinedStages(ctx)
+		}); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (m *cleanupManager) skipStageIDsThatAreUsedInKubernetes(ctx context.Context) error {
-	deployedDockerImagesNames, err := m.deployedDockerImagesNames(ctx)
+func (m *cleanupManager) reportKeptImagesMetadata() {
+	for imageName, stageIDCommitList := range m.imageNameStageIDCommitList {
+		for stageID, commitList := range stageIDCommitList {
+			for _, commit := range commitList {
+				m.report.AddImageMetadataRecord(ReportImageMetadataRecord{
+					ImageName: imageName,
+					StageID:   stageID,
+					Commit:    commit,
+					Action:    ReportActionKept,
+					Reason:    m.stageIDKeepReason(stageID),
+				})
+			}
+		}
+	}
+}
+
+// allowListProviders returns Kubernetes providers for each context (unless without kube) and the additional providers
+func (m *cleanupManager) allowListProviders() []allow_list.Provider {
+	var providers []allow_list.Provider
+	if !m.WithoutKube {
+		for _, contextClient := range m.KubernetesContextClients {
+			providers = append(providers, &allow_list.KubernetesProvider{
+				ContextName:   contextClient.ContextName,
+				Client:        contextClient.Client,
+				Namespace:     m.KubernetesNamespaceRestrictionByContext[contextClient.ContextName],
+				DynamicClient: m.KubernetesDynamicClientByContext[contextClient.ContextName],
+				Resources:     m.GitHistoryBasedCleanupOptions.KubernetesResources,
+			})
+		}
+	}
+
+	return append(providers, m.AllowListProviders...)
+}
+
+func (m *cleanupManager) skipStageIDsThatAreInAllowLists(ctx context.Context, providers []allow_list.Provider) error {
+	deployedDockerImagesUsages, err := deployedDockerImagesUsages(ctx, providers)
 	if err != nil {
 		return err
 	}
 
 	skippedDeployedImages := map[string]bool{}
 	for imageName, stageIDCommitList := range m.imageNameStageIDCommitListToCleanup {
-	Loop:
 		for stageID, _ := range stageIDCommitList {
 			dockerImageName := fmt.Sprintf("%s:%s", m.StorageManager.StagesStorage.String(), stageID)
-			for _, deployedDockerImageName := range deployedDockerImagesNames {
-				if deployedDockerImageName == dockerImageName {
-					m.keepImageNameStageID(imageName, stageID)
-
-					if !skippedDeployedImages[stageID] {
-						logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
-						logboek.Context(ctx).LogOptionalLn()
-						skippedDeployedImages[stageID] = true
-					}
+			usage, ok := deployedDockerImagesUsages[dockerImageName]
+			if !ok {
+				continue
+			}
 
-					continue Loop
-				}
+			m.keepImageNameStageID(imageName, stageID, fmt.Sprintf("used in %s", usage))
+
+			if !skippedDeployedImages[stageID] {
+				logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
+				logboek.Context(ctx).LogOptionalLn()
+				skippedDeployedImages[stageID] = true
 			}
 		}
 	}
@@ -283,17 +370,28 @@ func (m *cleanupManager) skipStageIDsThatAreUsedInKubernetes(ctx context.Context
 	return nil
 }
 
-func (m *cleanupManager) deployedDockerImagesNames(ctx context.Context) ([]string, error) {
-	var deployedDockerImagesNames []string
-	for _, contextClient := range m.KubernetesContextClients {
-		if err := logboek.Context(ctx).LogProcessInline("Getting deployed docker images (context %s)", contextClient.ContextName).
+// deployedDockerImagesUsages returns the union of allow lists: the first found provider and location by deployed docker image name
+func deployedDockerImagesUsages(ctx context.Context, providers []allow_list.Provider) (map[string]string, error) {
+	deployedDockerImagesUsages := map[string]string{}
+	for _, provider := range providers {
+		if err := logboek.Context(ctx).LogProcessInline("Getting deployed docker images (%s)", provider.String()).
 			DoError(func() error {
-				kubernetesClientDeployedDockerImagesNames, err := allow_list.DeployedDockerImages(contextClient.Client, m.KubernetesNamespaceRestrictionByContext[contextClient.ContextName])
+				providerDeployedDockerImages, err := provider.DeployedDockerImages(ctx)
 				if err != nil {
-					return fmt.Errorf("cannot get deployed imagesStageList: %s", err)
+					return fmt.Errorf("cannot get deployed images from %s: %s", provider.String(), err)
 				}
 
-				deployedDockerImagesNames = append(deployedDockerImagesNames, kubernetesClientDeployedDockerImagesNames...)
+				for _, deployedDockerImage := range providerDeployedDockerImages {
+					if _, ok := deployedDockerImagesUsages[deployedDockerImage.Name]; ok {
+						continue
+					}
+
+					usage := provider.String()
+					if deployedDockerImage.Location != "" {
+						usage = fmt.Sprintf("%s %s", usage, deployedDockerImage.Location)
+					}
+					deployedDockerImagesUsages[deployedDockerImage.Name] = usage
+				}
 
 				return nil
 			}); err != nil {
@@ -301,7 +399,7 @@ func (m *cleanupManager) deployedDockerImagesNames(ctx context.Context) ([]strin
 		}
 	}
 
-	return deployedDockerImagesNames, nil
+	return deployedDockerImagesUsages, nil
 }
 
 func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
@@ -310,25 +408,50 @@ func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
 		return fmt.Errorf("git plain open failed: %s", err)
 	}
 
-	var referencesToScan []*git_history_based_cleanup.ReferenceToScan
-	if err := logboek.Context(ctx).Default().LogProcess("Preparing references to scan").DoError(func() error {
-		referencesToScan, err = git_history_based_cleanup.ReferencesToScan(ctx, gitRepository, m.GitHistoryBasedCleanupOptions.KeepPolicies)
-		return err
-	}); err != nil {
-		return err
+	// images with the same set of keep policies share references to scan
+	referencesToScanByKeepPolicies := map[string][]*git_history_based_cleanup.ReferenceToScan{}
+	getReferencesToScan := func(imageName string) ([]*git_history_based_cleanup.ReferenceToScan, error) {
+		keepPolicies := m.GitHistoryBasedCleanupOptions.ImageKeepPolicies(imageName)
+
+		var keepPoliciesKeyParts []string
+		for _, policy := range keepPolicies {
+			keepPoliciesKeyParts = append(keepPoliciesKeyParts, policy.String())
+		}
+		keepPoliciesKey := strings.Join(keepPoliciesKeyParts, "\n")
+
+		if referencesToScan, ok := referencesToScanByKeepPolicies[keepPoliciesKey]; ok {
+			return referencesToScan, nil
+		}
+
+		var referencesToScan []*git_history_based_cleanup.ReferenceToScan
+		if err := logboek.Context(ctx).Default().LogProcess("Preparing references to scan").DoError(func() error {
+			referencesToScan, err = git_history_based_cleanup.ReferencesToScan(ctx, gitRepository, keepPolicies)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+
+		referencesToScanByKeepPolicies[keepPoliciesKey] = referencesToScan
+		return referencesToScan, nil
 	}
 
 	for imageName, stageIDCommitList := range m.imageNameStageIDCommitListToCleanup {
 		var reachedStageIDs []string
 		var hitStageIDCommitList map[string][]string
+		var stageIDReachedByReference map[string]*git_history_based_cleanup.ReferenceToScan
 		if err := logboek.Context(ctx).LogProcess(logging.ImageLogProcessName(imageName, false)).DoError(func() error {
 			if logboek.Context(ctx).Streams().Width() > 90 {
 				m.printStageIDCommitListTable(ctx, imageName)
 			}
 
+			referencesToScan, err := getReferencesToScan(imageName)
+			if err != nil {
+				return err
+			}
+
 			if err := logboek.Context(ctx).LogProcess("Scanning git references history").DoError(func() error {
 				if len(stageIDCommitList) != 0 {
-					reachedStageIDs, hitStageIDCommitList, err = git_history_based_cleanup.ScanReferencesHistory(ctx, gitRepository, referencesToScan, stageIDCommitList)
+					reachedStageIDs, hitStageIDCommitList, stageIDReachedByReference, err = git_history_based_cleanup.ScanReferencesHistory(ctx, gitRepository, referencesToScan, stageIDCommitList)
 				} else {
 					logboek.Context(ctx).LogLn("Scanning stopped due to nothing to seek")
 				}
@@ -354,6 +477,10 @@ func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
 				m.handleSavedStageIDs(ctx, reachedStageIDs)
 			}
 
+			for stageID, ref := range stageIDReachedByReference {
+				m.stageIDKeepReasons[stageID] = fmt.Sprintf("reachable from reference %s by keep policy %s", ref.Name().Short(), ref.KeepPolicy().String())
+			}
+
 			if err := logboek.Context(ctx).LogProcess("Cleaning image metadata").DoError(func() error {
 				return m.cleanupImageMetadata(ctx, imageName, hitStageIDCommitList, stageIDToUnlink)
 			}); err != nil {
@@ -424,10 +551,11 @@ func (m *cleanupManager) handleSavedStageIDs(ctx context.Context, savedStageIDs
 	})
 }
 
-func (m *cleanupManager) deleteStages(ctx context.Context, stages []*image.StageDescription) error {
+func (m *cleanupManager) deleteStages(ctx context.Context, stages []*image.StageDescription, reason string) error {
 	deleteStageOptions := manager.ForEachDeleteStageOptions{
 		DeleteImageOptions: storage.DeleteImageOptions{
-			RmiForce: false,
+			RmiForce:   false,
+			Quarantine: m.QuarantineGracePeriodDays != 0,
 		},
 		FilterStagesAndProcessRelatedDataOptions: storage.FilterStagesAndProcessRelatedDataOptions{
 			SkipUsedImage:            true,
@@ -436,14 +564,20 @@ func (m *cleanupManager) deleteStages(ctx context.Context, stages []*image.Stage
 		},
 	}
 
-	return deleteStages(ctx, m.StorageManager, m.DryRun, deleteStageOptions, stages)
+	return deleteStages(ctx, m.StorageManager, m.DryRun, deleteStageOptions, stages, m.report, reason)
 }
 
-func deleteStages(ctx context.Context, storageManager *manager.StorageManager, dryRun bool, deleteStageOptions manager.ForEachDeleteStageOptions, stages []*image.StageDescription) error {
+func deleteStages(ctx context.Context, storageManager *manager.StorageManager, dryRun bool, deleteStageOptions manager.ForEachDeleteStageOptions, stages []*image.StageDescription, report *Report, reason string) error {
+	action := ReportActionDeleted
+	if deleteStageOptions.Quarantine {
+		action = ReportActionQuarantined
+	}
+
 	if dryRun {
 		for _, stageDesc := range stages {
 			logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageDesc.Info.Tag)
 			logboek.Context(ctx).LogOptionalLn()
+			report.AddStageRecord(newReportStageRecord(stageDesc, action, reason))
 		}
 		return nil
 	}
@@ -455,16 +589,27 @@ func deleteStages(ctx context.Context, storageManager *manager.StorageManager, d
 			}
 
 			logboek.Context(ctx).Warn().LogF("WARNING: Image %s deletion failed: %s\n", stageDesc.Info.Name, err)
+			report.AddStageRecord(newReportStageRecord(stageDesc, ReportActionKept, fmt.Sprintf("deletion failed: %s", err)))
 
 			return nil
 		}
 
 		logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageDesc.Info.Tag)
+		report.AddStageRecord(newReportStageRecord(stageDesc, action, reason))
 
 		return nil
 	})
 }
 
+func newReportStageRecord(stageDesc *image.StageDescription, action ReportAction, reason string) ReportStageRecord {
+	return ReportStageRecord{
+		Tag:     stageDesc.Info.Tag,
+		ImageID: stageDesc.Info.ID,
+		Action:  action,
+		Reason:  reason,
+	}
+}
+
 func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName string, hitStageIDCommitList map[string][]string, stageIDsToUnlink []string) error {
 	stageIDCommitList := m.imageNameStageIDCommitListToCleanup[imageName]
 	nonexistentStageIDCommitList := m.imageNameNonexistentStageIDCommitList[imageName]
@@ -495,7 +640,7 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 
 		if len(stageIDCommitListToDelete) != 0 {
 			if err := logboek.Context(ctx).Info().LogProcess("Cleaning up metadata").DoError(func() error {
-				return m.deleteImageMetadata(ctx, imageName, stageIDCommitListToDelete, true)
+				return m.deleteImageMetadata(ctx, imageName, stageIDCommitListToDelete, true, "not reachable from references by keep policies")
 			}); err != nil {
 				return err
 			}
@@ -504,7 +649,7 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 
 	if len(nonexistentStageIDCommitList) != 0 {
 		if err := logboek.Context(ctx).Info().LogProcess("Deleting metadata for nonexistent stageIDs").DoError(func() error {
-			return m.deleteImageMetadata(ctx, imageName, nonexistentStageIDCommitList, false)
+			return m.deleteImageMetadata(ctx, imageName, nonexistentStageIDCommitList, false, "stage does not exist")
 		}); err != nil {
 			return err
 		}
@@ -512,7 +657,7 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 
 	if len(stageIDNonexistentCommitList) != 0 {
 		if err := logboek.Context(ctx).Info().LogProcess("Deleting metadata for nonexistent commits").DoError(func() error {
-			return m.deleteImageMetadata(ctx, imageName, stageIDNonexistentCommitList, false)
+			return m.deleteImageMetadata(ctx, imageName, stageIDNonexistentCommitList, false, "commit does not exist in git")
 		}); err != nil {
 			return err
 		}
@@ -528,7 +673,7 @@ func (m *cleanupManager) cleanupNonexistentImageMetadata(ctx context.Context) er
 
 	return logboek.Context(ctx).Default().LogProcess("Deleting metadata for nonexistent images").DoError(func() error {
 		for imageName, stageIDCommitList := range m.nonexistentImageNameStageIDCommitList {
-			if err := m.deleteImageMetadata(ctx, imageName, stageIDCommitList, false); err != nil {
+			if err := m.deleteImageMetadata(ctx, imageName, stageIDCommitList, false, "image does not exist in werf.yaml"); err != nil {
 				return err
 			}
 		}
@@ -537,8 +682,8 @@ func (m *cleanupManager) cleanupNonexistentImageMetadata(ctx context.Context) er
 	})
 }
 
-func (m *cleanupManager) deleteImageMetadata(ctx context.Context, imageName string, stageIDCommitList map[string][]string, updateCache bool) error {
-	if err := deleteImageMetadata(ctx, m.ProjectName, m.StorageManager, imageName, stageIDCommitList, m.DryRun); err != nil {
+func (m *cleanupManager) deleteImageMetadata(ctx context.Context, imageName string, stageIDCommitList map[string][]string, updateCache bool, reason string) error {
+	if err := deleteImageMetadata(ctx, m.ProjectName, m.StorageManager, imageName, stageIDCommitList, m.DryRun, m.report, reason); err != nil {
 		return err
 	}
 
@@ -549,13 +694,17 @@ func (m *cleanupManager) deleteImageMetadata(ctx context.Context, imageName stri
 	return nil
 }
 
-func deleteImageMetadata(ctx context.Context, projectName string, storageManager *manager.StorageManager, imageNameOrID string, stageIDCommitList map[string][]string, dryRun bool) error {
+func deleteImageMetadata(ctx context.Context, projectName string, storageManager *manager.StorageManager, imageNameOrID string, stageIDCommitList map[string][]string, dryRun bool, report *Report, reason string) error {
 	if dryRun {
 		for stageID, commitList := range stageIDCommitList {
 			logboek.Context(ctx).Info().LogFDetails("  imageName: %s\n", imageNameOrID)
 			logboek.Context(ctx).Info().LogFDetails("  stageID: %s\n", stageID)
 			logboek.Context(ctx).Info().LogFDetails("  commits: %d\n", len(commitList))
 			logboek.Context(ctx).Info().LogOptionalLn()
+
+			for _, commit := range commitList {
+				report.AddImageMetadataRecord(ReportImageMetadataRecord{ImageName: imageNameOrID, StageID: stageID, Commit: commit, Action: ReportActionDeleted, Reason: reason})
+			}
 		}
 		return nil
 	}
@@ -567,6 +716,7 @@ func deleteImageMetadata(ctx context.Context, projectName string, storageManager
 			}
 
 			logboek.Context(ctx).Warn().LogF("WARNING: Image metadata %s commit %s stage ID %s deletion failed: %s\n", imageNameOrID, commit, stageID, err)
+			report.AddImageMetadataRecord(ReportImageMetadataRecord{ImageName: imageNameOrID, StageID: stageID, Commit: commit, Action: ReportActionKept, Reason: fmt.Sprintf("deletion failed: %s", err)})
 
 			return nil
 		}
@@ -574,6 +724,7 @@ func deleteImageMetadata(ctx context.Context, projectName string, storageManager
 		logboek.Context(ctx).Info().LogFDetails("  imageName: %s\n", imageNameOrID)
 		logboek.Context(ctx).Info().LogFDetails("  stageID: %s\n", stageID)
 		logboek.Context(ctx).Info().LogFDetails("  commit: %s\n", commit)
+		report.AddImageMetadataRecord(ReportImageMetadataRecord{ImageName: imageNameOrID, StageID: stageID, Commit: commit, Action: ReportActionDeleted, Reason: reason})
 
 		return nil
 	})
@@ -592,6 +743,7 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 			var excludedStagesByStageID []*image.StageDescription
 			stage := m.mustGetStage(stageID)
 			stagesToDelete, excludedStagesByStageID = m.excludeStageAndRelativesByImageID(stagesToDelete, stage.Info.ID)
+			m.reportKeptStages(stage, excludedStagesByStageID, m.stageIDKeepReason(stageID))
 
 			logboek.Context(ctx).Debug().LogBlock("Saved stages (%s)", stage.Info.Tag).Do(func() {
 				for _, stage := range excludedStagesByStageID {
@@ -602,6 +754,8 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 		}
 	}
 
+	stagesToDelete = m.excludeRestoredStages(ctx, stagesToDelete)
+
 	if m.KeepStagesBuiltWithinLastNHours != 0 {
 		var excludedStages []*image.StageDescription
 		for _, stage := range stagesToDelete {
@@ -609,6 +763,7 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 				var excludedStagesByStage []*image.StageDescription
 				stagesToDelete, excludedStagesByStage = m.excludeStageAndRelativesByImageID(stagesToDelete, stage.Info.ID)
 				excludedStages = append(excludedStages, excludedStagesByStage...)
+				m.reportKeptStages(stage, excludedStagesByStage, fmt.Sprintf("built within last %d hours", m.KeepStagesBuiltWithinLastNHours))
 			}
 		}
 
@@ -622,9 +777,15 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 		}
 	}
 
+	deletionReason := "not used by kept image metadata"
+	if m.MaxRepoSize != 0 {
+		stagesToDelete = m.selectStagesToDeleteByRepoSize(ctx, stagesToDelete)
+		deletionReason = fmt.Sprintf("not used by kept image metadata and exceeds max repo size %s", units.BytesSize(float64(m.MaxRepoSize)))
+	}
+
 	if len(stagesToDelete) != 0 {
 		if err := logboek.Context(ctx).Default().LogProcess("Deleting stages tags").DoError(func() error {
-			return m.deleteStages(ctx, stagesToDelete)
+			return m.deleteStages(ctx, stagesToDelete, deletionReason)
 		}); err != nil {
 			return err
 		}
@@ -632,7 +793,7 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 
 	if len(m.nonexistentImportMetadataIDs) != 0 {
 		if err := logboek.Context(ctx).Default().LogProcess("Cleaning imports metadata").DoError(func() error {
-			return m.deleteImportsMetadata(ctx, m.nonexistentImportMetadataIDs)
+			return m.deleteImportsMetadata(ctx, m.nonexistentImportMetadataIDs, "source stage does not exist")
 		}); err != nil {
 			return err
 		}
@@ -641,6 +802,207 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 	return nil
 }
 
+// selectStagesToDeleteByRepoSize selects the oldest stages from the unused ones until the repo size fits MaxRepoSize.
+// The repo size is the size of the unique layers, so deleting a stage frees only the layers not shared with the remaining stages.
+// The related unused stages are deleted or kept together, so that the kept stage does not lose its parent.
+func (m *cleanupManager) selectStagesToDeleteByRepoSize(ctx context.Context, unusedStages []*image.StageDescription) []*image.StageDescription {
+	layersRefs := map[string]int{}
+	for _, stage := range m.stages {
+		for digest := range stageLayers(stage) {
+			layersRefs[digest]++
+		}
+	}
+	repoSize := stagesLayersSize(m.stages)
+
+	unusedStagesByName := map[string]bool{}
+	for _, stage := range unusedStages {
+		unusedStagesByName[stage.Info.Name] = true
+	}
+
+	var usedStages []*image.StageDescription
+	for _, stage := range m.stages {
+		if !unusedStagesByName[stage.Info.Name] {
+			usedStages = append(usedStages, stage)
+		}
+	}
+	usedStagesSize := stagesLayersSize(usedStages)
+
+	logboek.Context(ctx).Default().LogF("Repo size: %s (max %s), unused stages size: %s\n", units.BytesSize(float64(repoSize)), units.BytesSize(float64(m.MaxRepoSize)), units.BytesSize(float64(repoSize-usedStagesSize)))
+
+	if usedStagesSize > m.MaxRepoSize {
+		logboek.Context(ctx).Warn().LogF("WARNING: The size of stages protected by cleanup policies and Kubernetes exceeds max repo size %s\n", units.BytesSize(float64(m.MaxRepoSize)))
+	}
+
+	var stagesToDelete, keptStages []*image.StageDescription
+	for _, chain := range stagesChains(unusedStages) {
+		if repoSize > m.MaxRepoSize {
+			stagesToDelete = append(stagesToDelete, chain...)
+
+			for _, stage := range chain {
+				for digest, size := range stageLayers(stage) {
+					layersRefs[digest]--
+					if layersRefs[digest] == 0 {
+						repoSize -= size
+					}
+				}
+			}
+		} else {
+			keptStages = append(keptStages, chain...)
+
+			for _, stage := range chain {
+				m.report.AddStageRecord(newReportStageRecord(stage, ReportActionKept, fmt.Sprintf("fits max repo size %s", units.BytesSize(float64(m.MaxRepoSize)))))
+			}
+		}
+	}
+
+	if len(keptStages) != 0 {
+		logboek.Context(ctx).Default().LogBlock("Saved unused stages within max repo size").Do(func() {
+			for _, stage := range keptStages {
+				logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stage.Info.Tag)
+				logboek.Context(ctx).LogOptionalLn()
+			}
+		})
+	}
+
+	return stagesToDelete
+}
+
+// stagesChains groups the stages related by the parent image id, the stages of a chain are sorted by the creation time.
+// The chains are sorted by the creation time of the newest stage, so the chain which has not been extended for the longest time goes first.
+func stagesChains(stages []*image.StageDescription) [][]*image.StageDescription {
+	sortedStages := append([]*image.StageDescription{}, stages...)
+	sort.SliceStable(sortedStages, func(i, j int) bool {
+		return sortedStages[i].Info.GetCreatedAt().Before(sortedStages[j].Info.GetCreatedAt())
+	})
+
+	stagesByImageID := map[string]*image.StageDescription{}
+	for _, stage := range sortedStages {
+		stagesByImageID[stage.Info.ID] = stage
+	}
+
+	rootImageID := func(stage *image.StageDescription) string {
+		for {
+			parent, ok := stagesByImageID[stage.Info.ParentID]
+			if !ok || parent == stage {
+				return stage.Info.ID
+			}
+			stage = parent
+		}
+	}
+
+	var chains [][]*image.StageDescription
+	chainIndexByRootImageID := map[string]int{}
+	for _, stage := range sortedStages {
+		root := rootImageID(stage)
+		if ind, ok := chainIndexByRootImageID[root]; ok {
+			chains[ind] = append(chains[ind], stage)
+		} else {
+			chainIndexByRootImageID[root] = len(chains)
+			chains = append(chains, []*image.StageDescription{stage})
+		}
+	}
+
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i][len(chains[i])-1].Info.GetCreatedAt().Before(chains[j][len(chains[j])-1].Info.GetCreatedAt())
+	})
+
+	return chains
+}
+
+// stageLayers returns the stage layers sizes by digest.
+// The stage without layers info is counted as a single layer of the stage size.
+func stageLayers(stage *image.StageDescription) map[string]int64 {
+	layers := map[string]int64{}
+	if len(stage.Info.Layers) == 0 {
+		layers[stage.Info.ID] = stage.Info.Size
+		return layers
+	}
+
+	for _, layer := range stage.Info.Layers {
+		layers[layer.Digest] = layer.Size
+	}
+
+	return layers
+}
+
+func stagesLayersSize(stages []*image.StageDescription) int64 {
+	layersSizes := map[string]int64{}
+	for _, stage := range stages {
+		for digest, size := range stageLayers(stage) {
+			layersSizes[digest] = size
+		}
+	}
+
+	var size int64
+	for _, layerSize := range layersSizes {
+		size += layerSize
+	}
+
+	return size
+}
+
+// cleanupQuarantinedStages deletes the stages that have been in the quarantine longer than the grace period
+func (m *cleanupManager) cleanupQuarantinedStages(ctx context.Context) error {
+	repoStagesStorage := m.StorageManager.StagesStorage.(*storage.RepoStagesStorage)
+
+	quarantinedStages, err := repoStagesStorage.GetQuarantinedStages(ctx)
+	if err != nil {
+		return err
+	}
+
+	var expiredQuarantinedStages []*storage.QuarantinedStage
+	for _, quarantinedStage := range quarantinedStages {
+		// the quarantine date has no time, so the stage is kept for at least the full grace period
+		if time.Now().Before(quarantinedStage.QuarantinedAt.AddDate(0, 0, int(m.QuarantineGracePeriodDays)+1)) {
+			m.report.AddStageRecord(newReportQuarantinedStageRecord(quarantinedStage, ReportActionKept, fmt.Sprintf("quarantined on %s within grace period of %d days", quarantinedStage.QuarantinedAt.Format("2006-01-02"), m.QuarantineGracePeriodDays)))
+			continue
+		}
+
+		expiredQuarantinedStages = append(expiredQuarantinedStages, quarantinedStage)
+	}
+
+	if len(expiredQuarantinedStages) == 0 {
+		return nil
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Deleting expired quarantined stages").DoError(func() error {
+		return deleteQuarantinedStages(ctx, repoStagesStorage, expiredQuarantinedStages, m.DryRun, m.report, fmt.Sprintf("quarantine grace period of %d days expired", m.QuarantineGracePeriodDays))
+	})
+}
+
+func deleteQuarantinedStages(ctx context.Context, repoStagesStorage *storage.RepoStagesStorage, quarantinedStages []*storage.QuarantinedStage, dryRun bool, report *Report, reason string) error {
+	for _, quarantinedStage := range quarantinedStages {
+		if !dryRun {
+			if err := repoStagesStorage.DeleteQuarantinedStage(ctx, quarantinedStage); err != nil {
+				if err := handleDeletionError(err); err != nil {
+					return err
+				}
+
+				logboek.Context(ctx).Warn().LogF("WARNING: Quarantined image %s deletion failed: %s\n", quarantinedStage.ImageName, err)
+				report.AddStageRecord(newReportQuarantinedStageRecord(quarantinedStage, ReportActionKept, fmt.Sprintf("deletion failed: %s", err)))
+
+				continue
+			}
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", quarantinedStage.StageID)
+		logboek.Context(ctx).LogOptionalLn()
+		report.AddStageRecord(newReportQuarantinedStageRecord(quarantinedStage, ReportActionDeleted, reason))
+	}
+
+	return nil
+}
+
+// newReportQuarantinedStageRecord uses the quarantine tag to distinguish the quarantined image from the stage quarantined in the current run
+func newReportQuarantinedStageRecord(quarantinedStage *storage.QuarantinedStage, action ReportAction, reason string) ReportStageRecord {
+	_, tag := image.ParseRepositoryAndTag(quarantinedStage.ImageName)
+	return ReportStageRecord{
+		Tag:    tag,
+		Action: action,
+		Reason: reason,
+	}
+}
+
 func (m *cleanupManager) initImportsMetadata(ctx context.Context) error {
 	m.checksumSourceImageIDs = map[string][]string{}
 
@@ -658,7 +1020,7 @@ func (m *cleanupManager) initImportsMetadata(ctx context.Context) error {
 		if metadata == nil {
 			if err := logboek.Context(ctx).Warn().LogProcess("Deleting invalid import metadata %s", metadataID).
 				DoError(func() error {
-					return m.deleteImportsMetadata(ctx, []string{metadataID})
+					return m.deleteImportsMetadata(ctx, []string{metadataID}, "invalid metadata")
 				}); err != nil {
 				return fmt.Errorf("unable to delete import metadata %s: %s", metadataID, err)
 			}
@@ -681,6 +1043,7 @@ func (m *cleanupManager) initImportsMetadata(ctx context.Context) error {
 			}
 
 			m.checksumSourceImageIDs[checksum] = append(sourceImageIDs, sourceImageID)
+			m.report.AddImportMetadataRecord(ReportImportMetadataRecord{ImportMetadataID: importSourceID, Action: ReportActionKept, Reason: fmt.Sprintf("source stage %s exists", stage.Info.Tag)})
 		} else {
 			m.nonexistentImportMetadataIDs = append(m.nonexistentImportMetadataIDs, importSourceID)
 		}
@@ -689,15 +1052,16 @@ func (m *cleanupManager) initImportsMetadata(ctx context.Context) error {
 	})
 }
 
-func (m *cleanupManager) deleteImportsMetadata(ctx context.Context, importMetadataIDs []string) error {
-	return deleteImportsMetadata(ctx, m.ProjectName, m.StorageManager, importMetadataIDs, m.DryRun)
+func (m *cleanupManager) deleteImportsMetadata(ctx context.Context, importMetadataIDs []string, reason string) error {
+	return deleteImportsMetadata(ctx, m.ProjectName, m.StorageManager, importMetadataIDs, m.DryRun, m.report, reason)
 }
 
-func deleteImportsMetadata(ctx context.Context, projectName string, storageManager *manager.StorageManager, importMetadataIDs []string, dryRun bool) error {
+func deleteImportsMetadata(ctx context.Context, projectName string, storageManager *manager.StorageManager, importMetadataIDs []string, dryRun bool, report *Report, reason string) error {
 	if dryRun {
 		for _, importMetadataID := range importMetadataIDs {
 			logboek.Context(ctx).Info().LogFDetails("  importMetadataID: %s\n", importMetadataID)
 			logboek.Context(ctx).Info().LogOptionalLn()
+			report.AddImportMetadataRecord(ReportImportMetadataRecord{ImportMetadataID: importMetadataID, Action: ReportActionDeleted, Reason: reason})
 		}
 		return nil
 	}
@@ -709,16 +1073,57 @@ func deleteImportsMetadata(ctx context.Context, projectName string, storageManag
 			}
 
 			logboek.Context(ctx).Warn().LogF("WARNING: Import metadata ID %s deletion failed: %s\n", importMetadataID, err)
+			report.AddImportMetadataRecord(ReportImportMetadataRecord{ImportMetadataID: importMetadataID, Action: ReportActionKept, Reason: fmt.Sprintf("deletion failed: %s", err)})
 
 			return nil
 		}
 
 		logboek.Context(ctx).Info().LogFDetails("  importMetadataID: %s\n", importMetadataID)
+		report.AddImportMetadataRecord(ReportImportMetadataRecord{ImportMetadataID: importMetadataID, Action: ReportActionDeleted, Reason: reason})
 
 		return nil
 	})
 }
 
+// excludeRestoredStages keeps the stages restored from the quarantine with werf stage restore command and their relatives
+func (m *cleanupManager) excludeRestoredStages(ctx context.Context, stages []*image.StageDescription) []*image.StageDescription {
+	var excludedStages []*image.StageDescription
+	for _, stage := range stages {
+		restoredAt, ok := stage.Info.Annotations[storage.RestoredAtAnnotation]
+		if !ok {
+			continue
+		}
+
+		var excludedStagesByStage []*image.StageDescription
+		stages, excludedStagesByStage = m.excludeStageAndRelativesByImageID(stages, stage.Info.ID)
+		excludedStages = append(excludedStages, excludedStagesByStage...)
+		m.reportKeptStages(stage, excludedStagesByStage, fmt.Sprintf("restored from the quarantine at %s", restoredAt))
+	}
+
+	if len(excludedStages) != 0 {
+		logboek.Context(ctx).Default().LogBlock("Saved stages restored from the quarantine").Do(func() {
+			for _, stage := range excludedStages {
+				logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stage.Info.Tag)
+				logboek.Context(ctx).LogOptionalLn()
+			}
+		})
+	}
+
+	return stages
+}
+
+// reportKeptStages records the stage and its parents and import sources excluded from deletion with the reason
+func (m *cleanupManager) reportKeptStages(stage *image.StageDescription, excludedStages []*image.StageDescription, reason string) {
+	for _, excludedStage := range excludedStages {
+		excludedStageReason := reason
+		if excludedStage != stage {
+			excludedStageReason = fmt.Sprintf("related to stage %s: %s", stage.Info.Tag, reason)
+		}
+
+		m.report.AddStageRecord(newReportStageRecord(excludedStage, ReportActionKept, excludedStageReason))
+	}
+}
+
 func (m *cleanupManager) excludeStageAndRelativesByImageID(stages []*image.StageDescription, imageID string) ([]*image.StageDescription, []*image.StageDescription) {
 	stage := findStageByImageID(stages, imageID)
 	if stage == nil {
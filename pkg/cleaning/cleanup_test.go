@@ -0,0 +1,125 @@
+package cleaning
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+var _ = Describe("select stages to delete by repo size", func() {
+	now := time.Now()
+
+	newStage := func(tag string, age time.Duration, layers ...image.LayerInfo) *image.StageDescription {
+		info := &image.Info{Name: "repo:" + tag, Tag: tag, ID: "sha256:" + tag, Layers: layers}
+		info.SetCreatedAtUnixNano(now.Add(-age).UnixNano())
+		for _, layer := range layers {
+			info.Size += layer.Size
+		}
+
+		return &image.StageDescription{Info: info}
+	}
+
+	selectedTags := func(m *cleanupManager, unusedStages []*image.StageDescription) []string {
+		var tags []string
+		for _, stage := range m.selectStagesToDeleteByRepoSize(context.Background(), unusedStages) {
+			tags = append(tags, stage.Info.Tag)
+		}
+		return tags
+	}
+
+	keptTags := func(m *cleanupManager) []string {
+		var tags []string
+		for _, record := range m.report.Stages {
+			Ω(record.Action).Should(Equal(ReportActionKept))
+			tags = append(tags, record.Tag)
+		}
+		return tags
+	}
+
+	base := image.LayerInfo{Digest: "sha256:base", Size: 100}
+
+	It("should keep unused stages when the repo size fits max repo size", func() {
+		used := newStage("used", time.Hour, base)
+		unused1 := newStage("unused1", 3*time.Hour, base, image.LayerInfo{Digest: "sha256:1", Size: 10})
+		unused2 := newStage("unused2", 2*time.Hour, base, image.LayerInfo{Digest: "sha256:2", Size: 10})
+
+		m := &cleanupManager{stages: []*image.StageDescription{used, unused1, unused2}, report: NewReport(false), MaxRepoSize: 120}
+		Ω(selectedTags(m, []*image.StageDescription{unused1, unused2})).Should(BeEmpty())
+		Ω(keptTags(m)).Should(Equal([]string{"unused1", "unused2"}))
+	})
+
+	It("should delete the oldest unused stages until the repo size fits max repo size", func() {
+		used := newStage("used", time.Hour, base)
+		unused1 := newStage("unused1", 2*time.Hour, base, image.LayerInfo{Digest: "sha256:1", Size: 10})
+		unused2 := newStage("unused2", 4*time.Hour, base, image.LayerInfo{Digest: "sha256:2", Size: 10})
+		unused3 := newStage("unused3", 3*time.Hour, base, image.LayerInfo{Digest: "sha256:3", Size: 10})
+
+		m := &cleanupManager{stages: []*image.StageDescription{used, unused1, unused2, unused3}, report: NewReport(false), MaxRepoSize: 115}
+		Ω(selectedTags(m, []*image.StageDescription{unused1, unused2, unused3})).Should(Equal([]string{"unused2", "unused3"}))
+		Ω(keptTags(m)).Should(Equal([]string{"unused1"}))
+	})
+
+	It("should count the layers shared by the stages once", func() {
+		used := newStage("used", time.Hour, base, image.LayerInfo{Digest: "sha256:shared", Size: 50})
+		unused1 := newStage("unused1", 3*time.Hour, base, image.LayerInfo{Digest: "sha256:shared", Size: 50})
+		unused2 := newStage("unused2", 2*time.Hour, base, image.LayerInfo{Digest: "sha256:2", Size: 10})
+
+		// the repo size is 160, deleting unused1 frees nothing, because its layers are used by the other stages
+		m := &cleanupManager{stages: []*image.StageDescription{used, unused1, unused2}, report: NewReport(false), MaxRepoSize: 155}
+		Ω(selectedTags(m, []*image.StageDescription{unused1, unused2})).Should(Equal([]string{"unused1", "unused2"}))
+		Ω(keptTags(m)).Should(BeEmpty())
+
+		m = &cleanupManager{stages: []*image.StageDescription{used, unused1, unused2}, report: NewReport(false), MaxRepoSize: 160}
+		Ω(selectedTags(m, []*image.StageDescription{unused1, unused2})).Should(BeEmpty())
+	})
+
+	It("should delete or keep the stages of a chain together", func() {
+		used := newStage("used", time.Hour, base)
+		parent := newStage("parent", 4*time.Hour, base, image.LayerInfo{Digest: "sha256:parent", Size: 10})
+		child := newStage("child", time.Hour+time.Minute, base, image.LayerInfo{Digest: "sha256:parent", Size: 10}, image.LayerInfo{Digest: "sha256:child", Size: 10})
+		child.Info.ParentID = parent.Info.ID
+		unused := newStage("unused", 3*time.Hour, base, image.LayerInfo{Digest: "sha256:unused", Size: 10})
+
+		// the parent is the oldest stage, but the chain is as old as the child, so the unused stage goes first
+		m := &cleanupManager{stages: []*image.StageDescription{used, parent, child, unused}, report: NewReport(false), MaxRepoSize: 125}
+		Ω(selectedTags(m, []*image.StageDescription{parent, child, unused})).Should(Equal([]string{"unused"}))
+		Ω(keptTags(m)).Should(Equal([]string{"parent", "child"}))
+
+		// the parent is not deleted without the child
+		m = &cleanupManager{stages: []*image.StageDescription{used, parent, child, unused}, report: NewReport(false), MaxRepoSize: 105}
+		Ω(selectedTags(m, []*image.StageDescription{parent, child, unused})).Should(Equal([]string{"unused", "parent", "child"}))
+		Ω(keptTags(m)).Should(BeEmpty())
+	})
+
+	It("should use the stage size if there is no layers info", func() {
+		used := newStage("used", time.Hour)
+		used.Info.Size = 100
+		unused := newStage("unused", 2*time.Hour)
+		unused.Info.Size = 100
+
+		m := &cleanupManager{stages: []*image.StageDescription{used, unused}, report: NewReport(false), MaxRepoSize: 150}
+		Ω(selectedTags(m, []*image.StageDescription{unused})).Should(Equal([]string{"unused"}))
+	})
+})
+
+var _ = Describe("exclude restored stages", func() {
+	It("should keep restored stages and their parents", func() {
+		parent := &image.StageDescription{Info: &image.Info{Tag: "parent", ID: "sha256:parent"}}
+		restored := &image.StageDescription{Info: &image.Info{Tag: "restored", ID: "sha256:restored", ParentID: "sha256:parent", Annotations: map[string]string{
+			storage.RestoredAtAnnotation: "2020-01-01T00:00:00Z",
+		}}}
+		unused := &image.StageDescription{Info: &image.Info{Tag: "unused", ID: "sha256:unused"}}
+
+		m := &cleanupManager{report: NewReport(false)}
+		Ω(m.excludeRestoredStages(context.Background(), []*image.StageDescription{parent, restored, unused})).Should(Equal([]*image.StageDescription{unused}))
+		Ω(m.report.Stages).Should(Equal([]ReportStageRecord{
+			{Tag: "restored", ImageID: "sha256:restored", Action: ReportActionKept, Reason: "restored from the quarantine at 2020-01-01T00:00:00Z"},
+			{Tag: "parent", ImageID: "sha256:parent", Action: ReportActionKept, Reason: "related to stage restored: restored from the quarantine at 2020-01-01T00:00:00Z"},
+		}))
+	})
+})
@@ -26,9 +26,15 @@ type ReferenceToScan struct {
 
 type referenceScanOptions struct {
 	scanDepthLimit          int
+	keepPolicy              *config.MetaCleanupKeepPolicy
 	imagesCleanupKeepPolicy config.MetaCleanupKeepPolicyImagesPerReference
 }
 
+// KeepPolicy returns the keep policy the reference was selected by
+func (r *ReferenceToScan) KeepPolicy() *config.MetaCleanupKeepPolicy {
+	return r.keepPolicy
+}
+
 func (r *ReferenceToScan) String() string {
 	imagesCleanupKeepPolicy := r.imagesCleanupKeepPolicy.String()
 	if imagesCleanupKeepPolicy != "" {
@@ -231,7 +237,7 @@ func selectTagReferencesByRegexp(tagsRefs []*ReferenceToScan, regexp *regexp.Reg
 
 func applyCleanupKeepPolicy(refs []*ReferenceToScan, policy *config.MetaCleanupKeepPolicy) []*ReferenceToScan {
 	refs = applyReferencesLimit(refs, policy.References.Limit)
-	applyImagesPerReference(refs, policy.ImagesPerReference)
+	applyImagesPerReference(refs, policy)
 
 	return refs
 }
@@ -267,9 +273,10 @@ func applyReferencesLimit(refs []*ReferenceToScan, limit *config.MetaCleanupKeep
 	return policyRefs
 }
 
-func applyImagesPerReference(policyBranchesRefs []*ReferenceToScan, imagesPerReference config.MetaCleanupKeepPolicyImagesPerReference) {
+func applyImagesPerReference(policyBranchesRefs []*ReferenceToScan, policy *config.MetaCleanupKeepPolicy) {
 	for _, ref := range policyBranchesRefs {
-		ref.imagesCleanupKeepPolicy = imagesPerReference
+		ref.keepPolicy = policy
+		ref.imagesCleanupKeepPolicy = policy.ImagesPerReference
 	}
 }
 
@@ -17,8 +17,10 @@ import (
 	"github.com/werf/werf/pkg/util"
 )
 
-func ScanReferencesHistory(ctx context.Context, gitRepository *git.Repository, refs []*ReferenceToScan, expectedStageIDCommitList map[string][]string) ([]string, map[string][]string, error) {
+// ScanReferencesHistory returns reached stage IDs, hit commits by stage ID and the reference each stage ID was first reached by
+func ScanReferencesHistory(ctx context.Context, gitRepository *git.Repository, refs []*ReferenceToScan, expectedStageIDCommitList map[string][]string) ([]string, map[string][]string, map[string]*ReferenceToScan, error) {
 	var reachedStageIDs []string
+	stageIDReachedByReference := map[string]*ReferenceToScan{}
 	var stopCommitList []string
 	stageIDHitCommitList := map[string][]string{}
 
@@ -46,6 +48,12 @@ func ScanReferencesHistory(ctx context.Context, gitRepository *git.Repository, r
 			stopCommitList = util.AddNewStringsToStringArray(stopCommitList, refStopCommitList...)
 			reachedStageIDs = util.AddNewStringsToStringArray(reachedStageIDs, refReachedStageIDs...)
 
+			for _, stageID := range refReachedStageIDs {
+				if _, ok := stageIDReachedByReference[stageID]; !ok {
+					stageIDReachedByReference[stageID] = ref
+				}
+			}
+
 			for refStageID, refCommitList := range refStageIDHitCommitList {
 				hitCommitList, ok := stageIDHitCommitList[refStageID]
 				if !ok {
@@ -58,11 +66,11 @@ func ScanReferencesHistory(ctx context.Context, gitRepository *git.Repository, r
 
 			return nil
 		}); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 
-	return reachedStageIDs, stageIDHitCommitList, nil
+	return reachedStageIDs, stageIDHitCommitList, stageIDReachedByReference, nil
 }
 
 func applyImagesCleanupInPolicy(gitRepository *git.Repository, stageIDCommitList map[string][]string, in *time.Duration) map[string][]string {
@@ -11,8 +11,11 @@ import (
 	"github.com/werf/werf/pkg/storage/manager"
 )
 
+const purgeReason = "purge"
+
 type PurgeOptions struct {
 	RmContainersThatUseWerfImages bool
+	ReportPath                    string
 	DryRun                        bool
 }
 
@@ -25,7 +28,9 @@ func newPurgeManager(projectName string, storageManager *manager.StorageManager,
 		StorageManager:                storageManager,
 		ProjectName:                   projectName,
 		RmContainersThatUseWerfImages: options.RmContainersThatUseWerfImages,
+		ReportPath:                    options.ReportPath,
 		DryRun:                        options.DryRun,
+		report:                        NewReport(options.DryRun),
 	}
 }
 
@@ -33,10 +38,17 @@ type purgeManager struct {
 	StorageManager                *manager.StorageManager
 	ProjectName                   string
 	RmContainersThatUseWerfImages bool
+	ReportPath                    string
 	DryRun                        bool
+
+	report *Report
 }
 
-func (m *purgeManager) run(ctx context.Context) error {
+func (m *purgeManager) run(ctx context.Context) (err error) {
+	defer func() {
+		err = writeReport(ctx, m.report, m.ReportPath, err)
+	}()
+
 	if err := logboek.Context(ctx).Default().LogProcess("Deleting stages").DoError(func() error {
 		stages, err := m.StorageManager.GetStageDescriptionList(ctx)
 		if err != nil {
@@ -48,6 +60,19 @@ func (m *purgeManager) run(ctx context.Context) error {
 		return err
 	}
 
+	if repoStagesStorage, ok := m.StorageManager.StagesStorage.(*storage.RepoStagesStorage); ok {
+		if err := logboek.Context(ctx).Default().LogProcess("Deleting quarantined stages").DoError(func() error {
+			quarantinedStages, err := repoStagesStorage.GetQuarantinedStages(ctx)
+			if err != nil {
+				return err
+			}
+
+			return deleteQuarantinedStages(ctx, repoStagesStorage, quarantinedStages, m.DryRun, m.report, purgeReason)
+		}); err != nil {
+			return err
+		}
+	}
+
 	if err := logboek.Context(ctx).Default().LogProcess("Deleting imports metadata").DoError(func() error {
 		importMetadataIDs, err := m.StorageManager.StagesStorage.GetImportMetadataIDs(ctx, m.ProjectName)
 		if err != nil {
@@ -106,11 +131,11 @@ func (m *purgeManager) deleteStages(ctx context.Context, stages []*image.StageDe
 		},
 	}
 
-	return deleteStages(ctx, m.StorageManager, m.DryRun, deleteStageOptions, stages)
+	return deleteStages(ctx, m.StorageManager, m.DryRun, deleteStageOptions, stages, m.report, purgeReason)
 }
 
 func (m *purgeManager) deleteImportsMetadata(ctx context.Context, importsMetadataIDs []string) error {
-	return deleteImportsMetadata(ctx, m.ProjectName, m.StorageManager, importsMetadataIDs, m.DryRun)
+	return deleteImportsMetadata(ctx, m.ProjectName, m.StorageManager, importsMetadataIDs, m.DryRun, m.report, purgeReason)
 }
 
 func (m *purgeManager) deleteManagedImages(ctx context.Context, managedImages []string) error {
@@ -140,5 +165,5 @@ func (m *purgeManager) deleteManagedImages(ctx context.Context, managedImages []
 }
 
 func (m *purgeManager) deleteImageMetadata(ctx context.Context, imageNameOrID string, stageIDCommitList map[string][]string) error {
-	return deleteImageMetadata(ctx, m.ProjectName, m.StorageManager, imageNameOrID, stageIDCommitList, m.DryRun)
+	return deleteImageMetadata(ctx, m.ProjectName, m.StorageManager, imageNameOrID, stageIDCommitList, m.DryRun, m.report, purgeReason)
 }
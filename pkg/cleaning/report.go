@@ -0,0 +1,158 @@
+package cleaning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/werf/logboek"
+)
+
+type ReportAction string
+
+const (
+	ReportActionDeleted     ReportAction = "deleted"
+	ReportActionQuarantined ReportAction = "quarantined"
+	ReportActionKept        ReportAction = "kept"
+)
+
+// Report lists stages, image metadata and import metadata processed by cleanup or purge with the reason of the decision.
+// In the dry run mode deleted records are the records that would be deleted.
+// Error is set if cleanup or purge failed, in this case the report lists only the records processed before the failure.
+type Report struct {
+	mux             sync.Mutex
+	keptRecords     map[interface{}]bool
+	DryRun          bool
+	Error           string `json:",omitempty"`
+	Stages          []ReportStageRecord
+	ImagesMetadata  []ReportImageMetadataRecord
+	ImportsMetadata []ReportImportMetadataRecord
+}
+
+type ReportStageRecord struct {
+	Tag     string
+	ImageID string
+	Action  ReportAction
+	Reason  string
+}
+
+type ReportImageMetadataRecord struct {
+	ImageName string
+	StageID   string
+	Commit    string
+	Action    ReportAction
+	Reason    string
+}
+
+type ReportImportMetadataRecord struct {
+	ImportMetadataID string
+	Action           ReportAction
+	Reason           string
+}
+
+func NewReport(dryRun bool) *Report {
+	return &Report{
+		keptRecords:     map[interface{}]bool{},
+		DryRun:          dryRun,
+		Stages:          []ReportStageRecord{},
+		ImagesMetadata:  []ReportImageMetadataRecord{},
+		ImportsMetadata: []ReportImportMetadataRecord{},
+	}
+}
+
+func (report *Report) AddStageRecord(record ReportStageRecord) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	if report.isKeptRecordAdded(record.Action, ReportStageRecord{Tag: record.Tag}) {
+		return
+	}
+
+	report.Stages = append(report.Stages, record)
+}
+
+func (report *Report) AddImageMetadataRecord(record ReportImageMetadataRecord) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	if report.isKeptRecordAdded(record.Action, ReportImageMetadataRecord{ImageName: record.ImageName, StageID: record.StageID, Commit: record.Commit}) {
+		return
+	}
+
+	report.ImagesMetadata = append(report.ImagesMetadata, record)
+}
+
+func (report *Report) AddImportMetadataRecord(record ReportImportMetadataRecord) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	if report.isKeptRecordAdded(record.Action, ReportImportMetadataRecord{ImportMetadataID: record.ImportMetadataID}) {
+		return
+	}
+
+	report.ImportsMetadata = append(report.ImportsMetadata, record)
+}
+
+// isKeptRecordAdded checks whether the kept record with the same key has already been added to the report and remembers the key.
+// The first reason is kept, because the object can be kept for several reasons.
+func (report *Report) isKeptRecordAdded(action ReportAction, key interface{}) bool {
+	if action != ReportActionKept {
+		return false
+	}
+
+	if report.keptRecords[key] {
+		return true
+	}
+
+	report.keptRecords[key] = true
+	return false
+}
+
+func (report *Report) ToJsonData() ([]byte, error) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	data, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, []byte("\n")...)
+
+	return data, nil
+}
+
+// writeReport writes the report with the error of the run and returns the run error or the report writing error
+func writeReport(ctx context.Context, report *Report, reportPath string, runErr error) error {
+	if reportPath == "" {
+		return runErr
+	}
+
+	if runErr != nil {
+		report.mux.Lock()
+		report.Error = runErr.Error()
+		report.mux.Unlock()
+	}
+
+	data, err := report.ToJsonData()
+	if err != nil {
+		return reportError(ctx, runErr, fmt.Errorf("unable to prepare report json: %s", err))
+	}
+	logboek.Context(ctx).Debug().LogF("Writing json report to the %q:\n%s", reportPath, data)
+
+	if err := ioutil.WriteFile(reportPath, data, 0644); err != nil {
+		return reportError(ctx, runErr, fmt.Errorf("unable to write report to %s: %s", reportPath, err))
+	}
+
+	return runErr
+}
+
+func reportError(ctx context.Context, runErr, reportErr error) error {
+	if runErr == nil {
+		return reportErr
+	}
+
+	logboek.Context(ctx).Warn().LogF("WARNING: %s\n", reportErr)
+	return runErr
+}
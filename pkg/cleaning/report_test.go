@@ -0,0 +1,134 @@
+package cleaning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+var _ = Describe("report", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "werf-cleanup-report-test")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Ω(os.RemoveAll(tmpDir)).Should(Succeed())
+	})
+
+	readReport := func(path string) *Report {
+		data, err := ioutil.ReadFile(path)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		report := &Report{}
+		Ω(json.Unmarshal(data, report)).Should(Succeed())
+		return report
+	}
+
+	newStage := func(tag, parentTag string) *image.StageDescription {
+		info := &image.Info{Name: "repo:" + tag, Tag: tag, ID: "sha256:" + tag}
+		if parentTag != "" {
+			info.ParentID = "sha256:" + parentTag
+		}
+
+		return &image.StageDescription{Info: info}
+	}
+
+	It("should list kept and deleted stages and images metadata", func() {
+		stage := newStage("stage", "parent")
+		parent := newStage("parent", "")
+		unused := newStage("unused", "")
+
+		m := &cleanupManager{
+			stages:             []*image.StageDescription{stage, parent, unused},
+			report:             NewReport(true),
+			stageIDKeepReasons: map[string]string{"stage": "used in Kubernetes"},
+			imageNameStageIDCommitList: map[string]map[string][]string{
+				"app": {"stage": {"commit1", "commit2"}},
+			},
+		}
+
+		m.reportKeptImagesMetadata()
+
+		stagesToDelete, excludedStages := m.excludeStageAndRelativesByImageID(m.stages, stage.Info.ID)
+		m.reportKeptStages(stage, excludedStages, m.stageIDKeepReason("stage"))
+		Ω(deleteStages(context.Background(), &manager.StorageManager{}, true, manager.ForEachDeleteStageOptions{}, stagesToDelete, m.report, "not used")).Should(Succeed())
+
+		Ω(m.report.Stages).Should(Equal([]ReportStageRecord{
+			{Tag: "stage", ImageID: "sha256:stage", Action: ReportActionKept, Reason: "used in Kubernetes"},
+			{Tag: "parent", ImageID: "sha256:parent", Action: ReportActionKept, Reason: "related to stage stage: used in Kubernetes"},
+			{Tag: "unused", ImageID: "sha256:unused", Action: ReportActionDeleted, Reason: "not used"},
+		}))
+		Ω(m.report.ImagesMetadata).Should(ConsistOf(
+			ReportImageMetadataRecord{ImageName: "app", StageID: "stage", Commit: "commit1", Action: ReportActionKept, Reason: "used in Kubernetes"},
+			ReportImageMetadataRecord{ImageName: "app", StageID: "stage", Commit: "commit2", Action: ReportActionKept, Reason: "used in Kubernetes"},
+		))
+	})
+
+	It("should not duplicate kept records", func() {
+		report := NewReport(false)
+
+		report.AddStageRecord(ReportStageRecord{Tag: "stage", Action: ReportActionKept, Reason: "first"})
+		report.AddStageRecord(ReportStageRecord{Tag: "stage", Action: ReportActionKept, Reason: "second"})
+		report.AddStageRecord(ReportStageRecord{Tag: "other", Action: ReportActionQuarantined, Reason: "not used"})
+		report.AddStageRecord(ReportStageRecord{Tag: "other", Action: ReportActionKept, Reason: "deletion failed"})
+
+		report.AddImageMetadataRecord(ReportImageMetadataRecord{ImageName: "app", StageID: "stage", Commit: "commit", Action: ReportActionKept, Reason: "first"})
+		report.AddImageMetadataRecord(ReportImageMetadataRecord{ImageName: "app", StageID: "stage", Commit: "commit", Action: ReportActionKept, Reason: "second"})
+		report.AddImportMetadataRecord(ReportImportMetadataRecord{ImportMetadataID: "import", Action: ReportActionKept, Reason: "first"})
+		report.AddImportMetadataRecord(ReportImportMetadataRecord{ImportMetadataID: "import", Action: ReportActionKept, Reason: "second"})
+
+		Ω(report.Stages).Should(Equal([]ReportStageRecord{
+			{Tag: "stage", Action: ReportActionKept, Reason: "first"},
+			{Tag: "other", Action: ReportActionQuarantined, Reason: "not used"},
+			{Tag: "other", Action: ReportActionKept, Reason: "deletion failed"},
+		}))
+		Ω(report.ImagesMetadata).Should(HaveLen(1))
+		Ω(report.ImagesMetadata[0].Reason).Should(Equal("first"))
+		Ω(report.ImportsMetadata).Should(HaveLen(1))
+		Ω(report.ImportsMetadata[0].Reason).Should(Equal("first"))
+	})
+
+	It("should write the report with the run error", func() {
+		reportPath := filepath.Join(tmpDir, "report.json")
+		report := NewReport(false)
+		report.AddStageRecord(ReportStageRecord{Tag: "stage", Action: ReportActionDeleted, Reason: "not used"})
+
+		runErr := errors.New("registry is unavailable")
+		Ω(writeReport(context.Background(), report, reportPath, runErr)).Should(Equal(runErr))
+
+		writtenReport := readReport(reportPath)
+		Ω(writtenReport.Error).Should(Equal("registry is unavailable"))
+		Ω(writtenReport.Stages).Should(Equal([]ReportStageRecord{{Tag: "stage", Action: ReportActionDeleted, Reason: "not used"}}))
+	})
+
+	It("should write the report without the error if the run succeeded", func() {
+		reportPath := filepath.Join(tmpDir, "report.json")
+		Ω(writeReport(context.Background(), NewReport(true), reportPath, nil)).Should(Succeed())
+
+		data, err := ioutil.ReadFile(reportPath)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(data)).ShouldNot(ContainSubstring("Error"))
+		Ω(readReport(reportPath).DryRun).Should(BeTrue())
+	})
+
+	It("should return the run error if the report cannot be written", func() {
+		reportPath := filepath.Join(tmpDir, "nonexistent", "report.json")
+
+		runErr := errors.New("registry is unavailable")
+		Ω(writeReport(context.Background(), NewReport(false), reportPath, runErr)).Should(Equal(runErr))
+		Ω(writeReport(context.Background(), NewReport(false), reportPath, nil)).Should(MatchError(ContainSubstring("unable to write report")))
+	})
+})
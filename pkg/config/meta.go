@@ -1,9 +1,12 @@
 package config
 
+import "github.com/werf/werf/pkg/docker_registry"
+
 type Meta struct {
-	ConfigVersion int
-	Project       string
-	Deploy        MetaDeploy
-	Cleanup       MetaCleanup
-	GitWorktree   MetaGitWorktree
+	ConfigVersion   int
+	Project         string
+	Deploy          MetaDeploy
+	Cleanup         MetaCleanup
+	GitWorktree     MetaGitWorktree
+	RegistryMirrors docker_registry.RegistryMirrors
 }
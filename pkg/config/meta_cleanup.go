@@ -2,22 +2,66 @@ package config
 
 import (
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
 	"time"
 )
 
 type MetaCleanup struct {
-	KeepPolicies []*MetaCleanupKeepPolicy
+	KeepPolicies        []*MetaCleanupKeepPolicy
+	KubernetesResources []*MetaCleanupKubernetesResource
+}
+
+// MetaCleanupKubernetesResource is the additional kind of the Kubernetes objects (e.g. CRD) which images should be kept by cleanup
+type MetaCleanupKubernetesResource struct {
+	ApiVersion      string
+	Kind            string
+	ImagesJsonPaths []string
+}
+
+func (r *MetaCleanupKubernetesResource) String() string {
+	return fmt.Sprintf("%s/%s", r.ApiVersion, r.Kind)
+}
+
+// ImageKeepPolicies returns the policies applicable to the image, nil means that default policies should be used
+func (c MetaCleanup) ImageKeepPolicies(imageName string) []*MetaCleanupKeepPolicy {
+	var res []*MetaCleanupKeepPolicy
+	for _, policy := range c.KeepPolicies {
+		if policy.MatchImage(imageName) {
+			res = append(res, policy)
+		}
+	}
+
+	return res
 }
 
 type MetaCleanupKeepPolicy struct {
+	Images             []string
 	References         MetaCleanupKeepPolicyReferences
 	ImagesPerReference MetaCleanupKeepPolicyImagesPerReference
 }
 
+// MatchImage checks whether the policy is applicable to the image, the policy without images is applicable to all images
+func (p *MetaCleanupKeepPolicy) MatchImage(imageName string) bool {
+	if len(p.Images) == 0 {
+		return true
+	}
+
+	for _, image := range p.Images {
+		if matched, _ := path.Match(image, imageName); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (p *MetaCleanupKeepPolicy) String() string {
 	var parts []string
+	if len(p.Images) != 0 {
+		parts = append(parts, fmt.Sprintf("images=[%s]", strings.Join(p.Images, ", ")))
+	}
 	parts = append(parts, fmt.Sprintf("references={%s}", p.References.String()))
 
 	imagesPerReferencePart := p.ImagesPerReference.String()
@@ -0,0 +1,28 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("selecting cleanup keep policies for image", func(imageName string, expectedPolicies []int) {
+	metaCleanup := MetaCleanup{
+		KeepPolicies: []*MetaCleanupKeepPolicy{
+			{},
+			{Images: []string{"backend", "worker-*"}},
+			{Images: []string{"frontend"}},
+		},
+	}
+
+	var expected []*MetaCleanupKeepPolicy
+	for _, ind := range expectedPolicies {
+		expected = append(expected, metaCleanup.KeepPolicies[ind])
+	}
+
+	Ω(metaCleanup.ImageKeepPolicies(imageName)).Should(Equal(expected))
+},
+	Entry("image by name", "backend", []int{0, 1}),
+	Entry("image by glob", "worker-queue", []int{0, 1}),
+	Entry("another image by name", "frontend", []int{0, 2}),
+	Entry("image without scoped policies", "tools", []int{0}),
+)
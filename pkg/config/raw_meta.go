@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/slug"
 )
 
@@ -13,8 +14,10 @@ type rawMeta struct {
 	Deploy             *rawMetaDeploy      `yaml:"deploy,omitempty"`
 	Cleanup            *rawMetaCleanup     `yaml:"cleanup,omitempty"`
 	GitWorktree        *rawMetaGitWorktree `yaml:"gitWorktree,omitempty"`
+	RegistryMirrors    map[string]string   `yaml:"registryMirrors,omitempty"`
 
-	doc *doc `yaml:"-"` // parent
+	doc             *doc                            `yaml:"-"` // parent
+	registryMirrors docker_registry.RegistryMirrors `yaml:"-"`
 
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
 }
@@ -44,6 +47,12 @@ func (c *rawMeta) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return newDetailedConfigError(fmt.Sprintf("bad project name '%s' specified in config: %s", *c.Project, err), nil, c.doc)
 	}
 
+	registryMirrors, err := docker_registry.NewRegistryMirrors(c.RegistryMirrors)
+	if err != nil {
+		return newDetailedConfigError(fmt.Sprintf("bad 'registryMirrors' specified in config: %s", err), nil, c.doc)
+	}
+	c.registryMirrors = registryMirrors
+
 	return nil
 }
 
@@ -75,5 +84,7 @@ func (c *rawMeta) toMeta() *Meta {
 		meta.GitWorktree = c.GitWorktree.toMetaGitWorktree()
 	}
 
+	meta.RegistryMirrors = c.registryMirrors
+
 	return meta
 }
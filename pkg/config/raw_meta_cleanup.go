@@ -2,19 +2,24 @@ package config
 
 import (
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
 	"time"
+
+	"k8s.io/client-go/util/jsonpath"
 )
 
 type rawMetaCleanup struct {
-	KeepPolicies []*rawMetaCleanupKeepPolicy `yaml:"keepPolicies,omitempty"`
+	KeepPolicies        []*rawMetaCleanupKeepPolicy         `yaml:"keepPolicies,omitempty"`
+	KubernetesResources []*rawMetaCleanupKubernetesResource `yaml:"kubernetesResources,omitempty"`
 
 	rawMeta               *rawMeta
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
 }
 
 type rawMetaCleanupKeepPolicy struct {
+	Images             []string                                    `yaml:"images,omitempty"`
 	References         *rawMetaCleanupKeepPolicyReferences         `yaml:"references,omitempty"`
 	ImagesPerReference *rawMetaCleanupKeepPolicyImagesPerReference `yaml:"imagesPerReference,omitempty"`
 
@@ -36,6 +41,15 @@ type rawMetaCleanupKeepPolicyReferences struct {
 
 type rawMetaCleanupKeepPolicyImagesPerReference rawMetaCleanupKeepPolicyReferencesLimit
 
+type rawMetaCleanupKubernetesResource struct {
+	ApiVersion      string   `yaml:"apiVersion,omitempty"`
+	Kind            string   `yaml:"kind,omitempty"`
+	ImagesJsonPaths []string `yaml:"imagesJsonPaths,omitempty"`
+
+	rawMetaCleanup        *rawMetaCleanup
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
 type rawMetaCleanupKeepPolicyReferencesLimit struct {
 	Last     *int           `yaml:"last,omitempty"`
 	In       *time.Duration `yaml:"in,omitempty"`
@@ -86,6 +100,46 @@ func (c *rawMetaCleanupKeepPolicy) UnmarshalYAML(unmarshal func(interface{}) err
 		return newDetailedConfigError("cleanup keep policy must have references section!", c, c.rawMetaCleanup.rawMeta.doc)
 	}
 
+	for _, image := range c.Images {
+		if _, err := path.Match(image, ""); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("invalid value '%s' for `images: [string|GLOB, ...]`!", image), c, c.rawMetaCleanup.rawMeta.doc)
+		}
+	}
+
+	return nil
+}
+
+func (c *rawMetaCleanupKubernetesResource) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaCleanup); ok {
+		c.rawMetaCleanup = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaCleanupKubernetesResource
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawMetaCleanup.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.ApiVersion == "" || c.Kind == "" {
+		return newDetailedConfigError("`apiVersion: string` and `kind: string` required for cleanup kubernetes resource!", c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	if len(c.ImagesJsonPaths) == 0 {
+		return newDetailedConfigError("`imagesJsonPaths: [JSONPATH, ...]` required for cleanup kubernetes resource!", c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	for _, imagesJsonPath := range c.ImagesJsonPaths {
+		if err := jsonpath.New("").Parse(imagesJsonPath); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("invalid value '%s' for `imagesJsonPaths: [JSONPATH, ...]`: %s!", imagesJsonPath, err), c, c.rawMetaCleanup.rawMeta.doc)
+		}
+	}
+
 	return nil
 }
 
@@ -185,11 +239,24 @@ func (c *rawMetaCleanup) toMetaCleanup() MetaCleanup {
 		metaCleanup.KeepPolicies = append(metaCleanup.KeepPolicies, policy.toMetaCleanupKeepPolicy())
 	}
 
+	for _, resource := range c.KubernetesResources {
+		metaCleanup.KubernetesResources = append(metaCleanup.KubernetesResources, resource.toMetaCleanupKubernetesResource())
+	}
+
 	return metaCleanup
 }
 
+func (c *rawMetaCleanupKubernetesResource) toMetaCleanupKubernetesResource() *MetaCleanupKubernetesResource {
+	return &MetaCleanupKubernetesResource{
+		ApiVersion:      c.ApiVersion,
+		Kind:            c.Kind,
+		ImagesJsonPaths: c.ImagesJsonPaths,
+	}
+}
+
 func (c *rawMetaCleanupKeepPolicy) toMetaCleanupKeepPolicy() *MetaCleanupKeepPolicy {
 	policy := &MetaCleanupKeepPolicy{}
+	policy.Images = c.Images
 
 	if c.References != nil {
 		policy.References = c.References.toMetaCleanupKeepPolicyReferences()
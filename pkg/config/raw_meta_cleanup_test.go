@@ -0,0 +1,52 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("parsing cleanup kubernetes resources", func(kubernetesResources string, expectedError string) {
+	content := "configVersion: 1\nproject: test\ncleanup:\n  kubernetesResources:\n" + kubernetesResources
+	meta, _, _, err := splitByMetaAndRawImages([]*doc{{Content: []byte(content), RenderFilePath: "werf.yaml"}})
+
+	if expectedError != "" {
+		Ω(err).Should(MatchError(ContainSubstring(expectedError)))
+		return
+	}
+
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(meta.Cleanup.KubernetesResources).Should(Equal([]*MetaCleanupKubernetesResource{{
+		ApiVersion:      "argoproj.io/v1alpha1",
+		Kind:            "Rollout",
+		ImagesJsonPaths: []string{"{.spec.template.spec.containers[*].image}"},
+	}}))
+},
+	Entry("valid resource", `
+  - apiVersion: argoproj.io/v1alpha1
+    kind: Rollout
+    imagesJsonPaths: ["{.spec.template.spec.containers[*].image}"]
+`, ""),
+	Entry("without apiVersion", `
+  - kind: Rollout
+    imagesJsonPaths: ["{.spec.template.spec.containers[*].image}"]
+`, "`apiVersion: string` and `kind: string` required for cleanup kubernetes resource!"),
+	Entry("without kind", `
+  - apiVersion: argoproj.io/v1alpha1
+    imagesJsonPaths: ["{.spec.template.spec.containers[*].image}"]
+`, "`apiVersion: string` and `kind: string` required for cleanup kubernetes resource!"),
+	Entry("without imagesJsonPaths", `
+  - apiVersion: argoproj.io/v1alpha1
+    kind: Rollout
+`, "`imagesJsonPaths: [JSONPATH, ...]` required for cleanup kubernetes resource!"),
+	Entry("with invalid jsonpath", `
+  - apiVersion: argoproj.io/v1alpha1
+    kind: Rollout
+    imagesJsonPaths: ["{.spec.template.spec.containers[*].image"]
+`, "invalid value '{.spec.template.spec.containers[*].image' for `imagesJsonPaths: [JSONPATH, ...]`"),
+	Entry("with unknown field", `
+  - apiVersion: argoproj.io/v1alpha1
+    kind: Rollout
+    imagesJsonPaths: ["{.spec.template.spec.containers[*].image}"]
+    images: ["{.spec.template.spec.containers[*].image}"]
+`, "unknown fields: `images`!"),
+)
@@ -3,6 +3,7 @@ package container_runtime
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
@@ -40,6 +41,43 @@ func (runtime *LocalDockerServerRuntime) PullImage(ctx context.Context, ref stri
 	return nil
 }
 
+// PullImageThroughMirror only available for LocalDockerServerRuntime
+func (runtime *LocalDockerServerRuntime) PullImageThroughMirror(ctx context.Context, ref, mirrorRef string) error {
+	return pullImageThroughMirror(ctx, ref, mirrorRef)
+}
+
+// pullImageThroughMirror pulls the image from the mirror and tags it by the canonical reference.
+// The image is pulled from the registry if the mirror is not available or the reference cannot be tagged (the reference with digest).
+func pullImageThroughMirror(ctx context.Context, ref, mirrorRef string) error {
+	if strings.Contains(ref, "@") {
+		return pullImage(ctx, ref)
+	}
+
+	if err := docker.CliPullWithRetries(ctx, mirrorRef); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: unable to pull image %s from mirror %s: %s\n", ref, mirrorRef, err)
+		logboek.Context(ctx).Warn().LogF("WARNING: pulling image %s from registry\n", ref)
+		return pullImage(ctx, ref)
+	}
+
+	if err := docker.CliTag(ctx, mirrorRef, ref); err != nil {
+		return fmt.Errorf("unable to tag image %s by name %s: %s", mirrorRef, ref, err)
+	}
+
+	if err := docker.CliRmi(ctx, mirrorRef); err != nil {
+		return fmt.Errorf("unable to remove image tag %s: %s", mirrorRef, err)
+	}
+
+	return nil
+}
+
+func pullImage(ctx context.Context, ref string) error {
+	if err := docker.CliPullWithRetries(ctx, ref); err != nil {
+		return fmt.Errorf("unable to pull image %s: %s", ref, err)
+	}
+
+	return nil
+}
+
 func (runtime *LocalDockerServerRuntime) RefreshImageObject(ctx context.Context, img Image) error {
 	dockerImage := img.(*DockerImage)
 
@@ -12,14 +12,15 @@ import (
 )
 
 type DockerfileImageBuilder struct {
-	temporalId      string
-	isBuilt         bool
-	buildArgs       []string
-	filePathToStdin string
+	temporalId        string
+	isBuilt           bool
+	buildArgs         []string
+	filePathToStdin   string
+	baseImagesMirrors map[string]string
 }
 
 func NewDockerfileImageBuilder() *DockerfileImageBuilder {
-	return &DockerfileImageBuilder{temporalId: uuid.New().String()}
+	return &DockerfileImageBuilder{temporalId: uuid.New().String(), baseImagesMirrors: map[string]string{}}
 }
 
 func (b *DockerfileImageBuilder) GetBuiltId() string {
@@ -37,7 +38,16 @@ func (b *DockerfileImageBuilder) SetFilePathToStdin(path string) {
 	b.filePathToStdin = path
 }
 
+// AddBaseImageMirror makes the builder pull the base image from the mirror before the build if the image does not exist locally
+func (b *DockerfileImageBuilder) AddBaseImageMirror(ref, mirrorRef string) {
+	b.baseImagesMirrors[ref] = mirrorRef
+}
+
 func (b *DockerfileImageBuilder) Build(ctx context.Context) error {
+	if err := b.pullBaseImagesThroughMirrors(ctx); err != nil {
+		return err
+	}
+
 	buildArgs := append(b.buildArgs, fmt.Sprintf("--tag=%s", b.temporalId))
 
 	if b.filePathToStdin != "" {
@@ -71,6 +81,22 @@ func (b *DockerfileImageBuilder) Build(ctx context.Context) error {
 	return nil
 }
 
+func (b *DockerfileImageBuilder) pullBaseImagesThroughMirrors(ctx context.Context) error {
+	for ref, mirrorRef := range b.baseImagesMirrors {
+		if exist, err := docker.ImageExist(ctx, ref); err != nil {
+			return err
+		} else if exist {
+			continue
+		}
+
+		if err := pullImageThroughMirror(ctx, ref, mirrorRef); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (b *DockerfileImageBuilder) Cleanup(ctx context.Context) error {
 	if err := docker.CliRmi(ctx, b.temporalId, "--force"); err != nil {
 		return fmt.Errorf("unable to remove temporal dockerfile image %q: %s", b.temporalId, err)
@@ -15,4 +15,7 @@ const (
 	ShowLogsUntilAnnoName         = "werf.io/show-logs-until"
 
 	ShowEventsAnnoName = "werf.io/show-service-messages"
+
+	WeightAnnoName    = "werf.io/weight"
+	DependsOnAnnoName = "werf.io/depends-on"
 )
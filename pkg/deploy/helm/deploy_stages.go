@@ -0,0 +1,216 @@
+package helm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	helm_kube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// deployStage is a group of release resources applied together,
+// the external dependencies are the resources outside the release which should be ready before the stage is applied.
+type deployStage struct {
+	Weight               int
+	Resources            helm_kube.ResourceList
+	ExternalDependencies []*resourceRef
+}
+
+type resourceRef struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+func (ref *resourceRef) String() string {
+	if ref.Namespace == "" {
+		return fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", ref.Namespace, ref.Kind, ref.Name)
+}
+
+// Matches checks the resource by the kind or the resource type (e.g. job or jobs), the namespace is ignored for cluster-wide resources.
+func (ref *resourceRef) Matches(info *resource.Info) bool {
+	if info.Name != ref.Name {
+		return false
+	}
+
+	if info.Mapping.Scope.Name() == meta.RESTScopeNameNamespace && info.Namespace != ref.Namespace {
+		return false
+	}
+
+	return ref.Kind == strings.ToLower(info.Mapping.GroupVersionKind.Kind) || ref.Kind == info.Mapping.Resource.Resource
+}
+
+type plannedResource struct {
+	Info         *resource.Info
+	Weight       int
+	Dependencies []*resourceRef
+}
+
+// planDeployStages splits the resources into the stages ordered by werf.io/weight annotation (0 by default),
+// the resources with the same weight are split further, so that each resource is applied after the release resources it depends on.
+func planDeployStages(resources helm_kube.ResourceList, defaultNamespace string) ([]*deployStage, error) {
+	var planned []*plannedResource
+	weights := map[int]bool{}
+
+	for _, info := range resources {
+		annotations, err := meta.NewAccessor().Annotations(info.Object)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get annotations of %s: %s", describeResource(info), err)
+		}
+
+		r := &plannedResource{Info: info}
+
+		if value, ok := annotations[WeightAnnoName]; ok {
+			if r.Weight, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("%s annotation %s with invalid value %s: integer expected", describeResource(info), WeightAnnoName, value)
+			}
+		}
+
+		if value, ok := annotations[DependsOnAnnoName]; ok {
+			namespace := info.Namespace
+			if namespace == "" {
+				namespace = defaultNamespace
+			}
+
+			if r.Dependencies, err = parseDependsOnAnnotation(value, namespace); err != nil {
+				return nil, fmt.Errorf("%s annotation %s with invalid value %s: %s", describeResource(info), DependsOnAnnoName, value, err)
+			}
+		}
+
+		planned = append(planned, r)
+		weights[r.Weight] = true
+	}
+
+	findInRelease := func(ref *resourceRef) *plannedResource {
+		for _, r := range planned {
+			if ref.Matches(r.Info) {
+				return r
+			}
+		}
+
+		return nil
+	}
+
+	for _, r := range planned {
+		for _, ref := range r.Dependencies {
+			if dependency := findInRelease(ref); dependency != nil && dependency.Weight > r.Weight {
+				return nil, fmt.Errorf("%s with weight %d depends on %s with greater weight %d", describeResource(r.Info), r.Weight, ref, dependency.Weight)
+			}
+		}
+	}
+
+	var sortedWeights []int
+	for weight := range weights {
+		sortedWeights = append(sortedWeights, weight)
+	}
+	sort.Ints(sortedWeights)
+
+	var stages []*deployStage
+	applied := map[*resource.Info]bool{}
+
+	for _, weight := range sortedWeights {
+		var pending []*plannedResource
+		for _, r := range planned {
+			if r.Weight == weight {
+				pending = append(pending, r)
+			}
+		}
+
+		for len(pending) > 0 {
+			stage := &deployStage{Weight: weight}
+			externalDependencies := map[string]bool{}
+
+			var next []*plannedResource
+		pendingLoop:
+			for _, r := range pending {
+				for _, ref := range r.Dependencies {
+					if dependency := findInRelease(ref); dependency != nil && !applied[dependency.Info] {
+						next = append(next, r)
+						continue pendingLoop
+					}
+				}
+
+				stage.Resources = append(stage.Resources, r.Info)
+
+				for _, ref := range r.Dependencies {
+					if findInRelease(ref) == nil && !externalDependencies[ref.String()] {
+						externalDependencies[ref.String()] = true
+						stage.ExternalDependencies = append(stage.ExternalDependencies, ref)
+					}
+				}
+			}
+
+			if len(stage.Resources) == 0 {
+				var descs []string
+				for _, r := range next {
+					descs = append(descs, describeResource(r.Info))
+				}
+
+				return nil, fmt.Errorf("circular %s annotations: %s", DependsOnAnnoName, strings.Join(descs, ", "))
+			}
+
+			for _, info := range stage.Resources {
+				applied[info] = true
+			}
+
+			stages = append(stages, stage)
+			pending = next
+		}
+	}
+
+	return stages, nil
+}
+
+// parseDependsOnAnnotation parses the comma separated list of kind/name or namespace/kind/name references.
+func parseDependsOnAnnotation(value, defaultNamespace string) ([]*resourceRef, error) {
+	var refs []*resourceRef
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		ref := &resourceRef{Namespace: defaultNamespace}
+
+		fields := strings.Split(part, "/")
+		switch len(fields) {
+		case 2:
+			ref.Kind, ref.Name = fields[0], fields[1]
+		case 3:
+			ref.Namespace, ref.Kind, ref.Name = fields[0], fields[1], fields[2]
+		}
+
+		if ref.Namespace == "" || ref.Kind == "" || ref.Name == "" {
+			return nil, fmt.Errorf("kind/name or namespace/kind/name expected, got %q", part)
+		}
+
+		ref.Kind = strings.ToLower(ref.Kind)
+		refs = append(refs, ref)
+	}
+
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("kind/name or namespace/kind/name expected")
+	}
+
+	return refs, nil
+}
+
+func describeResource(info *resource.Info) string {
+	return fmt.Sprintf("%s/%s", strings.ToLower(info.Mapping.GroupVersionKind.Kind), info.Name)
+}
+
+func describeResources(resources helm_kube.ResourceList) string {
+	var descs []string
+	for _, info := range resources {
+		descs = append(descs, describeResource(info))
+	}
+
+	return strings.Join(descs, ", ")
+}
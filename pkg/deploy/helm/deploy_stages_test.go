@@ -0,0 +1,149 @@
+package helm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	helm_kube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func newTestInfo(kind, name string, annotations map[string]string) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace("ns")
+	obj.SetAnnotations(annotations)
+
+	return &resource.Info{
+		Name:      name,
+		Namespace: "ns",
+		Object:    obj,
+		Mapping: &meta.RESTMapping{
+			GroupVersionKind: schema.GroupVersionKind{Kind: kind},
+			Resource:         schema.GroupVersionResource{Resource: strings.ToLower(kind) + "s"},
+			Scope:            meta.RESTScopeNamespace,
+		},
+	}
+}
+
+func describeStages(stages []*deployStage) []string {
+	var res []string
+	for _, stage := range stages {
+		desc := describeResources(stage.Resources)
+		for _, ref := range stage.ExternalDependencies {
+			desc += " after " + ref.String()
+		}
+
+		res = append(res, desc)
+	}
+
+	return res
+}
+
+func TestPlanDeployStages(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources helm_kube.ResourceList
+		expected  []string
+	}{
+		{
+			name: "without annotations",
+			resources: helm_kube.ResourceList{
+				newTestInfo("Deployment", "app", nil),
+				newTestInfo("Service", "app", nil),
+			},
+			expected: []string{"deployment/app, service/app"},
+		},
+		{
+			name: "weights",
+			resources: helm_kube.ResourceList{
+				newTestInfo("Deployment", "app", nil),
+				newTestInfo("Job", "migrate", map[string]string{WeightAnnoName: "-10"}),
+				newTestInfo("Deployment", "worker", map[string]string{WeightAnnoName: "5"}),
+				newTestInfo("Service", "app", nil),
+			},
+			expected: []string{"job/migrate", "deployment/app, service/app", "deployment/worker"},
+		},
+		{
+			name: "dependencies with the same weight",
+			resources: helm_kube.ResourceList{
+				newTestInfo("Deployment", "app", map[string]string{DependsOnAnnoName: "job/migrate"}),
+				newTestInfo("Job", "migrate", map[string]string{DependsOnAnnoName: "Deployment/db"}),
+				newTestInfo("Deployment", "db", nil),
+				newTestInfo("Service", "app", nil),
+			},
+			expected: []string{"deployment/db, service/app", "job/migrate", "deployment/app"},
+		},
+		{
+			name: "external dependencies",
+			resources: helm_kube.ResourceList{
+				newTestInfo("Deployment", "app", map[string]string{DependsOnAnnoName: "statefulsets/db, other/job/init"}),
+				newTestInfo("Service", "app", nil),
+			},
+			expected: []string{"deployment/app, service/app after ns/statefulsets/db after other/job/init"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages, err := planDeployStages(tt.resources, "default")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := describeStages(stages); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("\n[EXPECTED]: %q\n[GOT]: %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPlanDeployStagesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources helm_kube.ResourceList
+		expected  string
+	}{
+		{
+			name:      "invalid weight",
+			resources: helm_kube.ResourceList{newTestInfo("Job", "migrate", map[string]string{WeightAnnoName: "first"})},
+			expected:  "job/migrate annotation werf.io/weight with invalid value first: integer expected",
+		},
+		{
+			name:      "invalid dependency",
+			resources: helm_kube.ResourceList{newTestInfo("Deployment", "app", map[string]string{DependsOnAnnoName: "migrate"})},
+			expected:  `deployment/app annotation werf.io/depends-on with invalid value migrate: kind/name or namespace/kind/name expected, got "migrate"`,
+		},
+		{
+			name: "dependency with greater weight",
+			resources: helm_kube.ResourceList{
+				newTestInfo("Deployment", "app", map[string]string{DependsOnAnnoName: "job/migrate"}),
+				newTestInfo("Job", "migrate", map[string]string{WeightAnnoName: "1"}),
+			},
+			expected: "deployment/app with weight 0 depends on ns/job/migrate with greater weight 1",
+		},
+		{
+			name: "circular dependencies",
+			resources: helm_kube.ResourceList{
+				newTestInfo("Deployment", "app", map[string]string{DependsOnAnnoName: "job/migrate"}),
+				newTestInfo("Job", "migrate", map[string]string{DependsOnAnnoName: "deployment/app"}),
+				newTestInfo("Service", "app", nil),
+			},
+			expected: "circular werf.io/depends-on annotations: deployment/app, job/migrate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planDeployStages(tt.resources, "default")
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %v", tt.expected, err)
+			}
+		})
+	}
+}
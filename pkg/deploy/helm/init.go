@@ -28,6 +28,8 @@ type InitActionConfigOptions struct {
 	HooksStatusProgressPeriod time.Duration
 	KubeConfigOptions         kube.KubeConfigOptions
 	ReleasesHistoryMax        int
+	// ResourcesWaitTimeout limits waiting for each deploy stage ordered by werf.io/weight and werf.io/depends-on annotations
+	ResourcesWaitTimeout time.Duration
 }
 
 func InitActionConfig(ctx context.Context, kubeInitializer KubeInitializer, namespace string, envSettings *cli.EnvSettings, actionConfig *action.Configuration, opts InitActionConfigOptions) error {
@@ -54,7 +56,9 @@ func InitActionConfig(ctx context.Context, kubeInitializer KubeInitializer, name
 	}
 
 	kubeClient := actionConfig.KubeClient.(*helm_kube.Client)
-	kubeClient.ResourcesWaiter = NewResourcesWaiter(kubeInitializer, kubeClient, time.Now(), opts.StatusProgressPeriod, opts.HooksStatusProgressPeriod)
+	resourcesWaiter := NewResourcesWaiter(kubeInitializer, kubeClient, time.Now(), opts.StatusProgressPeriod, opts.HooksStatusProgressPeriod)
+	kubeClient.ResourcesWaiter = resourcesWaiter
+	actionConfig.KubeClient = NewOrderedKubeClient(kubeClient, resourcesWaiter, opts.ResourcesWaitTimeout)
 
 	if registryClient, err := helm_v3.NewRegistryClient(logboek.Context(ctx).Debug().IsAccepted(), logboek.Context(ctx).ProxyOutStream()); err != nil {
 		return fmt.Errorf("unable to create registry client: %s", err)
@@ -0,0 +1,275 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/werf/logboek"
+	helm_kube "helm.sh/helm/v3/pkg/kube"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+const (
+	dependencyPollPeriod = 2 * time.Second
+	// defaultAvailableWaitTimeout limits waiting for the resource to be created when WaitTimeout is not set,
+	// so that the mistyped werf.io/depends-on annotation does not block the deploy forever
+	defaultAvailableWaitTimeout = 5 * time.Minute
+)
+
+// OrderedKubeClient creates and updates the release resources in stages ordered by werf.io/weight and werf.io/depends-on annotations:
+// the stage is applied only when the resources of the previous stage and the dependencies of the stage resources are ready.
+// Resources without these annotations are applied at once as helm does.
+type OrderedKubeClient struct {
+	*helm_kube.Client
+
+	ResourcesWaiter *ResourcesWaiter
+	// WaitTimeout limits waiting for each stage and dependency, when zero readiness is not limited
+	// and the resource is waited to be created for defaultAvailableWaitTimeout
+	WaitTimeout time.Duration
+
+	readyResources helm_kube.ResourceList
+}
+
+func NewOrderedKubeClient(client *helm_kube.Client, resourcesWaiter *ResourcesWaiter, waitTimeout time.Duration) *OrderedKubeClient {
+	return &OrderedKubeClient{
+		Client:          client,
+		ResourcesWaiter: resourcesWaiter,
+		WaitTimeout:     waitTimeout,
+	}
+}
+
+func (c *OrderedKubeClient) Create(resources helm_kube.ResourceList) (*helm_kube.Result, error) {
+	c.readyResources = nil
+
+	stages, err := planDeployStages(resources, c.defaultNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	if isSingleDeployStage(stages) {
+		return c.Client.Create(resources)
+	}
+
+	res := &helm_kube.Result{}
+	err = c.applyStages(stages, func(stage *deployStage) error {
+		stageRes, err := c.Client.Create(stage.Resources)
+		appendResult(res, stageRes)
+		return err
+	})
+
+	return res, err
+}
+
+func (c *OrderedKubeClient) Update(original, target helm_kube.ResourceList, force bool) (*helm_kube.Result, error) {
+	c.readyResources = nil
+
+	stages, err := planDeployStages(target, c.defaultNamespace())
+	if err != nil {
+		return &helm_kube.Result{}, err
+	}
+
+	if isSingleDeployStage(stages) {
+		return c.Client.Update(original, target, force)
+	}
+
+	res := &helm_kube.Result{}
+	if err := c.applyStages(stages, func(stage *deployStage) error {
+		// Only original resources of the stage are passed, otherwise all other original resources will be deleted
+		stageRes, err := c.Client.Update(original.Intersect(stage.Resources), stage.Resources, force)
+		appendResult(res, stageRes)
+		return err
+	}); err != nil {
+		return res, err
+	}
+
+	// Resources removed from the release are deleted when all the stages are applied
+	pruneRes, err := c.Client.Update(original.Difference(target), helm_kube.ResourceList{}, force)
+	appendResult(res, pruneRes)
+
+	return res, err
+}
+
+func (c *OrderedKubeClient) Wait(resources helm_kube.ResourceList, timeout time.Duration) error {
+	// Resources of the previous stages are ready already
+	resources = resources.Filter(func(info *resource.Info) bool {
+		return !c.readyResources.Contains(info)
+	})
+	c.readyResources = nil
+
+	return c.Client.Wait(resources, timeout)
+}
+
+func (c *OrderedKubeClient) applyStages(stages []*deployStage, applyFunc func(stage *deployStage) error) error {
+	// NOTE: kube client interface methods do not receive context
+	ctx := context.Background()
+
+	for i, stage := range stages {
+		for _, ref := range stage.ExternalDependencies {
+			if err := c.waitForDependency(ctx, ref); err != nil {
+				return err
+			}
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("Applying resources with weight %d: %s\n", stage.Weight, describeResources(stage.Resources))
+
+		if err := applyFunc(stage); err != nil {
+			return err
+		}
+
+		// The last stage is waited by the deploy process as usual
+		if i == len(stages)-1 {
+			break
+		}
+
+		// Custom resources of the next stages cannot be applied until their definitions are established
+		for _, info := range stage.Resources {
+			if info.Mapping.GroupVersionKind.Kind != "CustomResourceDefinition" {
+				continue
+			}
+
+			if _, err := c.waitUntilAvailable(ctx, &resourceRef{Kind: "customresourcedefinition", Name: info.Name}); err != nil {
+				return err
+			}
+		}
+
+		if trackedResources := stage.Resources.Filter(isTrackedResource); len(trackedResources) > 0 {
+			processTitle := fmt.Sprintf("Waiting for %s to become ready", describeResources(trackedResources))
+			if err := c.ResourcesWaiter.waitUntilReady(ctx, processTitle, trackedResources, c.WaitTimeout); err != nil {
+				return err
+			}
+		}
+
+		c.readyResources = append(c.readyResources, stage.Resources...)
+	}
+
+	return nil
+}
+
+// waitForDependency waits until the resource is available and then until it is ready if the kind is tracked.
+func (c *OrderedKubeClient) waitForDependency(ctx context.Context, ref *resourceRef) error {
+	info, err := c.waitUntilAvailable(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	if !isTrackedResource(info) {
+		return nil
+	}
+
+	return c.ResourcesWaiter.waitUntilReady(ctx, fmt.Sprintf("Waiting for %s to become ready", ref), helm_kube.ResourceList{info}, c.WaitTimeout)
+}
+
+// waitUntilAvailable waits until the resource exists (CRD should be established).
+func (c *OrderedKubeClient) waitUntilAvailable(ctx context.Context, ref *resourceRef) (*resource.Info, error) {
+	info, err := c.getAvailableResource(ref)
+	if err != nil || info != nil {
+		return info, err
+	}
+
+	timeout := c.WaitTimeout
+	if timeout == 0 {
+		timeout = defaultAvailableWaitTimeout
+	}
+
+	err = logboek.Context(ctx).Default().LogProcess("Waiting for %s to be available", ref).DoError(func() error {
+		timeoutCh := time.After(timeout)
+
+		for info == nil {
+			select {
+			case <-timeoutCh:
+				return fmt.Errorf("timed out waiting for %s to be available after %s: check the resource exists or werf.io/depends-on annotation", ref, timeout)
+			case <-time.After(dependencyPollPeriod):
+			}
+
+			if info, err = c.getAvailableResource(ref); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return info, err
+}
+
+// getAvailableResource returns nil if the resource does not exist or is not ready to be used yet.
+func (c *OrderedKubeClient) getAvailableResource(ref *resourceRef) (*resource.Info, error) {
+	infos, err := c.Factory.NewBuilder().
+		Unstructured().
+		NamespaceParam(ref.Namespace).DefaultNamespace().
+		ResourceTypeOrNameArgs(false, fmt.Sprintf("%s/%s", ref.Kind, ref.Name)).
+		Flatten().
+		Do().Infos()
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get %s: %s", ref, err)
+	}
+
+	if len(infos) == 0 {
+		return nil, nil
+	}
+
+	info := infos[0]
+	if info.Mapping.GroupVersionKind.Kind == "CustomResourceDefinition" && !isCustomResourceDefinitionEstablished(info) {
+		return nil, nil
+	}
+
+	return info, nil
+}
+
+func (c *OrderedKubeClient) defaultNamespace() string {
+	if c.Namespace != "" {
+		return c.Namespace
+	}
+
+	if ns, _, err := c.Factory.ToRawKubeConfigLoader().Namespace(); err == nil {
+		return ns
+	}
+
+	return v1.NamespaceDefault
+}
+
+func isSingleDeployStage(stages []*deployStage) bool {
+	return len(stages) == 0 || (len(stages) == 1 && len(stages[0].ExternalDependencies) == 0)
+}
+
+// isTrackedResource returns true for the kinds the ResourcesWaiter tracks, other resources are ready once applied.
+func isTrackedResource(info *resource.Info) bool {
+	switch info.Mapping.GroupVersionKind.Kind {
+	case "Deployment", "StatefulSet", "DaemonSet", "Job":
+		return true
+	}
+
+	return false
+}
+
+func isCustomResourceDefinitionEstablished(info *resource.Info) bool {
+	obj, ok := info.Object.(*unstructured.Unstructured)
+	if !ok {
+		return true
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, condition := range conditions {
+		if c, ok := condition.(map[string]interface{}); ok && c["type"] == "Established" && c["status"] == "True" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendResult(res, stageRes *helm_kube.Result) {
+	if stageRes == nil {
+		return
+	}
+
+	res.Created = append(res.Created, stageRes.Created...)
+	res.Updated = append(res.Updated, stageRes.Updated...)
+	res.Deleted = append(res.Deleted, stageRes.Deleted...)
+}
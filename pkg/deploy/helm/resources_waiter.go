@@ -54,12 +54,40 @@ func extractSpecReplicas(specReplicas *int32) int {
 }
 
 func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resources helm_kube.ResourceList, timeout time.Duration) error {
+	return waiter.waitUntilReady(ctx, "Waiting for release resources to become ready", resources, timeout)
+}
+
+func (waiter *ResourcesWaiter) waitUntilReady(ctx context.Context, processTitle string, resources helm_kube.ResourceList, timeout time.Duration) error {
 	if waiter.KubeInitializer != nil {
 		if err := waiter.KubeInitializer.Init(ctx); err != nil {
 			return fmt.Errorf("kube initializer failed: %s", err)
 		}
 	}
 
+	specs, err := makeMultitrackSpecs(ctx, resources)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: use context from resources-waiter object here, will be changed in helm 3
+	logboek.Context(ctx).LogOptionalLn()
+	return logboek.Context(ctx).LogProcess(processTitle).
+		DoError(func() error {
+			return waiter.track(specs, timeout)
+		})
+}
+
+func (waiter *ResourcesWaiter) track(specs multitrack.MultitrackSpecs, timeout time.Duration) error {
+	return multitrack.Multitrack(kube.Client, specs, multitrack.MultitrackOptions{
+		StatusProgressPeriod: waiter.StatusProgressPeriod,
+		Options: tracker.Options{
+			Timeout:      timeout,
+			LogsFromTime: waiter.LogsFromTime,
+		},
+	})
+}
+
+func makeMultitrackSpecs(ctx context.Context, resources helm_kube.ResourceList) (multitrack.MultitrackSpecs, error) {
 	specs := multitrack.MultitrackSpecs{}
 
 	for _, v := range resources {
@@ -67,7 +95,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *appsv1.Deployment:
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: extractSpecReplicas(value.Spec.Replicas), defaultPerReplica: 1}, "deploy")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.Deployments = append(specs.Deployments, *spec)
@@ -75,7 +103,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *appsv1beta1.Deployment:
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: extractSpecReplicas(value.Spec.Replicas), defaultPerReplica: 1}, "deploy")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.Deployments = append(specs.Deployments, *spec)
@@ -83,7 +111,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *appsv1beta2.Deployment:
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: extractSpecReplicas(value.Spec.Replicas), defaultPerReplica: 1}, "deploy")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.Deployments = append(specs.Deployments, *spec)
@@ -91,7 +119,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *extensions.Deployment:
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: extractSpecReplicas(value.Spec.Replicas), defaultPerReplica: 1}, "deploy")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.Deployments = append(specs.Deployments, *spec)
@@ -101,7 +129,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 			// TODO: It is better to fetch number of nodes dynamically, but in the most cases multiplier=3 will work ok.
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: 3, defaultPerReplica: 1}, "ds")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.DaemonSets = append(specs.DaemonSets, *spec)
@@ -111,7 +139,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 			// TODO: It is better to fetch number of nodes dynamically, but in the most cases multiplier=3 will work ok.
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: 3, defaultPerReplica: 1}, "ds")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.DaemonSets = append(specs.DaemonSets, *spec)
@@ -121,7 +149,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 			// TODO: It is better to fetch number of nodes dynamically, but in the most cases multiplier=3 will work ok.
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: 3, defaultPerReplica: 1}, "ds")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.DaemonSets = append(specs.DaemonSets, *spec)
@@ -129,7 +157,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *appsv1.StatefulSet:
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: extractSpecReplicas(value.Spec.Replicas), defaultPerReplica: 1}, "sts")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.StatefulSets = append(specs.StatefulSets, *spec)
@@ -137,7 +165,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *appsv1beta1.StatefulSet:
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: extractSpecReplicas(value.Spec.Replicas), defaultPerReplica: 1}, "sts")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.StatefulSets = append(specs.StatefulSets, *spec)
@@ -145,7 +173,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *appsv1beta2.StatefulSet:
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: extractSpecReplicas(value.Spec.Replicas), defaultPerReplica: 1}, "sts")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.StatefulSets = append(specs.StatefulSets, *spec)
@@ -153,7 +181,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *batchv1.Job:
 			spec, err := makeMultitrackSpec(ctx, &value.ObjectMeta, allowedFailuresCountOptions{multiplier: 1, defaultPerReplica: 0}, "job")
 			if err != nil {
-				return fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
+				return multitrack.MultitrackSpecs{}, fmt.Errorf("cannot track %s %s: %s", value.Kind, value.Name, err)
 			}
 			if spec != nil {
 				specs.Jobs = append(specs.Jobs, *spec)
@@ -167,18 +195,7 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		}
 	}
 
-	// NOTE: use context from resources-waiter object here, will be changed in helm 3
-	logboek.Context(ctx).LogOptionalLn()
-	return logboek.Context(ctx).LogProcess("Waiting for release resources to become ready").
-		DoError(func() error {
-			return multitrack.Multitrack(kube.Client, specs, multitrack.MultitrackOptions{
-				StatusProgressPeriod: waiter.StatusProgressPeriod,
-				Options: tracker.Options{
-					Timeout:      timeout,
-					LogsFromTime: waiter.LogsFromTime,
-				},
-			})
-		})
+	return specs, nil
 }
 
 func makeMultitrackSpec(ctx context.Context, objMeta *metav1.ObjectMeta, failuresCountOptions allowedFailuresCountOptions, kind string) (*multitrack.MultitrackSpec, error) {
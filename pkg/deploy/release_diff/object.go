@@ -0,0 +1,189 @@
+package release_diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"helm.sh/helm/v3/pkg/kube"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+)
+
+const maskedValue = "***"
+
+// Fields populated by the server which differ between the live and the predicted object and do not describe the changes
+var serverPopulatedFields = [][]string{
+	{"metadata", "creationTimestamp"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "selfLink"},
+	{"metadata", "uid"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"status"},
+}
+
+// predictObject returns the object the server would store after helm upgrade patches the live object.
+func predictObject(helper *resource.Helper, target *resource.Info, originalObj, liveObj runtime.Object) (runtime.Object, error) {
+	patch, patchType, err := createPatch(target, originalObj, liveObj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create patch for %s: %s", target.ObjectName(), err)
+	}
+
+	if patch == nil || string(patch) == "{}" {
+		return liveObj, nil
+	}
+
+	predictedObj, err := helper.DryRun(true).Patch(target.Namespace, target.Name, patchType, patch, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to dry-run patch for %s: %s", target.ObjectName(), err)
+	}
+
+	return predictedObj, nil
+}
+
+// createPatch creates the same patch as helm upgrade: three-way strategic merge patch for the built-in kinds
+// and generic json merge patch for the custom resources and CRDs.
+func createPatch(target *resource.Info, originalObj, liveObj runtime.Object) ([]byte, types.PatchType, error) {
+	oldData, err := json.Marshal(originalObj)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("serializing current configuration: %s", err)
+	}
+
+	newData, err := json.Marshal(target.Object)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("serializing target configuration: %s", err)
+	}
+
+	liveData, err := json.Marshal(liveObj)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("serializing live configuration: %s", err)
+	}
+
+	versionedObject := kube.AsVersioned(target)
+
+	_, isUnstructured := versionedObject.(runtime.Unstructured)
+	_, isCRD := versionedObject.(*apiextv1beta1.CustomResourceDefinition)
+
+	if isUnstructured || isCRD {
+		patch, err := jsonpatch.CreateMergePatch(oldData, newData)
+		return patch, types.MergePatchType, err
+	}
+
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(versionedObject)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("unable to create patch metadata from object: %s", err)
+	}
+
+	patch, err := strategicpatch.CreateThreeWayMergePatch(oldData, newData, liveData, patchMeta, true)
+	return patch, types.StrategicMergePatchType, err
+}
+
+// objectData converts the object to the map without the server populated fields, nil object is converted to nil map.
+func objectData(obj runtime.Object) (map[string]interface{}, error) {
+	if obj == nil {
+		return nil, nil
+	}
+
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	data = runtime.DeepCopyJSON(data)
+	for _, fields := range serverPopulatedFields {
+		unstructured.RemoveNestedField(data, fields...)
+	}
+
+	if annotations, found, _ := unstructured.NestedMap(data, "metadata", "annotations"); found && len(annotations) == 0 {
+		unstructured.RemoveNestedField(data, "metadata", "annotations")
+	}
+
+	return data, nil
+}
+
+func objectYaml(data map[string]interface{}) (string, error) {
+	if data == nil {
+		return "", nil
+	}
+
+	out, err := yaml.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal object: %s", err)
+	}
+
+	return string(out), nil
+}
+
+type masker struct {
+	secretValues []string
+}
+
+func newMasker(secretValues []string) *masker {
+	var values []string
+	for _, value := range secretValues {
+		if strings.TrimSpace(value) != "" {
+			values = append(values, value)
+		}
+	}
+
+	// Longer values first, so that a value containing another one is masked entirely
+	sort.SliceStable(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	return &masker{secretValues: values}
+}
+
+// MaskSecretData replaces the values of the Secret data with ***, changed values are marked with (before) and (after).
+func (m *masker) MaskSecretData(oldData, newData map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		oldValues, _, _ := unstructured.NestedMap(oldData, field)
+		newValues, _, _ := unstructured.NestedMap(newData, field)
+
+		maskedOldValues := map[string]interface{}{}
+		for key, oldValue := range oldValues {
+			newValue, exists := newValues[key]
+			if exists && fmt.Sprint(newValue) != fmt.Sprint(oldValue) {
+				maskedOldValues[key] = maskedValue + " (before)"
+			} else {
+				maskedOldValues[key] = maskedValue
+			}
+		}
+
+		maskedNewValues := map[string]interface{}{}
+		for key, newValue := range newValues {
+			oldValue, exists := oldValues[key]
+			if exists && fmt.Sprint(newValue) != fmt.Sprint(oldValue) {
+				maskedNewValues[key] = maskedValue + " (after)"
+			} else {
+				maskedNewValues[key] = maskedValue
+			}
+		}
+
+		if oldValues != nil {
+			_ = unstructured.SetNestedMap(oldData, maskedOldValues, field)
+		}
+
+		if newValues != nil {
+			_ = unstructured.SetNestedMap(newData, maskedNewValues, field)
+		}
+	}
+}
+
+// MaskValues replaces the secret values in the text with ***.
+func (m *masker) MaskValues(text string) string {
+	for _, value := range m.secretValues {
+		text = strings.ReplaceAll(text, value, maskedValue)
+	}
+
+	return text
+}
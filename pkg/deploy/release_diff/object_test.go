@@ -0,0 +1,70 @@
+package release_diff
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestObjectData(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":              "app",
+			"uid":               "6f5a4c3e",
+			"resourceVersion":   "10",
+			"creationTimestamp": "2021-01-01T00:00:00Z",
+			"managedFields":     []interface{}{map[string]interface{}{"manager": "helm"}},
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		"data":   map[string]interface{}{"key": "value"},
+		"status": map[string]interface{}{},
+	}}
+
+	data, err := objectData(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"data":       map[string]interface{}{"key": "value"},
+	}
+
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, data)
+	}
+
+	if _, found, _ := unstructured.NestedString(obj.Object, "metadata", "uid"); !found {
+		t.Errorf("Expected the source object not to be modified")
+	}
+}
+
+func TestMasker(t *testing.T) {
+	m := newMasker([]string{"password", "long-password", " "})
+
+	if masked := m.MaskValues("url: postgres://user:long-password@db\npass: password\n"); masked != "url: postgres://user:***@db\npass: ***\n" {
+		t.Errorf("Unexpected masked text: %q", masked)
+	}
+
+	oldData := map[string]interface{}{"data": map[string]interface{}{"same": "YQ==", "changed": "Yg==", "removed": "Yw=="}}
+	newData := map[string]interface{}{"data": map[string]interface{}{"same": "YQ==", "changed": "ZA==", "added": "ZQ=="}}
+	m.MaskSecretData(oldData, newData)
+
+	expectedOldData := map[string]interface{}{"data": map[string]interface{}{"same": "***", "changed": "*** (before)", "removed": "***"}}
+	expectedNewData := map[string]interface{}{"data": map[string]interface{}{"same": "***", "changed": "*** (after)", "added": "***"}}
+
+	if !reflect.DeepEqual(oldData, expectedOldData) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expectedOldData, oldData)
+	}
+
+	if !reflect.DeepEqual(newData, expectedNewData) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expectedNewData, newData)
+	}
+}
@@ -0,0 +1,277 @@
+package release_diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/werf/werf/pkg/util"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionPrune  Action = "prune"
+)
+
+var actionSigns = map[Action]string{
+	ActionCreate: "+",
+	ActionUpdate: "~",
+	ActionPrune:  "-",
+}
+
+var actionParticiples = map[Action]string{
+	ActionCreate: "created",
+	ActionUpdate: "updated",
+	ActionPrune:  "pruned",
+}
+
+type ResourceDiff struct {
+	Action    Action
+	Kind      string
+	Name      string
+	Namespace string
+	Diff      string
+}
+
+func (d ResourceDiff) ResourceID() string {
+	if d.Namespace == "" {
+		return fmt.Sprintf("%s/%s", d.Kind, d.Name)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", d.Namespace, d.Kind, d.Name)
+}
+
+type ReleaseDiff struct {
+	ReleaseName string
+	Resources   []ResourceDiff
+	Unchanged   int
+}
+
+type Options struct {
+	// SecretValuesToMask are replaced with *** in the diff, the data of Secret resources is always masked
+	SecretValuesToMask []string
+}
+
+// Calculate compares the target manifest of the release with the live objects in the cluster.
+// Changes of the existing objects are predicted by the server-side dry-run of the same patch helm upgrade sends,
+// so defaults and fields populated by the server and other controllers do not appear in the diff.
+// Objects of the last release missing in the target manifest will be pruned by the upgrade.
+func Calculate(actionConfig *action.Configuration, releaseName, targetManifest string, opts Options) (*ReleaseDiff, error) {
+	currentRelease, err := getCurrentRelease(actionConfig, releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	var current kube.ResourceList
+	if currentRelease != nil {
+		if current, err = actionConfig.KubeClient.Build(bytes.NewBufferString(currentRelease.Manifest), false); err != nil {
+			return nil, fmt.Errorf("unable to build kubernetes objects from current release manifest: %s", err)
+		}
+	}
+
+	target, err := actionConfig.KubeClient.Build(bytes.NewBufferString(targetManifest), false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build kubernetes objects from new release manifest: %s", err)
+	}
+
+	masker := newMasker(opts.SecretValuesToMask)
+	releaseDiff := &ReleaseDiff{ReleaseName: releaseName}
+
+	currentByKey := map[string]*resource.Info{}
+	for _, info := range current {
+		currentByKey[objectKey(info)] = info
+	}
+
+	targetKeys := map[string]bool{}
+	for _, info := range target {
+		targetKeys[objectKey(info)] = true
+
+		helper := resource.NewHelper(info.Client, info.Mapping)
+
+		liveObj, err := helper.Get(info.Namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			resourceDiff, err := newResourceDiff(ActionCreate, info, nil, info.Object, masker)
+			if err != nil {
+				return nil, err
+			}
+
+			releaseDiff.Resources = append(releaseDiff.Resources, resourceDiff)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to get %s: %s", info.ObjectName(), err)
+		}
+
+		originalObj := liveObj
+		if originalInfo, ok := currentByKey[objectKey(info)]; ok {
+			originalObj = originalInfo.Object
+		}
+
+		predictedObj, err := predictObject(helper, info, originalObj, liveObj)
+		if err != nil {
+			return nil, err
+		}
+
+		resourceDiff, err := newResourceDiff(ActionUpdate, info, liveObj, predictedObj, masker)
+		if err != nil {
+			return nil, err
+		}
+
+		if resourceDiff.Diff == "" {
+			releaseDiff.Unchanged++
+			continue
+		}
+
+		releaseDiff.Resources = append(releaseDiff.Resources, resourceDiff)
+	}
+
+	for _, info := range current {
+		if targetKeys[objectKey(info)] {
+			continue
+		}
+
+		liveObj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to get %s: %s", info.ObjectName(), err)
+		}
+
+		if isKeptOnDelete(liveObj) {
+			continue
+		}
+
+		resourceDiff, err := newResourceDiff(ActionPrune, info, liveObj, nil, masker)
+		if err != nil {
+			return nil, err
+		}
+
+		releaseDiff.Resources = append(releaseDiff.Resources, resourceDiff)
+	}
+
+	return releaseDiff, nil
+}
+
+// getCurrentRelease returns the release helm upgrade uses as the original one: the last release if deployed or the last deployed one.
+func getCurrentRelease(actionConfig *action.Configuration, releaseName string) (*release.Release, error) {
+	lastRelease, err := actionConfig.Releases.Last(releaseName)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get release %q: %s", releaseName, err)
+	}
+
+	if lastRelease.Info.Status == release.StatusDeployed {
+		return lastRelease, nil
+	}
+
+	deployedRelease, err := actionConfig.Releases.Deployed(releaseName)
+	if errors.Is(err, driver.ErrNoDeployedReleases) {
+		return lastRelease, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get deployed release %q: %s", releaseName, err)
+	}
+
+	return deployedRelease, nil
+}
+
+func objectKey(info *resource.Info) string {
+	gvk := info.Object.GetObjectKind().GroupVersionKind()
+	return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Kind, info.Namespace, info.Name)
+}
+
+func isKeptOnDelete(obj runtime.Object) bool {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return false
+	}
+
+	annotations, _, _ := unstructured.NestedStringMap(data, "metadata", "annotations")
+	return annotations[kube.ResourcePolicyAnno] == kube.KeepPolicy
+}
+
+func newResourceDiff(action Action, info *resource.Info, oldObj, newObj runtime.Object, masker *masker) (ResourceDiff, error) {
+	resourceDiff := ResourceDiff{
+		Action:    action,
+		Kind:      info.Object.GetObjectKind().GroupVersionKind().Kind,
+		Name:      info.Name,
+		Namespace: info.Namespace,
+	}
+
+	oldData, err := objectData(oldObj)
+	if err != nil {
+		return ResourceDiff{}, fmt.Errorf("unable to convert %s: %s", info.ObjectName(), err)
+	}
+
+	newData, err := objectData(newObj)
+	if err != nil {
+		return ResourceDiff{}, fmt.Errorf("unable to convert %s: %s", info.ObjectName(), err)
+	}
+
+	if resourceDiff.Kind == "Secret" {
+		masker.MaskSecretData(oldData, newData)
+	}
+
+	oldYaml, err := objectYaml(oldData)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+
+	newYaml, err := objectYaml(newData)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+
+	oldYaml, newYaml = masker.MaskValues(oldYaml), masker.MaskValues(newYaml)
+	if oldYaml == newYaml {
+		return resourceDiff, nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        util.SplitLines(oldYaml),
+		B:        util.SplitLines(newYaml),
+		FromFile: "live",
+		ToFile:   "target",
+		Context:  3,
+	})
+	if err != nil {
+		return ResourceDiff{}, fmt.Errorf("unable to diff %s: %s", info.ObjectName(), err)
+	}
+
+	resourceDiff.Diff = diff
+
+	return resourceDiff, nil
+}
+
+// Print writes the diff of every changed resource and the summary.
+func (d *ReleaseDiff) Print(w io.Writer) {
+	sort.SliceStable(d.Resources, func(i, j int) bool {
+		return d.Resources[i].ResourceID() < d.Resources[j].ResourceID()
+	})
+
+	counts := map[Action]int{}
+	for _, resourceDiff := range d.Resources {
+		counts[resourceDiff.Action]++
+
+		fmt.Fprintf(w, "%s %s will be %s\n", actionSigns[resourceDiff.Action], resourceDiff.ResourceID(), actionParticiples[resourceDiff.Action])
+		fmt.Fprintf(w, "%s\n", strings.TrimRight(resourceDiff.Diff, "\n"))
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Release %q: %d to create, %d to update, %d to prune, %d unchanged\n", d.ReleaseName, counts[ActionCreate], counts[ActionUpdate], counts[ActionPrune], d.Unchanged)
+}
@@ -0,0 +1,131 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/werf/werf/pkg/util"
+)
+
+type DiffAction string
+
+const (
+	DiffActionAdded   DiffAction = "+"
+	DiffActionRemoved DiffAction = "-"
+	DiffActionChanged DiffAction = "~"
+)
+
+// DiffEntry describes the change of the secret value (Key is the dot-separated path of the value)
+// or the secret file (Key is the path relative to the secret directory).
+type DiffEntry struct {
+	Action   DiffAction
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+// DiffValues returns the key-level diff of the decoded secret values sorted by key.
+func DiffValues(oldValues, newValues map[string]interface{}) []DiffEntry {
+	return diffFlatValues(flattenValues(oldValues), flattenValues(newValues))
+}
+
+// DiffFilesData returns the diff of the decoded secret files data sorted by file path.
+func DiffFilesData(oldFilesData, newFilesData map[string]string) []DiffEntry {
+	return diffFlatValues(oldFilesData, newFilesData)
+}
+
+// FormatDiffEntry returns the human readable description of the change, values are replaced with *** when maskValues is set.
+// Values are printed in one line, multiline values (e.g. secret files data) are printed as a unified diff.
+func FormatDiffEntry(entry DiffEntry, maskValues bool) string {
+	header := fmt.Sprintf("%s %s", entry.Action, entry.Key)
+	if maskValues {
+		return header
+	}
+
+	if strings.Contains(entry.OldValue, "\n") || strings.Contains(entry.NewValue, "\n") {
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:       util.SplitLines(entry.OldValue),
+			B:       util.SplitLines(entry.NewValue),
+			Context: 3,
+		})
+
+		return fmt.Sprintf("%s\n%s", header, strings.TrimRight(diff, "\n"))
+	}
+
+	switch entry.Action {
+	case DiffActionAdded:
+		return fmt.Sprintf("%s: %s", header, entry.NewValue)
+	case DiffActionRemoved:
+		return fmt.Sprintf("%s: %s", header, entry.OldValue)
+	default:
+		return fmt.Sprintf("%s: %s -> %s", header, entry.OldValue, entry.NewValue)
+	}
+}
+
+func diffFlatValues(oldValues, newValues map[string]string) []DiffEntry {
+	var result []DiffEntry
+
+	for key, oldValue := range oldValues {
+		if newValue, exists := newValues[key]; !exists {
+			result = append(result, DiffEntry{Action: DiffActionRemoved, Key: key, OldValue: oldValue})
+		} else if newValue != oldValue {
+			result = append(result, DiffEntry{Action: DiffActionChanged, Key: key, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	for key, newValue := range newValues {
+		if _, exists := oldValues[key]; !exists {
+			result = append(result, DiffEntry{Action: DiffActionAdded, Key: key, NewValue: newValue})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
+
+func flattenValues(values map[string]interface{}) map[string]string {
+	result := map[string]string{}
+	flattenValue("", values, result)
+	return result
+}
+
+func flattenValue(key string, value interface{}, result map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && key != "" {
+			result[key] = "{}"
+		}
+
+		for k, vv := range v {
+			if key == "" {
+				flattenValue(k, vv, result)
+			} else {
+				flattenValue(key+"."+k, vv, result)
+			}
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			result[key] = "[]"
+		}
+
+		for ind, vv := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", key, ind), vv, result)
+		}
+	case string:
+		result[key] = v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			result[key] = fmt.Sprintf("%v", v)
+		} else {
+			result[key] = string(data)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package secret
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffValues(t *testing.T) {
+	oldValues := map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": "old",
+			"user":     "admin",
+			"port":     float64(5432),
+		},
+		"token": "token",
+		"hosts": []interface{}{"a", "b"},
+	}
+
+	newValues := map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": "new",
+			"user":     "admin",
+			"port":     float64(5433),
+		},
+		"hosts":  []interface{}{"a"},
+		"apiKey": "key",
+	}
+
+	expected := []DiffEntry{
+		{Action: DiffActionAdded, Key: "apiKey", NewValue: "key"},
+		{Action: DiffActionChanged, Key: "db.password", OldValue: "old", NewValue: "new"},
+		{Action: DiffActionChanged, Key: "db.port", OldValue: "5432", NewValue: "5433"},
+		{Action: DiffActionRemoved, Key: "hosts[1]", OldValue: "b"},
+		{Action: DiffActionRemoved, Key: "token", OldValue: "token"},
+	}
+
+	if result := DiffValues(oldValues, newValues); !reflect.DeepEqual(result, expected) {
+		t.Errorf("\n[EXPECTED]: %+v\n[GOT]: %+v", expected, result)
+	}
+
+	if result := DiffValues(newValues, newValues); len(result) != 0 {
+		t.Errorf("Expected no changes for identical values, got: %+v", result)
+	}
+}
+
+func TestFormatDiffEntry(t *testing.T) {
+	entry := DiffEntry{Action: DiffActionChanged, Key: "db.password", OldValue: "old", NewValue: "new"}
+
+	if result := FormatDiffEntry(entry, false); result != "~ db.password: old -> new" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "~ db.password: old -> new", result)
+	}
+
+	if result := FormatDiffEntry(entry, true); result != "~ db.password" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "~ db.password", result)
+	}
+
+	fileEntry := DiffEntry{Action: DiffActionChanged, Key: "config.ini", OldValue: "a=1\nb=2\n", NewValue: "a=1\nb=3\n"}
+	expected := "~ config.ini\n@@ -1,2 +1,2 @@\n a=1\n-b=2\n+b=3"
+	if result := FormatDiffEntry(fileEntry, false); result != expected {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expected, result)
+	}
+}
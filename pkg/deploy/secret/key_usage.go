@@ -0,0 +1,69 @@
+package secret
+
+import (
+	"sort"
+
+	"github.com/werf/werf/pkg/secret"
+)
+
+type KeyUsageChecker struct {
+	ring *secret.KeyRingSecret
+}
+
+func NewKeyUsageChecker(keys [][]byte) (*KeyUsageChecker, error) {
+	ring, err := secret.NewKeyRingSecret(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyUsageChecker{ring: ring}, nil
+}
+
+// CheckData returns the indexes of the key ring keys that secret file data is encrypted with.
+func (c *KeyUsageChecker) CheckData(data []byte) ([]int, error) {
+	usedKeys := map[int]bool{}
+	if err := c.checkValue(data, usedKeys); err != nil {
+		return nil, err
+	}
+
+	return sortedKeyIndexes(usedKeys), nil
+}
+
+// CheckYamlData returns the indexes of the key ring keys that secret values of the yaml data are encrypted with.
+func (c *KeyUsageChecker) CheckYamlData(data []byte) ([]int, error) {
+	usedKeys := map[int]bool{}
+	if _, err := doYamlData(func(value []byte) ([]byte, error) {
+		return value, c.checkValue(value, usedKeys)
+	}, data); err != nil {
+		return nil, err
+	}
+
+	return sortedKeyIndexes(usedKeys), nil
+}
+
+func (c *KeyUsageChecker) checkValue(data []byte, usedKeys map[int]bool) error {
+	// data encrypted with age or with the environment key is not covered by the key ring
+	if len(data) == 0 || secret.IsAgeEncryptedData(data) || secret.GetEncryptedDataKeyName(data) != "" {
+		return nil
+	}
+
+	_, keyIndex, err := c.ring.DecryptWithKeyIndex(data)
+	if err != nil {
+		return err
+	}
+
+	usedKeys[keyIndex] = true
+
+	return nil
+}
+
+func sortedKeyIndexes(usedKeys map[int]bool) []int {
+	var result []int
+	for keyIndex := range usedKeys {
+		result = append(result, keyIndex)
+	}
+
+	sort.Ints(result)
+
+	return result
+}
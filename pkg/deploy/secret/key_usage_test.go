@@ -0,0 +1,61 @@
+package secret
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/werf/werf/pkg/secret"
+)
+
+func TestKeyUsageChecker(t *testing.T) {
+	keys := [][]byte{[]byte("a5b3f0e7d9c1b2a4f6e8d0c2b4a6f8e0"), []byte("11ac8312520b5ff037bae386ea2e8a07")}
+
+	checker, err := NewKeyUsageChecker(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypt := func(s secret.Secret, value string) string {
+		data, err := s.Encrypt([]byte(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	newSecret, err := secret.NewVersionedSecret(keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSecret.EncryptWithCurrent = true
+
+	oldSecret, err := secret.NewVersionedSecret(keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSecret.EncryptWithCurrent = true
+
+	usedKeys, err := checker.CheckYamlData([]byte(fmt.Sprintf("new: %s\nold: %s\n", encrypt(newSecret, "new"), encrypt(oldSecret, "old"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []int{0, 1}; !reflect.DeepEqual(usedKeys, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, usedKeys)
+	}
+
+	legacySecret, err := secret.NewAesSecret(keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usedKeys, err = checker.CheckData([]byte(encrypt(legacySecret, "legacy")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []int{1}; !reflect.DeepEqual(usedKeys, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, usedKeys)
+	}
+}
@@ -5,7 +5,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+
+	"filippo.io/age"
+
+	"github.com/werf/logboek"
 
 	"github.com/werf/werf/pkg/secret"
 	"github.com/werf/werf/pkg/util"
@@ -23,74 +29,242 @@ func GenerateSecretKey() ([]byte, error) {
 	return secret.GenerateAexSecretKey()
 }
 
+const SecretRecipientsFileName = ".werf_secret_recipients"
+
+var envNameUnsupportedCharsRegexp = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
 func GetManager(projectDir string) (Manager, error) {
-	var m Manager
-	var key []byte
-	var err error
+	ss, err := getSecret(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBaseManager(ss)
+}
+
+// GetEnvManager returns the manager that encrypts data with the environment key when the key is defined
+// and decrypts data with the key that the data is tagged with, so that the common secrets and the secrets of the specified environment can be used together.
+func GetEnvManager(projectDir, env string) (Manager, error) {
+	if env == "" {
+		return GetManager(projectDir)
+	}
+
+	envKey, envKeyErr := GetEnvSecretKey(projectDir, env)
+
+	commonSecret, err := getSecret(projectDir)
+	if err != nil {
+		if envKeyErr != nil {
+			return nil, err
+		}
+
+		commonSecret = &unavailableSecret{encryptErr: err, decryptErr: err}
+	}
+
+	var envSecret secret.Secret
+	if envKeyErr != nil {
+		envSecret = &unavailableSecret{encryptErr: envKeyErr, decryptErr: envKeyErr}
+	} else if envSecret, err = secret.NewNamedVersionedSecret(envKey, env); err != nil {
+		return nil, fmt.Errorf("check %q environment encryption key: %s", env, err)
+	}
+
+	return newBaseManager(&secret.EnvSecret{
+		Common:         commonSecret,
+		Env:            envSecret,
+		EnvName:        env,
+		EncryptWithEnv: envKeyErr == nil,
+	})
+}
+
+func newSymmetricSecret(keys [][]byte) (secret.Secret, error) {
+	if len(keys) == 1 {
+		return secret.NewSecret(keys[0])
+	}
 
-	key, err = GetSecretKey(projectDir)
+	ring, err := secret.NewKeyRingSecret(keys)
 	if err != nil {
 		return nil, err
 	}
 
-	m, err = NewManager(key)
+	return &keyRingSecret{KeyRingSecret: ring, keys: keys, reportedKeys: map[int]bool{}}, nil
+}
+
+func getSecret(projectDir string) (secret.Secret, error) {
+	recipients, err := GetSecretRecipients(projectDir)
 	if err != nil {
 		return nil, err
 	}
 
-	return m, nil
+	var symmetricSecret secret.Secret
+	if keys, err := GetSecretKeys(projectDir); err != nil {
+		// the key is only required when there is no age recipients to encrypt data to
+		if len(recipients) == 0 {
+			return nil, err
+		}
+
+		symmetricSecret = &unavailableSecret{encryptErr: err, decryptErr: err}
+	} else if symmetricSecret, err = newSymmetricSecret(keys); err != nil {
+		return nil, fmt.Errorf("check encryption key: %s", err)
+	}
+
+	var ageSecret secret.Secret
+	if identitiesData, err := GetSecretIdentities(projectDir); err != nil {
+		ageSecret = &unavailableSecret{Secret: secret.NewAgeSecret(recipients, nil), decryptErr: err}
+	} else if identities, err := secret.ParseAgeIdentities(identitiesData); err != nil {
+		return nil, fmt.Errorf("check secret identity: %s", err)
+	} else {
+		ageSecret = secret.NewAgeSecret(recipients, identities)
+	}
+
+	return &secret.MultiBackendSecret{
+		Symmetric:      symmetricSecret,
+		Age:            ageSecret,
+		EncryptWithAge: len(recipients) != 0,
+	}, nil
+}
+
+// GetSecretRecipients returns age recipients from the project .werf_secret_recipients file or nil if there is no such file.
+func GetSecretRecipients(projectDir string) ([]age.Recipient, error) {
+	if projectDir == "" {
+		return nil, nil
+	}
+
+	recipientsPath := filepath.Join(projectDir, SecretRecipientsFileName)
+	exist, err := util.RegularFileExists(recipientsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exist {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(recipientsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients, err := secret.ParseAgeRecipients(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", recipientsPath, err)
+	}
+
+	return recipients, nil
+}
+
+// GetSecretKeys returns the key ring from $WERF_SECRET_KEYS (comma separated keys, the first key is used to encrypt data)
+// or the single key from $WERF_SECRET_KEY, .werf_secret_key or ~/.werf/global_secret_key.
+func GetSecretKeys(projectDir string) ([][]byte, error) {
+	if value := os.Getenv("WERF_SECRET_KEYS"); value != "" {
+		var keys [][]byte
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, []byte(key))
+			}
+		}
+
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no keys specified in $WERF_SECRET_KEYS")
+		}
+
+		return keys, nil
+	}
+
+	key, err := GetSecretKey(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{key}, nil
 }
 
 func GetSecretKey(projectDir string) ([]byte, error) {
-	var secretKey []byte
-	var werfSecretKeyPaths []string
+	secretKey, err := lookupSecretData(projectDir, "WERF_SECRET_KEY", ".werf_secret_key", "global_secret_key")
+	if err != nil {
+		return nil, fmt.Errorf("encryption key not found in: %s", err)
+	}
+
+	return secretKey, nil
+}
+
+// GetEnvSecretKey returns the key of the specified environment from $WERF_SECRET_KEY_<ENV>, .werf_secret_key.<env> or ~/.werf/global_secret_key.<env>.
+func GetEnvSecretKey(projectDir, env string) ([]byte, error) {
+	secretKey, err := lookupSecretData(projectDir, EnvSecretKeyEnvName(env), ".werf_secret_key."+env, "global_secret_key."+env)
+	if err != nil {
+		return nil, fmt.Errorf("%q environment encryption key not found in: %s", env, err)
+	}
+
+	return secretKey, nil
+}
+
+// GetEncryptedDataEnv returns the environment that the data is encrypted for or an empty string for data encrypted with the common key.
+func GetEncryptedDataEnv(data []byte) string {
+	return secret.GetEncryptedDataKeyName(data)
+}
+
+// EnvSecretKeyEnvName returns the name of the environment variable with the key of the specified environment, e.g. WERF_SECRET_KEY_PRODUCTION.
+func EnvSecretKeyEnvName(env string) string {
+	return "WERF_SECRET_KEY_" + strings.ToUpper(envNameUnsupportedCharsRegexp.ReplaceAllString(env, "_"))
+}
+
+func GetSecretIdentities(projectDir string) ([]byte, error) {
+	identities, err := lookupSecretData(projectDir, "WERF_SECRET_IDENTITY", ".werf_secret_identity", "global_secret_identity")
+	if err != nil {
+		return nil, fmt.Errorf("secret identity not found in: %s", err)
+	}
+
+	return identities, nil
+}
+
+func lookupSecretData(projectDir, envName, projectFileName, globalFileName string) ([]byte, error) {
+	var secretData []byte
+	var secretDataPaths []string
 	var notFoundIn []string
 
-	secretKey = []byte(os.Getenv("WERF_SECRET_KEY"))
-	if len(secretKey) == 0 {
-		notFoundIn = append(notFoundIn, "$WERF_SECRET_KEY")
+	secretData = []byte(os.Getenv(envName))
+	if len(secretData) == 0 {
+		notFoundIn = append(notFoundIn, "$"+envName)
 
-		var werfSecretKeyPath string
+		var secretDataPath string
 
 		if projectDir != "" {
-			if projectWerfSecretKeyPath, err := filepath.Abs(filepath.Join(projectDir, ".werf_secret_key")); err != nil {
+			if projectSecretDataPath, err := filepath.Abs(filepath.Join(projectDir, projectFileName)); err != nil {
 				return nil, err
 			} else {
-				werfSecretKeyPaths = append(werfSecretKeyPaths, projectWerfSecretKeyPath)
+				secretDataPaths = append(secretDataPaths, projectSecretDataPath)
 			}
 		}
 
-		werfSecretKeyPaths = append(werfSecretKeyPaths, filepath.Join(werf.GetHomeDir(), "global_secret_key"))
+		secretDataPaths = append(secretDataPaths, filepath.Join(werf.GetHomeDir(), globalFileName))
 
-		for _, path := range werfSecretKeyPaths {
+		for _, path := range secretDataPaths {
 			exist, err := util.FileExists(path)
 			if err != nil {
 				return nil, err
 			}
 
 			if exist {
-				werfSecretKeyPath = path
+				secretDataPath = path
 				break
 			} else {
 				notFoundIn = append(notFoundIn, path)
 			}
 		}
 
-		if werfSecretKeyPath != "" {
-			data, err := ioutil.ReadFile(werfSecretKeyPath)
+		if secretDataPath != "" {
+			data, err := ioutil.ReadFile(secretDataPath)
 			if err != nil {
 				return nil, err
 			}
 
-			secretKey = []byte(strings.TrimSpace(string(data)))
+			secretData = []byte(strings.TrimSpace(string(data)))
 		}
 	}
 
-	if len(secretKey) == 0 {
-		return nil, fmt.Errorf("encryption key not found in: '%s'", strings.Join(notFoundIn, "', '"))
+	if len(secretData) == 0 {
+		return nil, fmt.Errorf("'%s'", strings.Join(notFoundIn, "', '"))
 	}
 
-	return secretKey, nil
+	return secretData, nil
 }
 
 func NewManager(key []byte) (Manager, error) {
@@ -105,3 +279,54 @@ func NewManager(key []byte) (Manager, error) {
 func NewSafeManager() (Manager, error) {
 	return newBaseManager(nil)
 }
+
+// unavailableSecret reports the lookup error of the missing key only when the key is actually needed.
+type unavailableSecret struct {
+	secret.Secret
+	encryptErr error
+	decryptErr error
+}
+
+func (s *unavailableSecret) Encrypt(data []byte) ([]byte, error) {
+	if s.encryptErr != nil {
+		return nil, s.encryptErr
+	}
+
+	return s.Secret.Encrypt(data)
+}
+
+func (s *unavailableSecret) Decrypt(data []byte) ([]byte, error) {
+	if s.decryptErr != nil {
+		return nil, s.decryptErr
+	}
+
+	return s.Secret.Decrypt(data)
+}
+
+// keyRingSecret warns once about every old key of the ring that is still used to decrypt data.
+type keyRingSecret struct {
+	*secret.KeyRingSecret
+	keys [][]byte
+
+	reportedKeys      map[int]bool
+	reportedKeysMutex sync.Mutex
+}
+
+func (s *keyRingSecret) Decrypt(data []byte) ([]byte, error) {
+	result, keyIndex, err := s.DecryptWithKeyIndex(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyIndex > 0 {
+		s.reportedKeysMutex.Lock()
+		defer s.reportedKeysMutex.Unlock()
+
+		if !s.reportedKeys[keyIndex] {
+			s.reportedKeys[keyIndex] = true
+			logboek.Warn().LogF("WARNING: Secrets encrypted with the old key #%d (%s) from $WERF_SECRET_KEYS are used, re-encrypt them with the first key (werf helm secret rotate-secret-key)\n", keyIndex+1, secret.KeyFingerprint(s.keys[keyIndex]))
+		}
+	}
+
+	return result, nil
+}
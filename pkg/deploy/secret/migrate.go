@@ -0,0 +1,62 @@
+package secret
+
+import (
+	"fmt"
+
+	"github.com/werf/werf/pkg/secret"
+)
+
+type Migrator struct {
+	ring *secret.KeyRingSecret
+}
+
+// NewMigrator creates Migrator that decrypts the legacy data with the last key of the ring and encrypts it with the first key.
+func NewMigrator(keys [][]byte) (*Migrator, error) {
+	ring, err := secret.NewKeyRingSecret(keys)
+	if err != nil {
+		return nil, fmt.Errorf("check encryption key: %s", err)
+	}
+
+	return &Migrator{ring: ring}, nil
+}
+
+// MigrateData re-encrypts secret file data with the current encryption format and reports whether the data has been changed.
+func (m *Migrator) MigrateData(data []byte) ([]byte, bool, error) {
+	resultData, changed, err := m.migrateValue(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("migration failed: check encryption key and data: %s", err)
+	}
+
+	return resultData, changed, nil
+}
+
+// MigrateYamlData re-encrypts every secret value of the yaml data with the current encryption format and reports whether any value has been changed.
+func (m *Migrator) MigrateYamlData(data []byte) ([]byte, bool, error) {
+	var changed bool
+	resultData, err := doYamlData(func(value []byte) ([]byte, error) {
+		resultValue, valueChanged, err := m.migrateValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		changed = changed || valueChanged
+
+		return resultValue, nil
+	}, data)
+	if err != nil {
+		return nil, false, fmt.Errorf("migration failed: check encryption key and data: %s", err)
+	}
+
+	return resultData, changed, nil
+}
+
+func (m *Migrator) migrateValue(data []byte) ([]byte, bool, error) {
+	// the data already encrypted with the current format checks that the keys are right, data of the environments is encrypted with other keys
+	if secret.IsAesGcmEncryptedData(data) && secret.GetEncryptedDataKeyName(data) == "" {
+		if _, err := m.ring.Decrypt(data); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return m.ring.Migrate(data)
+}
@@ -0,0 +1,72 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/werf/werf/pkg/secret"
+)
+
+func TestMigrator(t *testing.T) {
+	newKey := []byte("a5b3f0e7d9c1b2a4f6e8d0c2b4a6f8e0")
+	oldKey := []byte("11ac8312520b5ff037bae386ea2e8a07")
+
+	migrator, err := NewMigrator([][]byte{newKey, oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacySecret, err := secret.NewAesSecret(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyData, err := legacySecret.Encrypt([]byte("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migratedData, changed, err := migrator.MigrateData(legacyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !changed || !secret.IsAesGcmEncryptedData(migratedData) {
+		t.Fatalf("Expected legacy data to be migrated, got '%s'", migratedData)
+	}
+
+	newSecret, err := secret.NewAesGcmSecret(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := newSecret.Decrypt(migratedData); err != nil {
+		t.Fatal(err)
+	} else if string(result) != "legacy" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "legacy", result)
+	}
+
+	if _, changed, err := migrator.MigrateData(migratedData); err != nil || changed {
+		t.Errorf("Expected migrated data to be left as is, got: changed %v, %v", changed, err)
+	}
+}
+
+func TestMigrator_wrongKey(t *testing.T) {
+	strangerSecret, err := secret.NewAesGcmSecret([]byte("a5b3f0e7d9c1b2a4f6e8d0c2b4a6f8e0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strangerData, err := strangerSecret.Encrypt([]byte("stranger"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator([][]byte{[]byte("11ac8312520b5ff037bae386ea2e8a07")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := migrator.MigrateYamlData([]byte("value: " + string(strangerData) + "\n")); err == nil {
+		t.Errorf("Expected error for data encrypted with another key")
+	}
+}
@@ -14,11 +14,17 @@ import (
 	"github.com/werf/werf/pkg/util"
 )
 
-func GetSafeSecretManager(ctx context.Context, projectDir, helmChartDir string, secretValues []string, localGitRepo *git_repo.Local, ignoreSecretKey bool) (secret.Manager, error) {
+func GetSafeSecretManager(ctx context.Context, projectDir, helmChartDir, env string, secretValues []string, localGitRepo *git_repo.Local, ignoreSecretKey bool) (secret.Manager, error) {
 	isSecretsExists := false
 
 	secretDirPath := filepath.Join(helmChartDir, werf_chart.SecretDirName)
 	defaultSecretValuesFilePath := filepath.Join(helmChartDir, werf_chart.DefaultSecretValuesFileName)
+
+	secretValuesFilePaths := []string{defaultSecretValuesFilePath}
+	if env != "" {
+		secretValuesFilePaths = append(secretValuesFilePaths, filepath.Join(helmChartDir, werf_chart.EnvSecretValuesFileName(env)))
+	}
+
 	if giterminism_inspector.LooseGiterminism || localGitRepo == nil {
 		if exists, err := util.DirExists(secretDirPath); err != nil {
 			return nil, fmt.Errorf("unable to check directory %s existence: %s", secretDirPath, err)
@@ -26,10 +32,12 @@ func GetSafeSecretManager(ctx context.Context, projectDir, helmChartDir string,
 			isSecretsExists = true
 		}
 
-		if exists, err := util.RegularFileExists(defaultSecretValuesFilePath); err != nil {
-			return nil, fmt.Errorf("unable to check file %s existence: %s", defaultSecretValuesFilePath, err)
-		} else if exists {
-			isSecretsExists = true
+		for _, secretValuesFilePath := range secretValuesFilePaths {
+			if exists, err := util.RegularFileExists(secretValuesFilePath); err != nil {
+				return nil, fmt.Errorf("unable to check file %s existence: %s", secretValuesFilePath, err)
+			} else if exists {
+				isSecretsExists = true
+			}
 		}
 	} else {
 		commit, err := localGitRepo.HeadCommit(ctx)
@@ -43,10 +51,12 @@ func GetSafeSecretManager(ctx context.Context, projectDir, helmChartDir string,
 			isSecretsExists = true
 		}
 
-		if exists, err := localGitRepo.IsCommitFileExists(ctx, commit, defaultSecretValuesFilePath); err != nil {
-			return nil, fmt.Errorf("error checking existence of the file %q in the local git repo commit %s: %s", defaultSecretValuesFilePath, commit, err)
-		} else if exists {
-			isSecretsExists = true
+		for _, secretValuesFilePath := range secretValuesFilePaths {
+			if exists, err := localGitRepo.IsCommitFileExists(ctx, commit, secretValuesFilePath); err != nil {
+				return nil, fmt.Errorf("error checking existence of the file %q in the local git repo commit %s: %s", secretValuesFilePath, commit, err)
+			} else if exists {
+				isSecretsExists = true
+			}
 		}
 	}
 
@@ -60,12 +70,7 @@ func GetSafeSecretManager(ctx context.Context, projectDir, helmChartDir string,
 			return secret.NewSafeManager()
 		}
 
-		key, err := secret.GetSecretKey(projectDir)
-		if err != nil {
-			return nil, err
-		}
-
-		return secret.NewManager(key)
+		return secret.GetEnvManager(projectDir, env)
 	} else {
 		return secret.NewSafeManager()
 	}
@@ -0,0 +1,42 @@
+package secret_provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const envNamePrefix = "WERF_SECRET_VALUE_"
+
+var envNameForbiddenCharsRegexp = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// EnvProvider reads values from the environment variables, the variable name is composed of the path and the key,
+// e.g. secret/data/myapp#password is read from $WERF_SECRET_VALUE_SECRET_DATA_MYAPP_PASSWORD.
+type EnvProvider struct{}
+
+func NewEnvProvider() *EnvProvider {
+	return &EnvProvider{}
+}
+
+func (p *EnvProvider) Name() string {
+	return EnvProviderName
+}
+
+func (p *EnvProvider) GetValue(_ context.Context, path, key string) (string, error) {
+	envName := EnvName(path, key)
+
+	value, ok := os.LookupEnv(envName)
+	if !ok {
+		return "", fmt.Errorf("env variable %s is not set", envName)
+	}
+
+	return value, nil
+}
+
+// EnvName returns the name of the environment variable the value of the PATH#KEY reference is read from by EnvProvider.
+func EnvName(path, key string) string {
+	name := envNameForbiddenCharsRegexp.ReplaceAllString(strings.ToUpper(path+"_"+key), "_")
+	return envNamePrefix + strings.Trim(name, "_")
+}
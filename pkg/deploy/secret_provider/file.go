@@ -0,0 +1,79 @@
+package secret_provider
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/werf/pkg/deploy/secret"
+)
+
+// FileProvider reads values from the local secret values file encrypted with the werf secret key,
+// the file maps secret paths to the secret data and allows to use the chart without Vault (e.g. for local development):
+//
+//	secret/data/myapp:
+//	  password: <encrypted value>
+type FileProvider struct {
+	FilePath       string
+	SecretsManager secret.Manager
+
+	secrets     map[string]interface{}
+	secretsErr  error
+	secretsOnce sync.Once
+}
+
+func NewFileProvider(filePath string, secretsManager secret.Manager) (*FileProvider, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("secret values file path is not specified")
+	}
+
+	return &FileProvider{FilePath: filePath, SecretsManager: secretsManager}, nil
+}
+
+func (p *FileProvider) Name() string {
+	return FileProviderName
+}
+
+func (p *FileProvider) GetValue(_ context.Context, path, key string) (string, error) {
+	p.secretsOnce.Do(func() {
+		p.secrets, p.secretsErr = p.readSecrets()
+	})
+
+	if p.secretsErr != nil {
+		return "", p.secretsErr
+	}
+
+	data, ok := p.secrets[path].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("secret %q not found in the file %q", path, p.FilePath)
+	}
+
+	value, ok := lookupKey(data, key)
+	if !ok {
+		return "", fmt.Errorf("key %q not found in the secret %q of the file %q", key, path, p.FilePath)
+	}
+
+	return value, nil
+}
+
+func (p *FileProvider) readSecrets() (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(p.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read secret values file %q: %s", p.FilePath, err)
+	}
+
+	decodedData, err := p.SecretsManager.DecryptYamlData(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode secret values file %q: %s", p.FilePath, err)
+	}
+
+	secrets := map[string]interface{}{}
+	if err := yaml.Unmarshal(decodedData, &secrets); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal secret values file %q: %s", p.FilePath, err)
+	}
+
+	return secrets, nil
+}
@@ -0,0 +1,66 @@
+package secret_provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	VaultProviderName = "vault"
+	FileProviderName  = "file"
+	EnvProviderName   = "env"
+)
+
+// Provider resolves secret values referenced in chart templates with the werf_secret_value function.
+// The same PATH#KEY reference is resolved by any provider, so that the chart does not depend on the provider used.
+type Provider interface {
+	Name() string
+	GetValue(ctx context.Context, path, key string) (string, error)
+}
+
+// ParseReference splits the secret value reference PATH#KEY into the path and the key.
+func ParseReference(reference string) (string, string, error) {
+	ind := strings.LastIndex(reference, "#")
+	if ind <= 0 || ind == len(reference)-1 {
+		return "", "", fmt.Errorf("bad secret value reference %q: expected PATH#KEY", reference)
+	}
+
+	return reference[:ind], reference[ind+1:], nil
+}
+
+// lookupKey returns the value of the key or of the dot-separated nested key.
+func lookupKey(data map[string]interface{}, key string) (string, bool) {
+	if value, ok := data[key]; ok {
+		return valueToString(value), true
+	}
+
+	parts := strings.Split(key, ".")
+	var value interface{} = data
+	for _, part := range parts {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+
+		if value, ok = m[part]; !ok {
+			return "", false
+		}
+	}
+
+	return valueToString(value), true
+}
+
+func valueToString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,156 @@
+package secret_provider
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/werf/werf/pkg/deploy/secret"
+)
+
+func TestParseReference(t *testing.T) {
+	path, key, err := ParseReference("secret/data/myapp#db.password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "secret/data/myapp" || key != "db.password" {
+		t.Errorf("\n[EXPECTED]: secret/data/myapp, db.password\n[GOT]: %s, %s", path, key)
+	}
+
+	for _, reference := range []string{"secret/data/myapp", "#password", "secret/data/myapp#"} {
+		if _, _, err := ParseReference(reference); err == nil {
+			t.Errorf("Expected error for reference %q", reference)
+		}
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/myapp":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"v2","db":{"port":5432}},"metadata":{"version":1}}}`))
+		case "/v1/kv/myapp":
+			_, _ = w.Write([]byte(`{"data":{"password":"v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewVaultProvider(server.URL, "token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		path, key, expected string
+	}{
+		{path: "secret/data/myapp", key: "password", expected: "v2"},
+		{path: "secret/data/myapp", key: "db.port", expected: "5432"},
+		{path: "kv/myapp", key: "password", expected: "v1"},
+	} {
+		value, err := p.GetValue(context.Background(), tt.path, tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if value != tt.expected {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", tt.expected, value)
+		}
+	}
+
+	if _, err := p.GetValue(context.Background(), "secret/data/myapp", "user"); err == nil {
+		t.Errorf("Expected error for not existing key")
+	}
+
+	if _, err := p.GetValue(context.Background(), "secret/data/other", "password"); err == nil {
+		t.Errorf("Expected error for not existing secret")
+	}
+
+	forbiddenProvider, err := NewVaultProvider(server.URL, "bad-token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := forbiddenProvider.GetValue(context.Background(), "secret/data/myapp", "password"); err == nil {
+		t.Errorf("Expected error for bad token")
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	m, err := secret.NewManager([]byte("11ac8312520b5ff037bae386ea2e8a07"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedData, err := m.EncryptYamlData([]byte("secret/data/myapp:\n  password: file\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "werf-secret-provider-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "secret-values.yaml")
+	if err := ioutil.WriteFile(filePath, encryptedData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewFileProvider(filePath, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := p.GetValue(context.Background(), "secret/data/myapp", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value != "file" {
+		t.Errorf("\n[EXPECTED]: file\n[GOT]: %s", value)
+	}
+
+	if _, err := p.GetValue(context.Background(), "secret/data/other", "password"); err == nil {
+		t.Errorf("Expected error for not existing secret")
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	envName := EnvName("secret/data/my-app", "password")
+	if envName != "WERF_SECRET_VALUE_SECRET_DATA_MY_APP_PASSWORD" {
+		t.Errorf("\n[EXPECTED]: WERF_SECRET_VALUE_SECRET_DATA_MY_APP_PASSWORD\n[GOT]: %s", envName)
+	}
+
+	if err := os.Setenv(envName, "env"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(envName)
+
+	value, err := NewEnvProvider().GetValue(context.Background(), "secret/data/my-app", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value != "env" {
+		t.Errorf("\n[EXPECTED]: env\n[GOT]: %s", value)
+	}
+
+	if _, err := NewEnvProvider().GetValue(context.Background(), "secret/data/my-app", "user"); err == nil {
+		t.Errorf("Expected error for not set env variable")
+	}
+}
@@ -0,0 +1,127 @@
+package secret_provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultProvider reads values from HashiCorp Vault KV secrets engine (both versions 1 and 2),
+// the path is the API path of the secret (e.g. secret/data/myapp for KV version 2) and the key is the field of the secret data.
+type VaultProvider struct {
+	Address   string
+	Token     string
+	Namespace string
+
+	client *http.Client
+
+	secrets      map[string]map[string]interface{}
+	secretsMutex sync.Mutex
+}
+
+func NewVaultProvider(address, token, namespace string) (*VaultProvider, error) {
+	if address == "" {
+		return nil, fmt.Errorf("vault address is not specified")
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("vault token is not specified")
+	}
+
+	return &VaultProvider{
+		Address:   strings.TrimRight(address, "/"),
+		Token:     token,
+		Namespace: namespace,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		secrets:   map[string]map[string]interface{}{},
+	}, nil
+}
+
+func (p *VaultProvider) Name() string {
+	return VaultProviderName
+}
+
+func (p *VaultProvider) GetValue(ctx context.Context, path, key string) (string, error) {
+	data, err := p.getSecretData(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := lookupKey(data, key)
+	if !ok {
+		return "", fmt.Errorf("key %q not found in the vault secret %q", key, path)
+	}
+
+	return value, nil
+}
+
+func (p *VaultProvider) getSecretData(ctx context.Context, path string) (map[string]interface{}, error) {
+	path = strings.Trim(path, "/")
+
+	p.secretsMutex.Lock()
+	defer p.secretsMutex.Unlock()
+
+	if data, ok := p.secrets[path]; ok {
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s", p.Address, path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", p.Token)
+	if p.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.Namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read vault secret %q: %s", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read vault secret %q: %s", path, err)
+	}
+
+	var response struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []string               `json:"errors"`
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("vault secret %q not found", path)
+	case resp.StatusCode != http.StatusOK:
+		_ = json.Unmarshal(body, &response)
+		if len(response.Errors) != 0 {
+			return nil, fmt.Errorf("unable to read vault secret %q: %s: %s", path, resp.Status, strings.Join(response.Errors, ", "))
+		}
+
+		return nil, fmt.Errorf("unable to read vault secret %q: %s", path, resp.Status)
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unable to parse vault secret %q: %s", path, err)
+	}
+
+	data := response.Data
+
+	// KV version 2 wraps the secret data and adds the version metadata
+	if nestedData, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nestedData
+		}
+	}
+
+	p.secrets[path] = data
+
+	return data, nil
+}
@@ -0,0 +1,118 @@
+package werf_chart
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/werf/pkg/giterminism_inspector"
+	"github.com/werf/werf/pkg/util/secretvalues"
+)
+
+// postRenderer adds extra annotations and labels to the rendered manifests and inspects the result for plaintext secrets.
+type postRenderer struct {
+	wc *WerfChart
+}
+
+func (pr *postRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	modifiedManifests, err := pr.wc.ExtraAnnotationsAndLabelsPostRenderer.Run(renderedManifests)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pr.wc.inspectManifestsForPlaintextSecrets(modifiedManifests.String()); err != nil {
+		return nil, err
+	}
+
+	return modifiedManifests, nil
+}
+
+// inspectValuesForPlaintextSecrets inspects values.yaml of the chart and values passed by the user (--values, --set, etc.).
+func (wc *WerfChart) inspectValuesForPlaintextSecrets(inputVals map[string]interface{}) error {
+	if !giterminism_inspector.IsHelmPlaintextSecretsInspectionEnabled() {
+		return nil
+	}
+
+	// Subcharts values are inspected as a part of the rendered manifests only
+	if wc.HelmChart == nil || !wc.HelmChart.IsRoot() {
+		return nil
+	}
+
+	detector := secretvalues.NewPlaintextSecretsDetector(wc.secretValuesToMask)
+
+	var secrets []secretvalues.PlaintextSecret
+	secrets = append(secrets, detector.Detect("values.yaml", wc.HelmChart.Values)...)
+	secrets = append(secrets, detector.Detect("values", inputVals)...)
+
+	return wc.reportPlaintextSecrets(secrets)
+}
+
+// inspectManifestsForPlaintextSecrets inspects the rendered manifests, the data of Secret resources is decoded before inspection.
+func (wc *WerfChart) inspectManifestsForPlaintextSecrets(manifests string) error {
+	if !giterminism_inspector.IsHelmPlaintextSecretsInspectionEnabled() {
+		return nil
+	}
+
+	detector := secretvalues.NewPlaintextSecretsDetector(wc.secretValuesToMask)
+
+	splitManifestsByKeys := releaseutil.SplitManifests(manifests)
+
+	manifestsKeys := make([]string, 0, len(splitManifestsByKeys))
+	for k := range splitManifestsByKeys {
+		manifestsKeys = append(manifestsKeys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(manifestsKeys))
+
+	var secrets []secretvalues.PlaintextSecret
+	for _, manifestKey := range manifestsKeys {
+		var obj unstructured.Unstructured
+		if err := yaml.Unmarshal([]byte(splitManifestsByKeys[manifestKey]), &obj); err != nil || obj.GetKind() == "" {
+			continue
+		}
+
+		if obj.GetKind() == "Secret" {
+			if data, ok := obj.Object["data"].(map[string]interface{}); ok {
+				decodedData := map[string]interface{}{}
+				for k, v := range data {
+					if s, ok := v.(string); ok {
+						if decoded, err := base64.StdEncoding.DecodeString(s); err == nil {
+							decodedData[k] = string(decoded)
+							continue
+						}
+					}
+
+					decodedData[k] = v
+				}
+
+				obj.Object["data"] = decodedData
+			}
+		}
+
+		secrets = append(secrets, detector.Detect(fmt.Sprintf("%s/%s", obj.GetKind(), obj.GetName()), obj.Object)...)
+	}
+
+	return wc.reportPlaintextSecrets(secrets)
+}
+
+func (wc *WerfChart) reportPlaintextSecrets(secrets []secretvalues.PlaintextSecret) error {
+	if wc.reportedPlaintextSecrets == nil {
+		wc.reportedPlaintextSecrets = map[string]bool{}
+	}
+
+	var newSecrets []secretvalues.PlaintextSecret
+	for _, secret := range secrets {
+		if wc.reportedPlaintextSecrets[secret.String()] {
+			continue
+		}
+
+		wc.reportedPlaintextSecrets[secret.String()] = true
+		newSecrets = append(newSecrets, secret)
+	}
+
+	return giterminism_inspector.ReportHelmPlaintextSecrets(wc.Ctx, newSecrets)
+}
@@ -20,17 +20,7 @@ func DecodeSecretValuesFileFromGitCommit(ctx context.Context, path string, commi
 		data = d
 	}
 
-	decodedData, err := m.DecryptYamlData(data)
-	if err != nil {
-		return nil, fmt.Errorf("cannot decode file %q secret data: %s", path, err)
-	}
-
-	rawValues := map[string]interface{}{}
-	if err := yaml.Unmarshal(decodedData, &rawValues); err != nil {
-		return nil, fmt.Errorf("cannot unmarshal secret values file %s: %s", path, err)
-	}
-
-	return rawValues, nil
+	return DecodeSecretValuesData(path, data, m)
 }
 
 func DecodeSecretValuesFileFromFilesystem(ctx context.Context, path string, m secret.Manager) (map[string]interface{}, error) {
@@ -42,6 +32,10 @@ func DecodeSecretValuesFileFromFilesystem(ctx context.Context, path string, m se
 		data = d
 	}
 
+	return DecodeSecretValuesData(path, data, m)
+}
+
+func DecodeSecretValuesData(path string, data []byte, m secret.Manager) (map[string]interface{}, error) {
 	decodedData, err := m.DecryptYamlData(data)
 	if err != nil {
 		return nil, fmt.Errorf("cannot decode file %q secret data: %s", path, err)
@@ -23,6 +23,7 @@ import (
 	"github.com/werf/werf/pkg/deploy/helm"
 	"github.com/werf/werf/pkg/deploy/lock_manager"
 	"github.com/werf/werf/pkg/deploy/secret"
+	"github.com/werf/werf/pkg/deploy/secret_provider"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/giterminism_inspector"
 	"github.com/werf/werf/pkg/util"
@@ -34,6 +35,11 @@ const (
 	SecretDirName               = "secret"
 )
 
+// EnvSecretValuesFileName returns the name of the secret values file of the specified environment, e.g. secret-values.production.yaml.
+func EnvSecretValuesFileName(env string) string {
+	return fmt.Sprintf("secret-values.%s.yaml", env)
+}
+
 type WerfChartOptions struct {
 	ReleaseName string
 	ChartDir    string
@@ -42,8 +48,9 @@ type WerfChartOptions struct {
 	ExtraAnnotations map[string]string
 	ExtraLabels      map[string]string
 
-	LockManager    *lock_manager.LockManager
-	SecretsManager secret.Manager
+	LockManager         *lock_manager.LockManager
+	SecretsManager      secret.Manager
+	SecretValueProvider secret_provider.Provider
 }
 
 func NewWerfChart(ctx context.Context, localGitRepo *git_repo.Local, projectDir string, opts WerfChartOptions) *WerfChart {
@@ -57,9 +64,10 @@ func NewWerfChart(ctx context.Context, localGitRepo *git_repo.Local, projectDir
 		SecretValueFiles:                      opts.SecretValueFiles,
 		ExtraAnnotationsAndLabelsPostRenderer: helm.NewExtraAnnotationsAndLabelsPostRenderer(nil, nil),
 
-		LockManager:    opts.LockManager,
-		SecretsManager: opts.SecretsManager,
-		LocalGitRepo:   localGitRepo,
+		LockManager:         opts.LockManager,
+		SecretsManager:      opts.SecretsManager,
+		SecretValueProvider: opts.SecretValueProvider,
+		LocalGitRepo:        localGitRepo,
 
 		decodedSecretFilesData: make(map[string]string, 0),
 	}
@@ -76,11 +84,13 @@ type WerfChart struct {
 	ReleaseName      string
 	ChartDir         string
 	ProjectDir       string
+	Env              string
 	SecretValueFiles []string
 
 	ExtraAnnotationsAndLabelsPostRenderer *helm.ExtraAnnotationsAndLabelsPostRenderer
 	LockManager                           *lock_manager.LockManager
 	SecretsManager                        secret.Manager
+	SecretValueProvider                   secret_provider.Provider
 	LocalGitRepo                          *git_repo.Local
 
 	werfConfig             *config.WerfConfig
@@ -88,10 +98,14 @@ type WerfChart struct {
 	decodedSecretFilesData map[string]string
 	secretValuesToMask     []string
 	serviceValues          map[string]interface{}
+
+	reportedPlaintextSecrets map[string]bool
 }
 
-func (wc *WerfChart) GetPostRenderer() (postrender.PostRenderer, error) {
-	return wc.ExtraAnnotationsAndLabelsPostRenderer, nil
+// GetPostRenderer returns the post renderer which adds extra annotations and labels to the rendered manifests
+// and inspects them for plaintext secrets.
+func (wc *WerfChart) GetPostRenderer() postrender.PostRenderer {
+	return &postRenderer{wc: wc}
 }
 
 func (wc *WerfChart) SetupChart(c *chart.Chart) error {
@@ -107,6 +121,14 @@ func (wc *WerfChart) loadSecretsFromFilesystem() error {
 	} else if exists {
 		secretValuesFiles = append(secretValuesFiles, defaultSecretValuesFile)
 	}
+	if wc.Env != "" {
+		envSecretValuesFile := filepath.Join(wc.ChartDir, EnvSecretValuesFileName(wc.Env))
+		if exists, err := util.RegularFileExists(envSecretValuesFile); err != nil {
+			return fmt.Errorf("unable to check file %s existence: %s", envSecretValuesFile, err)
+		} else if exists {
+			secretValuesFiles = append(secretValuesFiles, envSecretValuesFile)
+		}
+	}
 	for _, path := range wc.SecretValueFiles {
 		secretValuesFiles = append(secretValuesFiles, path)
 	}
@@ -137,36 +159,35 @@ func (wc *WerfChart) loadSecretsFromFilesystem() error {
 				return fmt.Errorf("error reading file %s: %s", path, err)
 			}
 
-			decodedData, err := wc.SecretsManager.Decrypt([]byte(strings.TrimRightFunc(string(data), unicode.IsSpace)))
-			if err != nil {
-				return fmt.Errorf("error decoding %s: %s", path, err)
-			}
-
-			relativePath, err := filepath.Rel(secretDir, path)
-			if err != nil {
-				panic(err)
-			}
-
-			wc.decodedSecretFilesData[filepath.ToSlash(relativePath)] = string(decodedData)
-			wc.secretValuesToMask = append(wc.secretValuesToMask, string(decodedData))
-
-			return nil
+			return wc.decodeSecretFile(secretDir, path, data)
 		}); err != nil {
 			return fmt.Errorf("unable to read secrets from %s directory: %s", secretDir, err)
 		}
+
+		wc.setupEnvSecretFiles()
 	}
 
 	return nil
 }
 
 func (wc *WerfChart) loadSecretsFromLocalGitRepo() error {
-	var secretValuesFiles []string
-
 	commit, err := wc.LocalGitRepo.HeadCommit(wc.Ctx)
 	if err != nil {
 		return fmt.Errorf("unable to get local repo head commit: %s", err)
 	}
 
+	return wc.loadSecretsFromLocalGitRepoCommit(commit, true)
+}
+
+// LoadSecretsFromLocalGitRepoCommit decodes secret values and secret files of the chart at the specified local git repo commit.
+// Unlike the secrets of the head commit, project files are not compared with the commit files and extra secret values files are skipped when not found in the commit.
+func (wc *WerfChart) LoadSecretsFromLocalGitRepoCommit(commit string) error {
+	return wc.loadSecretsFromLocalGitRepoCommit(commit, false)
+}
+
+func (wc *WerfChart) loadSecretsFromLocalGitRepoCommit(commit string, isHeadCommit bool) error {
+	var secretValuesFiles []string
+
 	var chartDir string
 	if isSymlink, linkDest, err := wc.LocalGitRepo.CheckAndReadCommitSymlink(wc.Ctx, wc.ChartDir, commit); err != nil {
 		return fmt.Errorf("error checking %q is symlink in the local git repo commit %s: %s", wc.ChartDir, commit, err)
@@ -186,7 +207,28 @@ func (wc *WerfChart) loadSecretsFromLocalGitRepo() error {
 		logboek.Context(wc.Ctx).Debug().LogF("Check %s exists in the local git repo commit %s: NOT FOUND\n", defaultSecretValuesFile, commit)
 	}
 
+	if wc.Env != "" {
+		envSecretValuesFile := filepath.Join(chartDir, EnvSecretValuesFileName(wc.Env))
+		if exists, err := wc.LocalGitRepo.IsCommitFileExists(wc.Ctx, commit, envSecretValuesFile); err != nil {
+			return fmt.Errorf("error checking existence of the file %q in the local git repo commit %s: %s", envSecretValuesFile, commit, err)
+		} else if exists {
+			logboek.Context(wc.Ctx).Debug().LogF("Check %s exists in the local git repo commit %s: FOUND\n", envSecretValuesFile, commit)
+			secretValuesFiles = append(secretValuesFiles, envSecretValuesFile)
+		} else {
+			logboek.Context(wc.Ctx).Debug().LogF("Check %s exists in the local git repo commit %s: NOT FOUND\n", envSecretValuesFile, commit)
+		}
+	}
+
 	for _, path := range wc.SecretValueFiles {
+		if !isHeadCommit {
+			if exists, err := wc.LocalGitRepo.IsCommitFileExists(wc.Ctx, commit, path); err != nil {
+				return fmt.Errorf("error checking existence of the file %q in the local git repo commit %s: %s", path, commit, err)
+			} else if !exists {
+				logboek.Context(wc.Ctx).Debug().LogF("Check %s exists in the local git repo commit %s: NOT FOUND\n", path, commit)
+				continue
+			}
+		}
+
 		secretValuesFiles = append(secretValuesFiles, path)
 	}
 
@@ -195,12 +237,15 @@ func (wc *WerfChart) loadSecretsFromLocalGitRepo() error {
 
 		var decodedValues map[string]interface{}
 
-		commit, err := wc.LocalGitRepo.HeadCommit(wc.Ctx)
-		if err != nil {
-			return fmt.Errorf("unable to get local repo head commit: %s", err)
-		}
-
-		if vals, err := DecodeSecretValuesFileFromGitCommit(wc.Ctx, path, commit, *wc.LocalGitRepo, wc.SecretsManager, wc.ProjectDir); err != nil {
+		if isHeadCommit {
+			if vals, err := DecodeSecretValuesFileFromGitCommit(wc.Ctx, path, commit, *wc.LocalGitRepo, wc.SecretsManager, wc.ProjectDir); err != nil {
+				return fmt.Errorf("unable to decode secret values file %q: %s", path, err)
+			} else {
+				decodedValues = vals
+			}
+		} else if data, err := wc.LocalGitRepo.ReadCommitFile(wc.Ctx, commit, filepath.ToSlash(path)); err != nil {
+			return fmt.Errorf("error reading file %s from the local git repo commit %s: %s", path, commit, err)
+		} else if vals, err := DecodeSecretValuesData(path, data, wc.SecretsManager); err != nil {
 			return fmt.Errorf("unable to decode secret values file %q: %s", path, err)
 		} else {
 			decodedValues = vals
@@ -232,24 +277,62 @@ func (wc *WerfChart) loadSecretsFromLocalGitRepo() error {
 				return fmt.Errorf("error reading file %s from the local git repo commit %s: %s", path, commit, err)
 			}
 
-			decodedData, err := wc.SecretsManager.Decrypt([]byte(strings.TrimRightFunc(string(data), unicode.IsSpace)))
-			if err != nil {
-				return fmt.Errorf("error decoding %s: %s", path, err)
+			if err := wc.decodeSecretFile(secretDir, path, data); err != nil {
+				return err
 			}
+		}
 
-			relativePath, err := filepath.Rel(secretDir, path)
-			if err != nil {
-				panic(err)
-			}
+		wc.setupEnvSecretFiles()
+	}
 
-			wc.decodedSecretFilesData[filepath.ToSlash(relativePath)] = string(decodedData)
-			wc.secretValuesToMask = append(wc.secretValuesToMask, string(decodedData))
-		}
+	return nil
+}
+
+func (wc *WerfChart) decodeSecretFile(secretDir, path string, data []byte) error {
+	data = []byte(strings.TrimRightFunc(string(data), unicode.IsSpace))
+
+	// secrets of other environments cannot be decrypted with the available keys and are not used
+	if env := secret.GetEncryptedDataEnv(data); env != "" && env != wc.Env {
+		logboek.Context(wc.Ctx).Debug().LogF("Skipping secret file %s of the %q environment\n", path, env)
+		return nil
+	}
+
+	decodedData, err := wc.SecretsManager.Decrypt(data)
+	if err != nil {
+		return fmt.Errorf("error decoding %s: %s", path, err)
 	}
 
+	relativePath, err := filepath.Rel(secretDir, path)
+	if err != nil {
+		panic(err)
+	}
+
+	wc.decodedSecretFilesData[filepath.ToSlash(relativePath)] = string(decodedData)
+	wc.secretValuesToMask = append(wc.secretValuesToMask, string(decodedData))
+
 	return nil
 }
 
+// setupEnvSecretFiles makes the secret files from the <env>/ subdirectory of the secret directory available by the same paths as the common secret files,
+// so that the environment secret file overrides the common one in the werf_secret_file function.
+func (wc *WerfChart) setupEnvSecretFiles() {
+	if wc.Env == "" {
+		return
+	}
+
+	envDirPrefix := wc.Env + "/"
+	envSecretFilesData := map[string]string{}
+	for relativePath, decodedData := range wc.decodedSecretFilesData {
+		if strings.HasPrefix(relativePath, envDirPrefix) {
+			envSecretFilesData[strings.TrimPrefix(relativePath, envDirPrefix)] = decodedData
+		}
+	}
+
+	for relativePath, decodedData := range envSecretFilesData {
+		wc.decodedSecretFilesData[relativePath] = decodedData
+	}
+}
+
 func (wc *WerfChart) AfterLoad() error {
 	if wc.SecretsManager != nil {
 		if giterminism_inspector.LooseGiterminism || wc.LocalGitRepo == nil {
@@ -290,6 +373,10 @@ func (wc *WerfChart) AfterLoad() error {
 }
 
 func (wc *WerfChart) MakeValues(inputVals map[string]interface{}) (map[string]interface{}, error) {
+	if err := wc.inspectValuesForPlaintextSecrets(inputVals); err != nil {
+		return nil, err
+	}
+
 	vals := make(map[string]interface{})
 	chartutil.CoalesceTables(vals, wc.serviceValues) // NOTE: service values will not be saved into the marshalled release
 	chartutil.CoalesceTables(vals, wc.decodedSecretValues)
@@ -317,6 +404,32 @@ func (wc *WerfChart) SetupTemplateFuncs(t *template.Template, funcMap template.F
 		return decodedData, nil
 	}
 
+	funcMap["werf_secret_value"] = func(reference string) (string, error) {
+		if wc.SecretValueProvider == nil {
+			return "", fmt.Errorf("unable to resolve secret value %q: secret value provider is not specified (use --secret-value-provider option or $WERF_SECRET_VALUE_PROVIDER)", reference)
+		}
+
+		if !giterminism_inspector.LooseGiterminism && giterminism_inspector.IsGiterminismConfigLoaded() {
+			if err := giterminism_inspector.ReportHelmSecretValueProvider(wc.Ctx, wc.SecretValueProvider.Name()); err != nil {
+				return "", err
+			}
+		}
+
+		path, key, err := secret_provider.ParseReference(reference)
+		if err != nil {
+			return "", err
+		}
+
+		value, err := wc.SecretValueProvider.GetValue(wc.Ctx, path, key)
+		if err != nil {
+			return "", fmt.Errorf("unable to resolve secret value %q with the %s provider: %s", reference, wc.SecretValueProvider.Name(), err)
+		}
+
+		wc.secretValuesToMask = append(wc.secretValuesToMask, value)
+
+		return value, nil
+	}
+
 	helmIncludeFunc := funcMap["include"].(func(name string, data interface{}) (string, error))
 	setupIncludeWrapperFunc := func(name string) {
 		funcMap[name] = func(data interface{}) (string, error) {
@@ -329,6 +442,21 @@ func (wc *WerfChart) SetupTemplateFuncs(t *template.Template, funcMap template.F
 	}
 }
 
+// GetDecodedSecretValues returns the secret values decoded on the chart load.
+func (wc *WerfChart) GetDecodedSecretValues() map[string]interface{} {
+	return wc.decodedSecretValues
+}
+
+// GetDecodedSecretFilesData returns the secret files data decoded on the chart load by the paths relative to the secret directory.
+func (wc *WerfChart) GetDecodedSecretFilesData() map[string]string {
+	return wc.decodedSecretFilesData
+}
+
+// GetSecretValuesToMask returns the decoded secret values and secret files data along with the values resolved by werf_secret_value on render.
+func (wc *WerfChart) GetSecretValuesToMask() []string {
+	return wc.secretValuesToMask
+}
+
 func (wc *WerfChart) SetWerfConfig(werfConfig *config.WerfConfig) error {
 	wc.ExtraAnnotationsAndLabelsPostRenderer.Add(map[string]string{
 		"project.werf.io/name": werfConfig.Meta.Project,
@@ -344,6 +472,8 @@ func (wc *WerfChart) SetEnv(env string) error {
 		"project.werf.io/env": env,
 	}, nil)
 
+	wc.Env = env
+
 	return nil
 }
 
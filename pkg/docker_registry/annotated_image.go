@@ -0,0 +1,45 @@
+package docker_registry
+
+import (
+	"bytes"
+	"encoding/json"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// annotatedImage replaces the manifest annotations keeping the config and the layers
+type annotatedImage struct {
+	v1.Image
+	annotations map[string]string
+}
+
+func (img *annotatedImage) Manifest() (*v1.Manifest, error) {
+	manifest, err := img.Image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest = manifest.DeepCopy()
+	manifest.Annotations = img.annotations
+
+	return manifest, nil
+}
+
+func (img *annotatedImage) RawManifest() ([]byte, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(manifest)
+}
+
+func (img *annotatedImage) Digest() (v1.Hash, error) {
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	digest, _, err := v1.SHA256(bytes.NewReader(rawManifest))
+	return digest, err
+}
@@ -3,21 +3,30 @@ package docker_registry
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 
 	"github.com/werf/logboek"
 
+	"github.com/werf/werf/pkg/docker"
 	"github.com/werf/werf/pkg/docker_registry/container_registry_extensions"
 	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/werf"
 )
 
 type api struct {
@@ -37,8 +46,8 @@ func newAPI(options apiOptions) *api {
 	}
 }
 
-func (api *api) Tags(_ context.Context, reference string) ([]string, error) {
-	tags, err := api.list(reference)
+func (api *api) Tags(ctx context.Context, reference string) ([]string, error) {
+	tags, err := api.list(ctx, reference)
 	if err != nil {
 		if IsNameUnknownError(err) {
 			return []string{}, nil
@@ -68,8 +77,8 @@ func (api *api) TryGetRepoImage(ctx context.Context, reference string) (*image.I
 	}
 }
 
-func (api *api) GetRepoImageConfigFile(_ context.Context, reference string) (*v1.ConfigFile, error) {
-	imageInfo, _, err := api.image(reference)
+func (api *api) GetRepoImageConfigFile(ctx context.Context, reference string) (*v1.ConfigFile, error) {
+	imageInfo, _, err := api.image(ctx, reference)
 	if err != nil {
 		return nil, err
 	}
@@ -77,8 +86,38 @@ func (api *api) GetRepoImageConfigFile(_ context.Context, reference string) (*v1
 	return imageInfo.ConfigFile()
 }
 
-func (api *api) GetRepoImage(_ context.Context, reference string) (*image.Info, error) {
-	imageInfo, _, err := api.image(reference)
+// GetRepoImageManifest returns the manifest of the image or the OCI artifact (e.g. the helm chart)
+func (api *api) GetRepoImageManifest(ctx context.Context, reference string) (*v1.Manifest, error) {
+	img, _, err := api.image(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	return img.Manifest()
+}
+
+// GetRepoImageLayer returns the compressed layer of the image or the OCI artifact by the layer digest
+func (api *api) GetRepoImageLayer(ctx context.Context, reference, digest string) (io.ReadCloser, error) {
+	img, _, err := api.image(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid layer digest %q: %s", digest, err)
+	}
+
+	layer, err := img.LayerByDigest(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return layer.Compressed()
+}
+
+func (api *api) GetRepoImage(ctx context.Context, reference string) (*image.Info, error) {
+	imageInfo, _, err := api.image(ctx, reference)
 	if err != nil {
 		return nil, err
 	}
@@ -99,14 +138,21 @@ func (api *api) GetRepoImage(_ context.Context, reference string) (*image.Info,
 	}
 
 	var totalSize int64
+	var layersInfo []image.LayerInfo
 	if layers, err := imageInfo.Layers(); err != nil {
 		return nil, err
 	} else {
 		for _, l := range layers {
+			lDigest, err := l.Digest()
+			if err != nil {
+				return nil, err
+			}
+
 			if lSize, err := l.Size(); err != nil {
 				return nil, err
 			} else {
 				totalSize += lSize
+				layersInfo = append(layersInfo, image.LayerInfo{Digest: lDigest.String(), Size: lSize})
 			}
 		}
 	}
@@ -117,14 +163,16 @@ func (api *api) GetRepoImage(_ context.Context, reference string) (*image.Info,
 	}
 
 	repoImage := &image.Info{
-		Name:       reference,
-		Repository: strings.Join([]string{parsedReference.RegistryStr(), parsedReference.RepositoryStr()}, "/"),
-		ID:         manifest.Config.Digest.String(),
-		Tag:        parsedReference.TagStr(),
-		RepoDigest: digest.String(),
-		ParentID:   configFile.Config.Image,
-		Labels:     configFile.Config.Labels,
-		Size:       totalSize,
+		Name:        reference,
+		Repository:  strings.Join([]string{parsedReference.RegistryStr(), parsedReference.RepositoryStr()}, "/"),
+		ID:          manifest.Config.Digest.String(),
+		Tag:         parsedReference.TagStr(),
+		RepoDigest:  digest.String(),
+		ParentID:    configFile.Config.Image,
+		Labels:      configFile.Config.Labels,
+		Annotations: manifest.Annotations,
+		Size:        totalSize,
+		Layers:      layersInfo,
 	}
 
 	repoImage.SetCreatedAtUnix(configFile.Created.Unix())
@@ -132,13 +180,13 @@ func (api *api) GetRepoImage(_ context.Context, reference string) (*image.Info,
 	return repoImage, nil
 }
 
-func (api *api) list(reference string) ([]string, error) {
+func (api *api) list(ctx context.Context, reference string) ([]string, error) {
 	repo, err := name.NewRepository(reference, api.newRepositoryOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("parsing repo %q: %v", reference, err)
 	}
 
-	tags, err := remote.List(repo, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport()))
+	tags, err := api.listWithTagsCache(ctx, repo)
 	if err != nil {
 		return nil, fmt.Errorf("reading tags for %q: %v", repo, err)
 	}
@@ -146,13 +194,139 @@ func (api *api) list(reference string) ([]string, error) {
 	return tags, nil
 }
 
-func (api *api) deleteImageByReference(reference string) error {
+// listWithTagsCache lists tags page by page revalidating pages from the tags cache with ETag and Last-Modified headers.
+func (api *api) listWithTagsCache(ctx context.Context, repo name.Repository) ([]string, error) {
+	auth, err := credentialProviderKeychain{}.Resolve(repo.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	tr, err := transport.NewWithContext(ctx, repo.Registry, auth, api.getHttpTransport(), []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: tr}
+
+	cachedPages := map[string]*image.TagsCachePage{}
+	if image.CommonTagsCache != nil {
+		pages, err := image.CommonTagsCache.GetPages(ctx, repo.String())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, page := range pages {
+			cachedPages[page.URL] = page
+		}
+	}
+
+	pageURL := (&url.URL{
+		Scheme: repo.Registry.Scheme(),
+		Host:   repo.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/tags/list", repo.RepositoryStr()),
+		// ECR returns an error if n > 1000
+		RawQuery: "n=1000",
+	}).String()
+
+	var pages []*image.TagsCachePage
+	var tags []string
+	for pageURL != "" {
+		page, err := listTagsPage(ctx, client, pageURL, cachedPages[pageURL])
+		if err != nil {
+			return nil, err
+		}
+
+		pages = append(pages, page)
+		tags = append(tags, page.Tags...)
+		pageURL = page.NextURL
+	}
+
+	if image.CommonTagsCache != nil {
+		if err := image.CommonTagsCache.StorePages(ctx, repo.String(), pages); err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+func listTagsPage(ctx context.Context, client *http.Client, pageURL string, cachedPage *image.TagsCachePage) (*image.TagsCachePage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if cachedPage != nil {
+		if cachedPage.ETag != "" {
+			req.Header.Set("If-None-Match", cachedPage.ETag)
+		}
+
+		if cachedPage.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cachedPage.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cachedPage != nil {
+		return cachedPage, nil
+	}
+
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	nextURL, err := getNextPageURL(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &image.TagsCachePage{
+		URL:          pageURL,
+		NextURL:      nextURL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Tags:         body.Tags,
+	}, nil
+}
+
+// getNextPageURL returns the next page URL from the Link header or an empty string if it is the last page.
+func getNextPageURL(resp *http.Response) (string, error) {
+	link := resp.Header.Get("Link")
+	if link == "" {
+		return "", nil
+	}
+
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start != 0 || end == -1 {
+		return "", fmt.Errorf("failed to parse link header %q", link)
+	}
+
+	linkURL, err := url.Parse(link[1:end])
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Request.URL.ResolveReference(linkURL).String(), nil
+}
+
+func (api *api) deleteImageByReference(ctx context.Context, reference string) error {
 	r, err := name.ParseReference(reference, api.parseReferenceOptions()...)
 	if err != nil {
 		return fmt.Errorf("parsing reference %q: %v", reference, err)
 	}
 
-	if err := remote.Delete(r, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport())); err != nil {
+	if err := remote.Delete(r, remote.WithAuthFromKeychain(credentialProviderKeychain{}), remote.WithTransport(api.getHttpTransport()), remote.WithContext(ctx)); err != nil {
 		return fmt.Errorf("deleting image %q: %v", r, err)
 	}
 
@@ -160,37 +334,51 @@ func (api *api) deleteImageByReference(reference string) error {
 }
 
 func (api *api) PushImage(ctx context.Context, reference string, opts *PushImageOptions) error {
-	retriesLimit := 5
-
-attemptLoop:
-	for attempt := 1; attempt <= retriesLimit; attempt++ {
-		if err := api.pushImage(ctx, reference, opts); err != nil {
-			for _, substr := range []string{
-				"REDACTED: UNKNOWN",
-				"http2: server sent GOAWAY and closed the connection",
-				"http2: Transport received Server's graceful shutdown GOAWAY",
-			} {
-				if strings.Contains(err.Error(), substr) {
-					seconds := rand.Intn(5) + 1
-
-					msg := fmt.Sprintf("Retrying publishing in %d seconds (%d/%d) ...\n", seconds, attempt, retriesLimit)
-					logboek.Context(ctx).Warn().LogLn(msg)
-
-					time.Sleep(time.Duration(seconds) * time.Second)
-					continue attemptLoop
-				}
-			}
+	// The whole push is retried, so that the requests of the push are not retried by the transport once again
+	pushCtx := withoutRequestsRetries(ctx)
+	attempts := requestsPolicy.MaxRetries + 1
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = api.pushImage(pushCtx, reference, opts); err == nil || !isRetryablePushError(err) || attempt == attempts {
+			break
+		}
 
-			return err
+		delay := requestsPolicy.retryDelay(attempt, nil)
+
+		msg := fmt.Sprintf("Retrying publishing in %s (%d/%d) ...\n", delay.Round(time.Millisecond), attempt, requestsPolicy.MaxRetries)
+		logboek.Context(ctx).Warn().LogLn(msg)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %s", err, ctx.Err())
+		case <-time.After(delay):
 		}
+	}
 
-		return nil
+	return err
+}
+
+func isRetryablePushError(err error) bool {
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) && isRetryableStatusCode(transportErr.StatusCode) {
+		return true
 	}
 
-	return nil
+	for _, substr := range []string{
+		"REDACTED: UNKNOWN",
+		"http2: server sent GOAWAY and closed the connection",
+		"http2: Transport received Server's graceful shutdown GOAWAY",
+	} {
+		if strings.Contains(err.Error(), substr) {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (api *api) pushImage(_ context.Context, reference string, opts *PushImageOptions) error {
+func (api *api) pushImage(ctx context.Context, reference string, opts *PushImageOptions) error {
 	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
 	if err != nil {
 		return fmt.Errorf("parsing reference %q: %v", reference, err)
@@ -205,17 +393,124 @@ func (api *api) pushImage(_ context.Context, reference string, opts *PushImageOp
 
 	oldDefaultTransport := http.DefaultTransport
 	http.DefaultTransport = api.getHttpTransport()
-	err = remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	err = remote.Write(ref, img, remote.WithAuthFromKeychain(credentialProviderKeychain{}), remote.WithContext(ctx))
 	http.DefaultTransport = oldDefaultTransport
 
 	if err != nil {
-		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
+		return fmt.Errorf("write to the remote %s have failed: %w", ref.String(), err)
 	}
 
 	return nil
 }
 
-func (api *api) image(reference string) (v1.Image, name.Reference, error) {
+// CopyImage copies the manifest, the config and the layers from the source to the destination registry-to-registry.
+// The layers are mounted from the source repository if both repositories are on the same host, otherwise the layers are streamed without saving.
+func (api *api) CopyImage(ctx context.Context, sourceReference, destinationReference string) error {
+	return api.copyImage(ctx, sourceReference, destinationReference, func(img v1.Image) v1.Image { return img })
+}
+
+// CopyImageWithAnnotations copies the image registry-to-registry replacing the manifest annotations (nil annotations are removed).
+// The config and the layers are not changed, so the destination image has the same image ID, but another digest.
+func (api *api) CopyImageWithAnnotations(ctx context.Context, sourceReference, destinationReference string, annotations map[string]string) error {
+	return api.copyImage(ctx, sourceReference, destinationReference, func(img v1.Image) v1.Image {
+		return &annotatedImage{Image: img, annotations: annotations}
+	})
+}
+
+func (api *api) copyImage(ctx context.Context, sourceReference, destinationReference string, mutateImage func(img v1.Image) v1.Image) error {
+	sourceRef, err := name.ParseReference(sourceReference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", sourceReference, err)
+	}
+
+	destinationRef, err := name.ParseReference(destinationReference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", destinationReference, err)
+	}
+
+	img, err := remote.Image(sourceRef, remote.WithAuthFromKeychain(credentialProviderKeychain{}), remote.WithTransport(api.getHttpTransport()), remote.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("reading image %q: %v", sourceRef, err)
+	}
+
+	if err := remote.Write(destinationRef, mutateImage(img), remote.WithAuthFromKeychain(credentialProviderKeychain{}), remote.WithTransport(api.getHttpTransport()), remote.WithContext(ctx)); err != nil {
+		return fmt.Errorf("copying image %q to %q: %v", sourceRef, destinationRef, err)
+	}
+
+	return nil
+}
+
+// PushDockerImage pushes the local docker image with the layers recompressed with the specified compression.
+// The image is exported with docker save on each push, the recompressed layers are reused from the CompressedLayersCache.
+// LayerCompressionUnsupportedError is returned if the registry does not accept OCI media types.
+func (api *api) PushDockerImage(ctx context.Context, reference string, compression LayerCompression) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	tmpDir, err := ioutil.TempDir(werf.GetTmpDir(), "push-image-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, "image.tar")
+	if err := docker.ImageSaveToFile(ctx, archivePath, reference); err != nil {
+		return fmt.Errorf("unable to save local image %q: %s", reference, err)
+	}
+
+	img, err := tarball.ImageFromPath(archivePath, nil)
+	if err != nil {
+		return fmt.Errorf("unable to read saved image %q: %s", reference, err)
+	}
+
+	return api.pushCompressedImage(ctx, ref, img, compression, GetCompressedLayersCache())
+}
+
+func (api *api) pushCompressedImage(ctx context.Context, ref name.Reference, img v1.Image, compression LayerCompression, cache *CompressedLayersCache) error {
+	return cache.WithSharedLock(ctx, func() error {
+		var compressedImg v1.Image
+		if err := logboek.Context(ctx).Info().LogProcess("Compressing layers with %s", compression).DoError(func() error {
+			var err error
+			compressedImg, err = recompressImageLayers(img, compression, cache)
+			return err
+		}); isEstargzBuildError(err) {
+			logboek.Context(ctx).Warn().LogF("WARNING: %s: falling back to %s layers\n", err, GzipLayerCompression)
+			compressedImg = img
+		} else if err != nil {
+			return err
+		}
+
+		if err := remote.Write(ref, compressedImg, remote.WithAuthFromKeychain(credentialProviderKeychain{}), remote.WithTransport(api.getHttpTransport()), remote.WithContext(ctx)); err != nil {
+			var transportErr *transport.Error
+			if errors.As(err, &transportErr) && isOCIMediaTypeRejectedError(transportErr) {
+				return LayerCompressionUnsupportedError{fmt.Errorf("registry rejected %s compressed image %q: %s", compression, ref, err)}
+			}
+
+			return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
+		}
+
+		return nil
+	})
+}
+
+func isOCIMediaTypeRejectedError(err *transport.Error) bool {
+	if err.StatusCode == http.StatusUnsupportedMediaType {
+		return true
+	}
+
+	for _, diagnostic := range err.Errors {
+		switch diagnostic.Code {
+		case transport.ManifestInvalidErrorCode, transport.UnsupportedErrorCode:
+			return true
+		}
+	}
+
+	return false
+}
+
+func (api *api) image(ctx context.Context, reference string) (v1.Image, name.Reference, error) {
 	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parsing reference %q: %v", reference, err)
@@ -226,7 +521,7 @@ func (api *api) image(reference string) (v1.Image, name.Reference, error) {
 	// FIXME: Needed for the insecure https registry to work.
 	oldDefaultTransport := http.DefaultTransport
 	http.DefaultTransport = api.getHttpTransport()
-	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	img, err := remote.Image(ref, remote.WithAuthFromKeychain(credentialProviderKeychain{}), remote.WithContext(ctx))
 	http.DefaultTransport = oldDefaultTransport
 
 	if err != nil {
@@ -251,11 +546,12 @@ func (api *api) parseReferenceOptions() []name.Option {
 	return options
 }
 
+// getHttpTransport returns the transport for the go-containerregistry calls with the requests policy applied.
 func (api *api) getHttpTransport() (transport http.RoundTripper) {
-	transport = http.DefaultTransport
+	transport = defaultHttpTransport
 
 	if api.SkipTlsVerifyRegistry {
-		defaultTransport := http.DefaultTransport.(*http.Transport)
+		defaultTransport := defaultHttpTransport.(*http.Transport)
 
 		newTransport := &http.Transport{
 			Proxy:                 defaultTransport.Proxy,
@@ -271,5 +567,5 @@ func (api *api) getHttpTransport() (transport http.RoundTripper) {
 		transport = newTransport
 	}
 
-	return
+	return newRetryTransport(transport)
 }
@@ -0,0 +1,265 @@
+package docker_registry
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var _ = Describe("copy image", func() {
+	It("should copy the image registry-to-registry keeping the digest", func() {
+		server := httptest.NewServer(registry.New())
+		defer server.Close()
+		host := strings.TrimPrefix(server.URL, "http://")
+
+		img, err := random.Image(1024, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		sourceRef, err := name.ParseReference(host+"/secondary:stage", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(sourceRef, img)).Should(Succeed())
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		Ω(api.CopyImage(context.Background(), sourceRef.String(), host+"/primary:stage")).Should(Succeed())
+
+		info, err := api.GetRepoImage(context.Background(), host+"/primary:stage")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		expectedDigest, err := img.Digest()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(info.RepoDigest).Should(Equal(expectedDigest.String()))
+	})
+})
+
+var _ = Describe("copy image with annotations", func() {
+	It("should keep the image config and replace the manifest annotations", func() {
+		server := httptest.NewServer(registry.New())
+		defer server.Close()
+		host := strings.TrimPrefix(server.URL, "http://")
+
+		img, err := random.Image(1024, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		sourceRef, err := name.ParseReference(host+"/repo:stage", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(sourceRef, img)).Should(Succeed())
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		Ω(api.CopyImageWithAnnotations(context.Background(), sourceRef.String(), host+"/repo:annotated", map[string]string{"key": "value"})).Should(Succeed())
+		Ω(api.CopyImageWithAnnotations(context.Background(), host+"/repo:annotated", host+"/repo:restored", nil)).Should(Succeed())
+
+		sourceInfo, err := api.GetRepoImage(context.Background(), sourceRef.String())
+		Ω(err).ShouldNot(HaveOccurred())
+
+		for reference, expectedAnnotations := range map[string]map[string]string{
+			host + "/repo:annotated": {"key": "value"},
+			host + "/repo:restored":  nil,
+		} {
+			info, err := api.GetRepoImage(context.Background(), reference)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(info.ID).Should(Equal(sourceInfo.ID))
+
+			ref, err := name.ParseReference(reference, name.Insecure)
+			Ω(err).ShouldNot(HaveOccurred())
+			copiedImg, err := remote.Image(ref)
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest, err := copiedImg.Manifest()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(manifest.Annotations).Should(Equal(expectedAnnotations))
+		}
+
+		annotatedInfo, err := api.GetRepoImage(context.Background(), host+"/repo:annotated")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(annotatedInfo.RepoDigest).ShouldNot(Equal(sourceInfo.RepoDigest))
+	})
+})
+
+var _ = Describe("get repo image manifest and layer", func() {
+	It("should return the manifest and the compressed layer by digest", func() {
+		server := httptest.NewServer(registry.New())
+		defer server.Close()
+		host := strings.TrimPrefix(server.URL, "http://")
+
+		img, err := random.Image(1024, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ref, err := name.ParseReference(host+"/repo:bundle", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(ref, img)).Should(Succeed())
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		manifest, err := api.GetRepoImageManifest(context.Background(), ref.String())
+		Ω(err).ShouldNot(HaveOccurred())
+
+		expectedManifest, err := img.Manifest()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(manifest.Layers).Should(Equal(expectedManifest.Layers))
+
+		layers, err := img.Layers()
+		Ω(err).ShouldNot(HaveOccurred())
+		expectedRc, err := layers[1].Compressed()
+		Ω(err).ShouldNot(HaveOccurred())
+		defer expectedRc.Close()
+		expectedData, err := ioutil.ReadAll(expectedRc)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		rc, err := api.GetRepoImageLayer(context.Background(), ref.String(), manifest.Layers[1].Digest.String())
+		Ω(err).ShouldNot(HaveOccurred())
+		defer rc.Close()
+		data, err := ioutil.ReadAll(rc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(data).Should(Equal(expectedData))
+	})
+})
+
+var _ = Describe("list tags", func() {
+	var tmpDir string
+	var savedTagsCache *image.TagsCache
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "werf-tags-cache-test")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werf.Init(filepath.Join(tmpDir, "tmp"), filepath.Join(tmpDir, "home"))).Should(Succeed())
+
+		savedTagsCache = image.CommonTagsCache
+		image.CommonTagsCache = image.NewTagsCache(filepath.Join(tmpDir, "tags"))
+	})
+
+	AfterEach(func() {
+		image.CommonTagsCache = savedTagsCache
+		Ω(os.RemoveAll(tmpDir)).Should(Succeed())
+	})
+
+	It("should revalidate cached pages with ETag", func() {
+		pagesTags := map[string][]string{"": {"a", "b"}, "b": {"c"}}
+		var fullResponses, notModifiedResponses int
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v2/" {
+				return
+			}
+
+			last := r.URL.Query().Get("last")
+			etag := fmt.Sprintf("%q", strings.Join(pagesTags[last], ","))
+			if r.Header.Get("If-None-Match") == etag {
+				notModifiedResponses++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			if last == "" {
+				w.Header().Set("Link", `</v2/repo/tags/list?n=1000&last=b>; rel="next"`)
+			}
+			w.Header().Set("ETag", etag)
+			fullResponses++
+			_, _ = fmt.Fprintf(w, `{"name": "repo", "tags": ["%s"]}`, strings.Join(pagesTags[last], `", "`))
+		}))
+		defer server.Close()
+		repo := strings.TrimPrefix(server.URL, "http://") + "/repo"
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		tags, err := api.Tags(context.Background(), repo)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"a", "b", "c"}))
+		Ω(fullResponses).Should(Equal(2))
+
+		tags, err = api.Tags(context.Background(), repo)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"a", "b", "c"}))
+		Ω(fullResponses).Should(Equal(2))
+		Ω(notModifiedResponses).Should(Equal(2))
+
+		pagesTags["b"] = []string{"c", "d"}
+		tags, err = api.Tags(context.Background(), repo)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"a", "b", "c", "d"}))
+		Ω(fullResponses).Should(Equal(3))
+	})
+})
+
+var _ = Describe("push image", func() {
+	var savedRequestsPolicy *requestsPolicyState
+	var manifestPutsCount, failedManifestPutsLimit int32
+	var host string
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		savedRequestsPolicy = requestsPolicy
+		manifestPutsCount, failedManifestPutsLimit = 0, 0
+
+		registryHandler := registry.New()
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/manifests/") {
+				if atomic.AddInt32(&manifestPutsCount, 1) <= atomic.LoadInt32(&failedManifestPutsLimit) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+			}
+
+			registryHandler.ServeHTTP(w, r)
+		}))
+		host = strings.TrimPrefix(server.URL, "http://")
+	})
+
+	AfterEach(func() {
+		requestsPolicy = savedRequestsPolicy
+		server.Close()
+	})
+
+	It("should push once without retries", func() {
+		requestsPolicy = newRequestsPolicyState(RequestsPolicyOptions{MaxRetries: 0})
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		Ω(api.PushImage(context.Background(), host+"/repo:tag", nil)).Should(Succeed())
+		Ω(atomic.LoadInt32(&manifestPutsCount)).Should(Equal(int32(1)))
+
+		_, err := api.GetRepoImage(context.Background(), host+"/repo:tag")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("should return the error without retries", func() {
+		requestsPolicy = newRequestsPolicyState(RequestsPolicyOptions{MaxRetries: 0})
+		failedManifestPutsLimit = 1
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		Ω(api.PushImage(context.Background(), host+"/repo:tag", nil)).ShouldNot(Succeed())
+		Ω(atomic.LoadInt32(&manifestPutsCount)).Should(Equal(int32(1)))
+	})
+
+	It("should retry the push and return the last error when retries are exhausted", func() {
+		requestsPolicy = newRequestsPolicyState(RequestsPolicyOptions{MaxRetries: 1})
+		failedManifestPutsLimit = 100
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		err := api.PushImage(context.Background(), host+"/repo:tag", nil)
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring("503"))
+		Ω(atomic.LoadInt32(&manifestPutsCount)).Should(Equal(int32(2)))
+	})
+
+	It("should retry the failed push", func() {
+		requestsPolicy = newRequestsPolicyState(RequestsPolicyOptions{MaxRetries: 1})
+		failedManifestPutsLimit = 1
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		Ω(api.PushImage(context.Background(), host+"/repo:tag", nil)).Should(Succeed())
+		Ω(atomic.LoadInt32(&manifestPutsCount)).Should(Equal(int32(2)))
+	})
+})
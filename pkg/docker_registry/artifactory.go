@@ -0,0 +1,144 @@
+package docker_registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+const ArtifactoryImplementationName = "artifactory"
+
+type ArtifactoryNotFoundError apiError
+
+type ArtifactoryUnauthorizedError apiError
+
+var artifactoryPatterns = []string{`^.*\.jfrog\.io`, "^artifactory\\..*"}
+
+type artifactory struct {
+	*defaultImplementation
+	artifactoryApi
+	credentials Credentials
+}
+
+type artifactoryOptions struct {
+	defaultImplementationOptions
+	credentials Credentials
+}
+
+func newArtifactory(options artifactoryOptions) (*artifactory, error) {
+	d, err := newDefaultImplementation(options.defaultImplementationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	artifactory := &artifactory{
+		defaultImplementation: d,
+		artifactoryApi:        newArtifactoryApi(),
+		credentials:           options.credentials,
+	}
+
+	return artifactory, nil
+}
+
+func (r *artifactory) CreateRepo(ctx context.Context, reference string) error {
+	hostname, repoKey, _, err := r.parseReference(reference)
+	if err != nil {
+		return err
+	}
+
+	creds, err := resolveCredentials(ctx, hostname, r.credentials)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.artifactoryApi.getRepository(ctx, hostname, repoKey, creds.Username, creds.Password)
+	if err == nil {
+		return nil
+	} else if resp == nil || resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusNotFound {
+		return r.processError(resp, err)
+	}
+
+	resp, err = r.artifactoryApi.createLocalDockerRepository(ctx, hostname, repoKey, creds.Username, creds.Password)
+	if err != nil {
+		return r.processError(resp, err)
+	}
+
+	return nil
+}
+
+func (r *artifactory) DeleteRepo(ctx context.Context, reference string) error {
+	hostname, repoKey, imagePath, err := r.parseReference(reference)
+	if err != nil {
+		return err
+	}
+
+	creds, err := resolveCredentials(ctx, hostname, r.credentials)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.artifactoryApi.deleteItem(ctx, hostname, repoKey, imagePath, creds.Username, creds.Password)
+	if err != nil {
+		return r.processError(resp, err)
+	}
+
+	return nil
+}
+
+// DeleteRepoImage removes the tag folder through the Artifactory REST API.
+// The manifest DELETE of the registry API removes only the manifest, leaving the tag folder with layers behind.
+func (r *artifactory) DeleteRepoImage(ctx context.Context, repoImage *image.Info) error {
+	hostname, repoKey, imagePath, err := r.parseReference(repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	creds, err := resolveCredentials(ctx, hostname, r.credentials)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.artifactoryApi.deleteItem(ctx, hostname, repoKey, strings.Join([]string{imagePath, repoImage.Tag}, "/"), creds.Username, creds.Password)
+	if err != nil {
+		return r.processError(resp, err)
+	}
+
+	return nil
+}
+
+func (r *artifactory) processError(resp *http.Response, err error) error {
+	if resp != nil {
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return ArtifactoryUnauthorizedError{error: err}
+		} else if resp.StatusCode == http.StatusNotFound {
+			return ArtifactoryNotFoundError{error: err}
+		}
+	}
+
+	return err
+}
+
+func (r *artifactory) String() string {
+	return ArtifactoryImplementationName
+}
+
+// parseReference splits the reference into the hostname, the repository key and the image path
+// (the repository path method: ARTIFACTORY_HOST/REPOSITORY_KEY/IMAGE_PATH).
+func (r *artifactory) parseReference(reference string) (string, string, string, error) {
+	parsedReference, err := name.NewRepository(reference)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	repositoryParts := strings.SplitN(parsedReference.RepositoryStr(), "/", 2)
+	if len(repositoryParts) != 2 {
+		return "", "", "", fmt.Errorf("unexpected reference %s: expected ARTIFACTORY_HOST/REPOSITORY_KEY/IMAGE_PATH", reference)
+	}
+
+	return parsedReference.RegistryStr(), repositoryParts[0], repositoryParts[1], nil
+}
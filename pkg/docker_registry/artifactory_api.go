@@ -0,0 +1,93 @@
+package docker_registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	neturl "net/url"
+	"path"
+)
+
+type artifactoryApi struct{}
+
+func newArtifactoryApi() artifactoryApi {
+	return artifactoryApi{}
+}
+
+func (api *artifactoryApi) deleteItem(ctx context.Context, hostname, repoKey, itemPath, username, password string) (*http.Response, error) {
+	url, err := api.url(hostname, repoKey, itemPath)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, _, err := doRequest(ctx, http.MethodDelete, url, nil, doRequestOptions{
+		BasicAuth: doRequestBasicAuth{
+			username: username,
+			password: password,
+		},
+		AcceptedCodes: []int{http.StatusOK, http.StatusAccepted, http.StatusNoContent},
+	})
+
+	return resp, err
+}
+
+func (api *artifactoryApi) getRepository(ctx context.Context, hostname, repoKey, username, password string) (*http.Response, error) {
+	url, err := api.url(hostname, "api", "repositories", repoKey)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, _, err := doRequest(ctx, http.MethodGet, url, nil, doRequestOptions{
+		Headers: map[string]string{
+			"Accept": "application/json",
+		},
+		BasicAuth: doRequestBasicAuth{
+			username: username,
+			password: password,
+		},
+		AcceptedCodes: []int{http.StatusOK},
+	})
+
+	return resp, err
+}
+
+func (api *artifactoryApi) createLocalDockerRepository(ctx context.Context, hostname, repoKey, username, password string) (*http.Response, error) {
+	url, err := api.url(hostname, "api", "repositories", repoKey)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"key":         repoKey,
+		"rclass":      "local",
+		"packageType": "docker",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, _, err := doRequest(ctx, http.MethodPut, url, bytes.NewBuffer(body), doRequestOptions{
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		BasicAuth: doRequestBasicAuth{
+			username: username,
+			password: password,
+		},
+		AcceptedCodes: []int{http.StatusOK, http.StatusCreated},
+	})
+
+	return resp, err
+}
+
+func (api *artifactoryApi) url(hostname string, pathParts ...string) (string, error) {
+	u, err := neturl.Parse("https://" + hostname + "/artifactory")
+	if err != nil {
+		return "", err
+	}
+
+	u.Path = path.Join(append([]string{u.Path}, pathParts...)...)
+
+	return u.String(), nil
+}
@@ -14,6 +14,9 @@ import (
 	"github.com/werf/logboek"
 )
 
+// client is used by the native REST APIs of implementations and applies the same requests policy as the Docker Registry API calls.
+var client = &http.Client{Transport: newRetryTransport(defaultHttpTransport)}
+
 type apiError struct {
 	error
 }
@@ -30,7 +33,7 @@ type doRequestBasicAuth struct {
 }
 
 func doRequest(ctx context.Context, method, url string, body io.Reader, options doRequestOptions) (*http.Response, []byte, error) {
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -44,7 +47,7 @@ func doRequest(ctx context.Context, method, url string, body io.Reader, options
 	}
 
 	logboek.Context(ctx).Debug().LogF("--> %s %s\n", method, url)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, nil, err
 	}
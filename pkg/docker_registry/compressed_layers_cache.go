@@ -0,0 +1,228 @@
+package docker_registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	CompressedLayersCacheVersion = "1"
+	CompressedLayersCacheTTL     = 7 * 24 * time.Hour
+
+	compressedLayersCacheLockName                      = "compressed_layers_cache"
+	compressedLayersCacheLayerFile                     = "layer"
+	compressedLayersCacheRecordFile                    = "record.json"
+	compressedLayersCacheAccessTimestampUpdatePeriod   = time.Hour
+	compressedLayersCacheIncompleteEntryRemovalTimeout = 24 * time.Hour
+)
+
+// CompressedLayersCache stores the layers recompressed for the push by the compression and the source layer diff id.
+// The layers shared by the stages are compressed only once and are reused by the following pushes.
+type CompressedLayersCache struct {
+	CacheDir string
+}
+
+type CompressedLayersCacheRecord struct {
+	Digest      string
+	DiffID      string
+	Size        int64
+	Annotations map[string]string
+}
+
+func NewCompressedLayersCache(cacheDir string) *CompressedLayersCache {
+	return &CompressedLayersCache{CacheDir: cacheDir}
+}
+
+func GetCompressedLayersCache() *CompressedLayersCache {
+	return NewCompressedLayersCache(filepath.Join(werf.GetLocalCacheDir(), "compressed_layers", CompressedLayersCacheVersion))
+}
+
+// WithSharedLock protects the cached layers from the GC until f is done
+func (cache *CompressedLayersCache) WithSharedLock(ctx context.Context, f func() error) error {
+	return werf.WithHostLock(ctx, compressedLayersCacheLockName, lockgate.AcquireOptions{Shared: true}, f)
+}
+
+func (cache *CompressedLayersCache) GetOrCreateLayer(layer v1.Layer, compression LayerCompression) (*compressedLayer, error) {
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get layer diff id: %s", err)
+	}
+
+	entryDir := filepath.Join(cache.CacheDir, string(compression), diffID.Hex)
+	if res, err := cache.readEntry(entryDir, compression); err != nil || res != nil {
+		return res, err
+	}
+
+	compressionDir := filepath.Dir(entryDir)
+	if err := os.MkdirAll(compressionDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("error creating dir %s: %s", compressionDir, err)
+	}
+
+	tmpEntryDir, err := ioutil.TempDir(compressionDir, "tmp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpEntryDir)
+
+	res, err := compressLayer(layer, compression, tmpEntryDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(res.path, filepath.Join(tmpEntryDir, compressedLayersCacheLayerFile)); err != nil {
+		return nil, err
+	}
+
+	record := &CompressedLayersCacheRecord{
+		Digest:      res.digest.String(),
+		DiffID:      res.diffID.String(),
+		Size:        res.size,
+		Annotations: res.annotations,
+	}
+
+	if dataBytes, err := json.Marshal(record); err != nil {
+		return nil, fmt.Errorf("error marshalling json: %s", err)
+	} else if err := ioutil.WriteFile(filepath.Join(tmpEntryDir, compressedLayersCacheRecordFile), append(dataBytes, []byte("\n")...), 0644); err != nil {
+		return nil, fmt.Errorf("error writing record: %s", err)
+	}
+
+	if err := os.Rename(tmpEntryDir, entryDir); err != nil {
+		// the same layer could be compressed by another process
+		if res, readErr := cache.readEntry(entryDir, compression); readErr == nil && res != nil {
+			return res, nil
+		}
+
+		return nil, fmt.Errorf("unable to store compressed layer into %s: %s", entryDir, err)
+	}
+
+	res.path = filepath.Join(entryDir, compressedLayersCacheLayerFile)
+
+	return res, nil
+}
+
+func (cache *CompressedLayersCache) readEntry(entryDir string, compression LayerCompression) (*compressedLayer, error) {
+	recordPath := filepath.Join(entryDir, compressedLayersCacheRecordFile)
+
+	recordStat, err := os.Stat(recordPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error accessing %s: %s", recordPath, err)
+	}
+
+	dataBytes, err := ioutil.ReadFile(recordPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", recordPath, err)
+	}
+
+	record := &CompressedLayersCacheRecord{}
+	if err := json.Unmarshal(dataBytes, record); err != nil {
+		return nil, fmt.Errorf("invalid compressed layers cache json record %s: %s", recordPath, err)
+	}
+
+	res := newCompressedLayer(compression, v1.Hash{})
+	res.path = filepath.Join(entryDir, compressedLayersCacheLayerFile)
+	res.size = record.Size
+	res.annotations = record.Annotations
+
+	if res.digest, err = v1.NewHash(record.Digest); err != nil {
+		return nil, fmt.Errorf("invalid compressed layers cache record %s: %s", recordPath, err)
+	}
+
+	if res.diffID, err = v1.NewHash(record.DiffID); err != nil {
+		return nil, fmt.Errorf("invalid compressed layers cache record %s: %s", recordPath, err)
+	}
+
+	if _, err := os.Stat(res.path); err != nil {
+		return nil, fmt.Errorf("error accessing %s: %s", res.path, err)
+	}
+
+	// the record modification time is the last access time which is used by the GC
+	if now := time.Now(); now.Sub(recordStat.ModTime()) > compressedLayersCacheAccessTimestampUpdatePeriod {
+		if err := os.Chtimes(recordPath, now, now); err != nil {
+			return nil, fmt.Errorf("unable to update access time of %s: %s", recordPath, err)
+		}
+	}
+
+	return res, nil
+}
+
+// GC removes the layers which have not been used for the CompressedLayersCacheTTL.
+// GC is skipped if the cache is used by another process.
+func (cache *CompressedLayersCache) GC(ctx context.Context, dryRun bool) error {
+	if _, err := os.Stat(cache.CacheDir); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error accessing %s: %s", cache.CacheDir, err)
+	}
+
+	isAcquired, lock, err := werf.AcquireHostLock(ctx, compressedLayersCacheLockName, lockgate.AcquireOptions{NonBlocking: true})
+	if err != nil {
+		return fmt.Errorf("cannot acquire %s host lock: %s", compressedLayersCacheLockName, err)
+	}
+
+	if !isAcquired {
+		logboek.Context(ctx).Debug().LogF("Ignore compressed layers cache used by another process\n")
+		return nil
+	}
+	defer werf.ReleaseHostLock(lock)
+
+	compressionDirs, err := ioutil.ReadDir(cache.CacheDir)
+	if err != nil {
+		return fmt.Errorf("unable to list %s: %s", cache.CacheDir, err)
+	}
+
+	now := time.Now()
+	var removedEntries int
+	for _, compressionDir := range compressionDirs {
+		compressionDirPath := filepath.Join(cache.CacheDir, compressionDir.Name())
+
+		entries, err := ioutil.ReadDir(compressionDirPath)
+		if err != nil {
+			return fmt.Errorf("unable to list %s: %s", compressionDirPath, err)
+		}
+
+		for _, entry := range entries {
+			entryPath := filepath.Join(compressionDirPath, entry.Name())
+
+			if recordStat, err := os.Stat(filepath.Join(entryPath, compressedLayersCacheRecordFile)); err == nil {
+				if now.Sub(recordStat.ModTime()) <= CompressedLayersCacheTTL {
+					continue
+				}
+			} else if !os.IsNotExist(err) {
+				return fmt.Errorf("error accessing %s: %s", entryPath, err)
+			} else if now.Sub(entry.ModTime()) <= compressedLayersCacheIncompleteEntryRemovalTimeout {
+				continue
+			}
+
+			logboek.Context(ctx).LogLn(entryPath)
+			removedEntries++
+
+			if dryRun {
+				continue
+			}
+
+			if err := os.RemoveAll(entryPath); err != nil {
+				return fmt.Errorf("unable to remove %s: %s", entryPath, err)
+			}
+		}
+	}
+
+	if removedEntries > 0 {
+		logboek.Context(ctx).LogF("Compressed layers cache: removed %d layers not used for %s\n", removedEntries, CompressedLayersCacheTTL)
+	}
+
+	return nil
+}
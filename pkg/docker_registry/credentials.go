@@ -0,0 +1,389 @@
+package docker_registry
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/docker/cli/cli/config"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/google"
+	"gopkg.in/yaml.v2"
+)
+
+// Credentials are the registry credentials.
+// The same credentials are used for the Docker Registry API and for the native API of the implementation.
+type Credentials struct {
+	Username      string `yaml:"username,omitempty"`
+	Password      string `yaml:"password,omitempty"`
+	RegistryToken string `yaml:"registryToken,omitempty"`
+	IdentityToken string `yaml:"identityToken,omitempty"`
+
+	// Token is the token for the native API of the implementation (Docker Hub, GitHub Packages, Quay).
+	Token string `yaml:"token,omitempty"`
+}
+
+func (c Credentials) IsEmpty() bool {
+	return c == Credentials{}
+}
+
+func (c Credentials) hasRegistryAuth() bool {
+	return c.Username != "" || c.Password != "" || c.RegistryToken != "" || c.IdentityToken != ""
+}
+
+// CredentialProvider provides credentials for the registry host, nil credentials mean that the provider has no credentials for the host.
+type CredentialProvider interface {
+	Credentials(ctx context.Context, host string) (*Credentials, error)
+	String() string
+}
+
+type CredentialProviderOptions struct {
+	// CredentialsFile is the path to the YAML file with credentials by registry host.
+	CredentialsFile string
+}
+
+var credentialProvider = NewDefaultCredentialProvider(CredentialProviderOptions{})
+
+// NewDefaultCredentialProvider returns the provider that checks the credentials file, the environment and the docker config (with credential helpers).
+// ECR and GCR token exchange is used if there are no registry credentials for the host.
+func NewDefaultCredentialProvider(options CredentialProviderOptions) CredentialProvider {
+	p := &defaultCredentialProvider{}
+	if options.CredentialsFile != "" {
+		p.providers = append(p.providers, newCredentialsFileProvider(options.CredentialsFile))
+	}
+
+	p.providers = append(p.providers, envCredentialProvider{}, dockerConfigCredentialProvider{})
+	p.tokenExchangeProviders = []CredentialProvider{newAwsEcrCredentialProvider(), gcrCredentialProvider{}}
+
+	return p
+}
+
+type defaultCredentialProvider struct {
+	providers              []CredentialProvider
+	tokenExchangeProviders []CredentialProvider
+}
+
+// Credentials takes the registry credentials and the native API token from the first providers that have them.
+func (p *defaultCredentialProvider) Credentials(ctx context.Context, host string) (*Credentials, error) {
+	res := &Credentials{}
+
+	merge := func(providers []CredentialProvider) error {
+		for _, provider := range providers {
+			if res.hasRegistryAuth() && res.Token != "" {
+				return nil
+			}
+
+			creds, err := provider.Credentials(ctx, host)
+			if err != nil {
+				return fmt.Errorf("%s credentials for %s: %s", provider, host, err)
+			} else if creds == nil {
+				continue
+			}
+
+			if !res.hasRegistryAuth() {
+				res.Username, res.Password, res.RegistryToken, res.IdentityToken = creds.Username, creds.Password, creds.RegistryToken, creds.IdentityToken
+			}
+
+			if res.Token == "" {
+				res.Token = creds.Token
+			}
+		}
+
+		return nil
+	}
+
+	if err := merge(p.providers); err != nil {
+		return nil, err
+	}
+
+	if !res.hasRegistryAuth() {
+		if err := merge(p.tokenExchangeProviders); err != nil {
+			return nil, err
+		}
+	}
+
+	if res.IsEmpty() {
+		return nil, nil
+	}
+
+	return res, nil
+}
+
+func (p *defaultCredentialProvider) String() string {
+	var names []string
+	for _, provider := range append(p.providers, p.tokenExchangeProviders...) {
+		names = append(names, provider.String())
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// credentialsFileProvider reads credentials by registry host from the YAML file (username, password, registryToken, identityToken and token fields).
+type credentialsFileProvider struct {
+	path string
+
+	credentials map[string]Credentials
+	err         error
+	once        sync.Once
+}
+
+func newCredentialsFileProvider(path string) *credentialsFileProvider {
+	return &credentialsFileProvider{path: path}
+}
+
+func (p *credentialsFileProvider) Credentials(_ context.Context, host string) (*Credentials, error) {
+	p.once.Do(func() {
+		p.credentials, p.err = p.load()
+	})
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if creds, ok := p.credentials[host]; ok {
+		return &creds, nil
+	}
+
+	return nil, nil
+}
+
+func (p *credentialsFileProvider) load() (map[string]Credentials, error) {
+	data, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read credentials file %s: %s", p.path, err)
+	}
+
+	var rawCredentials map[string]Credentials
+	if err := yaml.UnmarshalStrict(data, &rawCredentials); err != nil {
+		return nil, fmt.Errorf("unable to parse credentials file %s: %s", p.path, err)
+	}
+
+	res := map[string]Credentials{}
+	for host, creds := range rawCredentials {
+		registry, err := name.NewRegistry(host, name.WeakValidation)
+		if err != nil {
+			return nil, fmt.Errorf("bad registry %q in credentials file %s: %s", host, p.path, err)
+		}
+
+		res[registry.RegistryStr()] = creds
+	}
+
+	return res, nil
+}
+
+func (p *credentialsFileProvider) String() string {
+	return "credentials file"
+}
+
+var envCredentialsHostRegexp = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// envCredentialProvider gets credentials from $WERF_REGISTRY_AUTH_<HOST>_{USERNAME,PASSWORD,REGISTRY_TOKEN,TOKEN},
+// where HOST is the uppercase registry host with other than letters and digits replaced by underscore (e.g. REGISTRY_EXAMPLE_COM_5000).
+type envCredentialProvider struct{}
+
+func envCredentialsPrefix(host string) string {
+	return fmt.Sprintf("WERF_REGISTRY_AUTH_%s_", envCredentialsHostRegexp.ReplaceAllString(strings.ToUpper(host), "_"))
+}
+
+func (p envCredentialProvider) Credentials(_ context.Context, host string) (*Credentials, error) {
+	prefix := envCredentialsPrefix(host)
+	return &Credentials{
+		Username:      os.Getenv(prefix + "USERNAME"),
+		Password:      os.Getenv(prefix + "PASSWORD"),
+		RegistryToken: os.Getenv(prefix + "REGISTRY_TOKEN"),
+		Token:         os.Getenv(prefix + "TOKEN"),
+	}, nil
+}
+
+func (p envCredentialProvider) String() string {
+	return "environment"
+}
+
+// dockerConfigCredentialProvider gets credentials from the docker config including credsStore and credHelpers.
+type dockerConfigCredentialProvider struct{}
+
+func (p dockerConfigCredentialProvider) Credentials(_ context.Context, host string) (*Credentials, error) {
+	cf, err := config.Load(os.Getenv("DOCKER_CONFIG"))
+	if err != nil {
+		return nil, err
+	}
+
+	key := host
+	if key == name.DefaultRegistry {
+		key = authn.DefaultAuthKey
+	}
+
+	cfg, err := cf.GetAuthConfig(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{
+		Username:      cfg.Username,
+		Password:      cfg.Password,
+		RegistryToken: cfg.RegistryToken,
+		IdentityToken: cfg.IdentityToken,
+	}, nil
+}
+
+func (p dockerConfigCredentialProvider) String() string {
+	return "docker config"
+}
+
+// awsEcrCredentialProvider exchanges AWS credentials for the ECR authorization token.
+type awsEcrCredentialProvider struct {
+	cache      map[string]awsEcrCachedCredentials
+	cacheMutex sync.Mutex
+}
+
+type awsEcrCachedCredentials struct {
+	credentials Credentials
+	expiresAt   time.Time
+}
+
+func newAwsEcrCredentialProvider() *awsEcrCredentialProvider {
+	return &awsEcrCredentialProvider{cache: map[string]awsEcrCachedCredentials{}}
+}
+
+func (p *awsEcrCredentialProvider) Credentials(_ context.Context, host string) (*Credentials, error) {
+	matches := awsEcrPatternRegexp.FindStringSubmatch(host)
+	if matches == nil {
+		return nil, nil
+	}
+	registryId, region := matches[1], matches[3]
+
+	p.cacheMutex.Lock()
+	defer p.cacheMutex.Unlock()
+
+	if cached, ok := p.cache[host]; ok && time.Now().Before(cached.expiresAt) {
+		return &cached.credentials, nil
+	}
+
+	mySession, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	service := ecr.New(mySession, aws.NewConfig().WithRegion(region))
+	output, err := service.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{RegistryIds: []*string{&registryId}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.AuthorizationData) == 0 || output.AuthorizationData[0].AuthorizationToken == nil {
+		return nil, fmt.Errorf("no authorization data returned")
+	}
+	authorizationData := output.AuthorizationData[0]
+
+	decodedToken, err := base64.StdEncoding.DecodeString(*authorizationData.AuthorizationToken)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode authorization token: %s", err)
+	}
+
+	parts := strings.SplitN(string(decodedToken), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("unexpected authorization token format")
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	if authorizationData.ExpiresAt != nil {
+		// renew the token a bit earlier to avoid expiration during long operations
+		expiresAt = authorizationData.ExpiresAt.Add(-5 * time.Minute)
+	}
+
+	cached := awsEcrCachedCredentials{
+		credentials: Credentials{Username: parts[0], Password: parts[1]},
+		expiresAt:   expiresAt,
+	}
+	p.cache[host] = cached
+
+	return &cached.credentials, nil
+}
+
+func (p *awsEcrCredentialProvider) String() string {
+	return "ECR token exchange"
+}
+
+// gcrCredentialProvider exchanges gcloud or application default credentials for the GCR access token.
+type gcrCredentialProvider struct{}
+
+func (p gcrCredentialProvider) Credentials(_ context.Context, host string) (*Credentials, error) {
+	if implementation, err := detectImplementation(host); err != nil || implementation != GcrImplementationName {
+		return nil, nil
+	}
+
+	registry, err := name.NewRegistry(host, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator, err := google.Keychain.Resolve(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	authConfig, err := authenticator.Authorization()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{
+		Username:      authConfig.Username,
+		Password:      authConfig.Password,
+		RegistryToken: authConfig.RegistryToken,
+		IdentityToken: authConfig.IdentityToken,
+	}, nil
+}
+
+func (p gcrCredentialProvider) String() string {
+	return "GCR token exchange"
+}
+
+// resolveCredentials returns the explicitly specified credentials (e.g. with implementation options) or the credentials from the provider.
+func resolveCredentials(ctx context.Context, host string, explicit Credentials) (Credentials, error) {
+	if !explicit.IsEmpty() {
+		return explicit, nil
+	}
+
+	creds, err := credentialProvider.Credentials(ctx, host)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	if creds == nil {
+		return Credentials{}, nil
+	}
+
+	return *creds, nil
+}
+
+// credentialProviderKeychain resolves Docker Registry API credentials with the credential provider.
+type credentialProviderKeychain struct{}
+
+func (k credentialProviderKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	creds, err := resolveCredentials(context.Background(), target.RegistryStr(), Credentials{})
+	if err != nil {
+		return nil, err
+	}
+
+	if !creds.hasRegistryAuth() {
+		return authn.Anonymous, nil
+	}
+
+	return authn.FromConfig(authn.AuthConfig{
+		Username:      creds.Username,
+		Password:      creds.Password,
+		RegistryToken: creds.RegistryToken,
+		IdentityToken: creds.IdentityToken,
+	}), nil
+}
@@ -0,0 +1,70 @@
+package docker_registry
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type staticCredentialProvider map[string]Credentials
+
+func (p staticCredentialProvider) Credentials(_ context.Context, host string) (*Credentials, error) {
+	if creds, ok := p[host]; ok {
+		return &creds, nil
+	}
+
+	return nil, nil
+}
+
+func (p staticCredentialProvider) String() string {
+	return "static"
+}
+
+var _ = DescribeTable("env credentials prefix", func(host, expectedPrefix string) {
+	Ω(envCredentialsPrefix(host)).Should(Equal(expectedPrefix))
+},
+	Entry("hostname", "registry.example.com", "WERF_REGISTRY_AUTH_REGISTRY_EXAMPLE_COM_"),
+	Entry("hostname with port", "registry.example.com:5000", "WERF_REGISTRY_AUTH_REGISTRY_EXAMPLE_COM_5000_"),
+	Entry("docker hub", "index.docker.io", "WERF_REGISTRY_AUTH_INDEX_DOCKER_IO_"),
+)
+
+var _ = Describe("default credential provider", func() {
+	It("should take registry credentials and native API token from the first providers that have them", func() {
+		provider := &defaultCredentialProvider{
+			providers: []CredentialProvider{
+				staticCredentialProvider{"quay.io": {Token: "api-token"}},
+				staticCredentialProvider{"quay.io": {Username: "robot", Password: "secret"}},
+				staticCredentialProvider{"quay.io": {Username: "other", Password: "other", Token: "other"}},
+			},
+		}
+
+		creds, err := provider.Credentials(context.Background(), "quay.io")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*creds).Should(Equal(Credentials{Username: "robot", Password: "secret", Token: "api-token"}))
+	})
+
+	It("should use token exchange only if there are no registry credentials", func() {
+		provider := &defaultCredentialProvider{
+			providers:              []CredentialProvider{staticCredentialProvider{"gcr.io": {Username: "user", Password: "pass"}}},
+			tokenExchangeProviders: []CredentialProvider{staticCredentialProvider{"gcr.io": {Username: "oauth2accesstoken", Password: "token"}, "eu.gcr.io": {Username: "oauth2accesstoken", Password: "token"}}},
+		}
+
+		creds, err := provider.Credentials(context.Background(), "gcr.io")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(creds.Username).Should(Equal("user"))
+
+		creds, err = provider.Credentials(context.Background(), "eu.gcr.io")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(creds.Username).Should(Equal("oauth2accesstoken"))
+	})
+
+	It("should return nil if there are no credentials", func() {
+		provider := &defaultCredentialProvider{providers: []CredentialProvider{staticCredentialProvider{}}}
+
+		creds, err := provider.Credentials(context.Background(), "registry.example.com")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(creds).Should(BeNil())
+	})
+})
@@ -32,9 +32,9 @@ func (r *defaultImplementation) DeleteRepo(_ context.Context, _ string) error {
 	return fmt.Errorf("method is not implemented")
 }
 
-func (r *defaultImplementation) DeleteRepoImage(_ context.Context, repoImage *image.Info) error {
+func (r *defaultImplementation) DeleteRepoImage(ctx context.Context, repoImage *image.Info) error {
 	reference := strings.Join([]string{repoImage.Repository, repoImage.RepoDigest}, "@")
-	return r.api.deleteImageByReference(reference)
+	return r.api.deleteImageByReference(ctx, reference)
 }
 
 func (r *defaultImplementation) String() string {
@@ -22,18 +22,13 @@ var dockerHubPatterns = []string{"^index\\.docker\\.io", "^registry\\.hub\\.dock
 type dockerHub struct {
 	*defaultImplementation
 	dockerHubApi
-	dockerHubCredentials
+	credentials Credentials
+	token       string
 }
 
 type dockerHubOptions struct {
 	defaultImplementationOptions
-	dockerHubCredentials
-}
-
-type dockerHubCredentials struct {
-	username string
-	password string
-	token    string
+	credentials Credentials
 }
 
 func newDockerHub(options dockerHubOptions) (*dockerHub, error) {
@@ -45,7 +40,7 @@ func newDockerHub(options dockerHubOptions) (*dockerHub, error) {
 	dockerHub := &dockerHub{
 		defaultImplementation: d,
 		dockerHubApi:          newDockerHubApi(),
-		dockerHubCredentials:  options.dockerHubCredentials,
+		credentials:           options.credentials,
 	}
 
 	return dockerHub, nil
@@ -110,24 +105,36 @@ func (r *dockerHub) deleteRepo(ctx context.Context, reference string) error {
 }
 
 func (r *dockerHub) getToken(ctx context.Context) (string, error) {
-	if r.dockerHubCredentials.token == "" {
-		token, resp, err := r.dockerHubApi.getToken(ctx, r.dockerHubCredentials.username, r.dockerHubCredentials.password)
-		if resp != nil {
-			if resp.StatusCode == http.StatusUnauthorized {
-				return "", DockerHubUnauthorizedError{error: err}
-			} else if resp.StatusCode == http.StatusNotFound {
-				return "", DockerHubNotFoundError{error: err}
-			}
-		}
+	if r.token != "" {
+		return r.token, nil
+	}
 
-		if err != nil {
-			return "", err
+	creds, err := resolveCredentials(ctx, name.DefaultRegistry, r.credentials)
+	if err != nil {
+		return "", err
+	}
+
+	if creds.Token != "" {
+		r.token = creds.Token
+		return r.token, nil
+	}
+
+	token, resp, err := r.dockerHubApi.getToken(ctx, creds.Username, creds.Password)
+	if resp != nil {
+		if resp.StatusCode == http.StatusUnauthorized {
+			return "", DockerHubUnauthorizedError{error: err}
+		} else if resp.StatusCode == http.StatusNotFound {
+			return "", DockerHubNotFoundError{error: err}
 		}
+	}
 
-		r.dockerHubCredentials.token = token
+	if err != nil {
+		return "", err
 	}
 
-	return r.dockerHubCredentials.token, nil
+	r.token = token
+
+	return r.token, nil
 }
 
 func (r *dockerHub) String() string {
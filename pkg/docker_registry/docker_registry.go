@@ -3,11 +3,13 @@ package docker_registry
 import (
 	"context"
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	"github.com/werf/werf/pkg/image"
 )
@@ -17,10 +19,15 @@ type DockerRegistry interface {
 	DeleteRepo(ctx context.Context, reference string) error
 	Tags(ctx context.Context, reference string) ([]string, error)
 	GetRepoImage(ctx context.Context, reference string) (*image.Info, error)
+	GetRepoImageManifest(ctx context.Context, reference string) (*v1.Manifest, error)
+	GetRepoImageLayer(ctx context.Context, reference, digest string) (io.ReadCloser, error)
 	TryGetRepoImage(ctx context.Context, reference string) (*image.Info, error)
 	IsRepoImageExists(ctx context.Context, reference string) (bool, error)
 	DeleteRepoImage(ctx context.Context, repoImage *image.Info) error
 	PushImage(ctx context.Context, reference string, opts *PushImageOptions) error
+	CopyImage(ctx context.Context, sourceReference, destinationReference string) error
+	CopyImageWithAnnotations(ctx context.Context, sourceReference, destinationReference string, annotations map[string]string) error
+	PushDockerImage(ctx context.Context, reference string, compression LayerCompression) error
 
 	String() string
 }
@@ -32,15 +39,30 @@ type PushImageOptions struct {
 type DockerRegistryOptions struct {
 	InsecureRegistry      bool
 	SkipTlsVerifyRegistry bool
+	ArtifactoryUsername   string
+	ArtifactoryPassword   string
 	DockerHubToken        string
 	DockerHubUsername     string
 	DockerHubPassword     string
 	GitHubToken           string
 	HarborUsername        string
 	HarborPassword        string
+	NexusUsername         string
+	NexusPassword         string
+	NexusRepository       string
 	QuayToken             string
 }
 
+func (o *DockerRegistryOptions) artifactoryOptions() artifactoryOptions {
+	return artifactoryOptions{
+		defaultImplementationOptions: o.defaultOptions(),
+		credentials: Credentials{
+			Username: o.ArtifactoryUsername,
+			Password: o.ArtifactoryPassword,
+		},
+	}
+}
+
 func (o *DockerRegistryOptions) awsEcrOptions() awsEcrOptions {
 	return awsEcrOptions{
 		defaultImplementationOptions: o.defaultOptions(),
@@ -56,10 +78,10 @@ func (o *DockerRegistryOptions) azureAcrOptions() azureCrOptions {
 func (o *DockerRegistryOptions) dockerHubOptions() dockerHubOptions {
 	return dockerHubOptions{
 		defaultImplementationOptions: o.defaultOptions(),
-		dockerHubCredentials: dockerHubCredentials{
-			token:    o.DockerHubToken,
-			username: o.DockerHubUsername,
-			password: o.DockerHubPassword,
+		credentials: Credentials{
+			Token:    o.DockerHubToken,
+			Username: o.DockerHubUsername,
+			Password: o.DockerHubPassword,
 		},
 	}
 }
@@ -73,8 +95,8 @@ func (o *DockerRegistryOptions) gcrOptions() GcrOptions {
 func (o *DockerRegistryOptions) gitHubPackagesOptions() gitHubPackagesOptions {
 	return gitHubPackagesOptions{
 		defaultImplementationOptions: o.defaultOptions(),
-		gitHubCredentials: gitHubCredentials{
-			token: o.GitHubToken,
+		credentials: Credentials{
+			Token: o.GitHubToken,
 		},
 	}
 }
@@ -88,18 +110,29 @@ func (o *DockerRegistryOptions) gitLabRegistryOptions() gitLabRegistryOptions {
 func (o *DockerRegistryOptions) harborOptions() harborOptions {
 	return harborOptions{
 		defaultImplementationOptions: o.defaultOptions(),
-		harborCredentials: harborCredentials{
-			username: o.HarborUsername,
-			password: o.HarborPassword,
+		credentials: Credentials{
+			Username: o.HarborUsername,
+			Password: o.HarborPassword,
+		},
+	}
+}
+
+func (o *DockerRegistryOptions) nexusOptions() nexusOptions {
+	return nexusOptions{
+		defaultImplementationOptions: o.defaultOptions(),
+		credentials: Credentials{
+			Username: o.NexusUsername,
+			Password: o.NexusPassword,
 		},
+		repository: o.NexusRepository,
 	}
 }
 
 func (o *DockerRegistryOptions) quayOptions() quayOptions {
 	return quayOptions{
 		defaultImplementationOptions: o.defaultOptions(),
-		quayCredentials: quayCredentials{
-			token: o.QuayToken,
+		credentials: Credentials{
+			Token: o.QuayToken,
 		},
 	}
 }
@@ -113,6 +146,8 @@ func (o *DockerRegistryOptions) defaultOptions() defaultImplementationOptions {
 
 func NewDockerRegistry(repositoryAddress string, implementation string, options DockerRegistryOptions) (DockerRegistry, error) {
 	switch implementation {
+	case ArtifactoryImplementationName:
+		return newArtifactory(options.artifactoryOptions())
 	case AwsEcrImplementationName:
 		return newAwsEcr(options.awsEcrOptions())
 	case AzureCrImplementationName:
@@ -127,6 +162,8 @@ func NewDockerRegistry(repositoryAddress string, implementation string, options
 		return newGitLabRegistry(options.gitLabRegistryOptions())
 	case HarborImplementationName:
 		return newHarbor(options.harborOptions())
+	case NexusImplementationName:
+		return newNexus(options.nexusOptions())
 	case QuayImplementationName:
 		return newQuay(options.quayOptions())
 	case DefaultImplementationName:
@@ -176,6 +213,10 @@ func detectImplementation(accountOrRepositoryAddress string) (string, error) {
 		name     string
 		patterns []string
 	}{
+		{
+			name:     ArtifactoryImplementationName,
+			patterns: artifactoryPatterns,
+		},
 		{
 			name:     AwsEcrImplementationName,
 			patterns: awsEcrPatterns,
@@ -204,6 +245,10 @@ func detectImplementation(accountOrRepositoryAddress string) (string, error) {
 			name:     HarborImplementationName,
 			patterns: harborPatterns,
 		},
+		{
+			name:     NexusImplementationName,
+			patterns: nexusPatterns,
+		},
 		{
 			name:     QuayImplementationName,
 			patterns: quayPatterns,
@@ -226,6 +271,7 @@ func detectImplementation(accountOrRepositoryAddress string) (string, error) {
 
 func ImplementationList() []string {
 	return []string{
+		ArtifactoryImplementationName,
 		AwsEcrImplementationName,
 		AzureCrImplementationName,
 		DefaultImplementationName,
@@ -234,6 +280,7 @@ func ImplementationList() []string {
 		GitHubPackagesImplementationName,
 		GitLabRegistryImplementationName,
 		HarborImplementationName,
+		NexusImplementationName,
 		QuayImplementationName,
 	}
 }
@@ -21,16 +21,12 @@ var gitHubPackagesPatterns = []string{"^docker\\.pkg\\.github\\.com"}
 type gitHubPackages struct {
 	*defaultImplementation
 	gitHubApi
-	gitHubCredentials
+	credentials Credentials
 }
 
 type gitHubPackagesOptions struct {
 	defaultImplementationOptions
-	gitHubCredentials
-}
-
-type gitHubCredentials struct {
-	token string
+	credentials Credentials
 }
 
 func newGitHubPackages(options gitHubPackagesOptions) (*gitHubPackages, error) {
@@ -42,7 +38,7 @@ func newGitHubPackages(options gitHubPackagesOptions) (*gitHubPackages, error) {
 	gitHub := &gitHubPackages{
 		defaultImplementation: d,
 		gitHubApi:             newGitHubApi(),
-		gitHubCredentials:     options.gitHubCredentials,
+		credentials:           options.credentials,
 	}
 
 	return gitHub, nil
@@ -77,7 +73,12 @@ func (r *gitHubPackages) DeleteRepoImage(ctx context.Context, repoImage *image.I
 		return err
 	}
 
-	err = r.deletePackageVersion(ctx, owner, project, packageName, repoImage.Tag)
+	token, err := r.getToken(ctx, repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	err = r.deletePackageVersion(ctx, owner, project, packageName, repoImage.Tag, token)
 	if err != nil {
 		return err
 	}
@@ -85,7 +86,7 @@ func (r *gitHubPackages) DeleteRepoImage(ctx context.Context, repoImage *image.I
 	return nil
 }
 
-func (r *gitHubPackages) deletePackageVersion(ctx context.Context, owner, project, packageName, packageVersion string) error {
+func (r *gitHubPackages) deletePackageVersion(ctx context.Context, owner, project, packageName, packageVersion, token string) error {
 	processError := func(resp *http.Response, err error) error {
 		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
 			return GitHubPackagesUnauthorizedError{error: err}
@@ -94,12 +95,12 @@ func (r *gitHubPackages) deletePackageVersion(ctx context.Context, owner, projec
 		return err
 	}
 
-	packageVersionId, resp, err := r.gitHubApi.getPackageVersionId(ctx, owner, project, packageName, packageVersion, r.token)
+	packageVersionId, resp, err := r.gitHubApi.getPackageVersionId(ctx, owner, project, packageName, packageVersion, token)
 	if err != nil {
 		return processError(resp, err)
 	}
 
-	if resp, err := r.gitHubApi.deletePackageVersion(ctx, packageVersionId, r.token); err != nil {
+	if resp, err := r.gitHubApi.deletePackageVersion(ctx, packageVersionId, token); err != nil {
 		return processError(resp, err)
 	}
 
@@ -116,9 +117,14 @@ func (r *gitHubPackages) deleteRepo(ctx context.Context, reference string) error
 		return err
 	}
 
+	token, err := r.getToken(ctx, reference)
+	if err != nil {
+		return err
+	}
+
 	tags, err := r.Tags(ctx, reference)
 	for _, tag := range tags {
-		if err := r.deletePackageVersion(ctx, owner, project, packageName, tag); err != nil {
+		if err := r.deletePackageVersion(ctx, owner, project, packageName, tag, token); err != nil {
 			return err
 		}
 	}
@@ -126,6 +132,25 @@ func (r *gitHubPackages) deleteRepo(ctx context.Context, reference string) error
 	return nil
 }
 
+// getToken returns the GitHub token, the docker login password is the token as well.
+func (r *gitHubPackages) getToken(ctx context.Context, reference string) (string, error) {
+	parsedReference, err := name.NewRepository(reference)
+	if err != nil {
+		return "", err
+	}
+
+	creds, err := resolveCredentials(ctx, parsedReference.RegistryStr(), r.credentials)
+	if err != nil {
+		return "", err
+	}
+
+	if creds.Token != "" {
+		return creds.Token, nil
+	}
+
+	return creds.Password, nil
+}
+
 func (r *gitHubPackages) String() string {
 	return GitHubPackagesImplementationName
 }
@@ -19,17 +19,12 @@ var harborPatterns = []string{"^harbor\\..*"}
 type harbor struct {
 	*defaultImplementation
 	harborApi
-	harborCredentials
+	credentials Credentials
 }
 
 type harborOptions struct {
 	defaultImplementationOptions
-	harborCredentials
-}
-
-type harborCredentials struct {
-	username string
-	password string
+	credentials Credentials
 }
 
 func newHarbor(options harborOptions) (*harbor, error) {
@@ -40,7 +35,7 @@ func newHarbor(options harborOptions) (*harbor, error) {
 
 	harbor := &harbor{
 		defaultImplementation: d,
-		harborCredentials:     options.harborCredentials,
+		credentials:           options.credentials,
 		harborApi:             newHarborApi(),
 	}
 
@@ -90,7 +85,12 @@ func (r *harbor) deleteRepo(ctx context.Context, reference string) error {
 		return err
 	}
 
-	resp, err := r.harborApi.DeleteRepository(ctx, hostname, repository, r.harborCredentials.username, r.harborCredentials.password)
+	creds, err := resolveCredentials(ctx, hostname, r.credentials)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.harborApi.DeleteRepository(ctx, hostname, repository, creds.Username, creds.Password)
 	if resp != nil {
 		if resp.StatusCode == http.StatusNotFound {
 			return HarborNotFoundError{error: err}
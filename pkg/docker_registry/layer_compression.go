@@ -0,0 +1,368 @@
+package docker_registry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/containerd/stargz-snapshotter/estargz"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+type LayerCompression string
+
+const (
+	GzipLayerCompression    LayerCompression = "gzip"
+	ZstdLayerCompression    LayerCompression = "zstd"
+	EstargzLayerCompression LayerCompression = "estargz"
+
+	OCIZstdLayerMediaType types.MediaType = "application/vnd.oci.image.layer.v1.tar+zstd"
+)
+
+func ParseLayerCompression(compression string) (LayerCompression, error) {
+	switch LayerCompression(compression) {
+	case "":
+		return GzipLayerCompression, nil
+	case GzipLayerCompression, ZstdLayerCompression, EstargzLayerCompression:
+		return LayerCompression(compression), nil
+	default:
+		return "", fmt.Errorf("unsupported layer compression %q: expected %s, %s or %s", compression, GzipLayerCompression, ZstdLayerCompression, EstargzLayerCompression)
+	}
+}
+
+// LayerCompressionUnsupportedError means that the registry rejected OCI media types and the image should be pushed with gzip layers
+type LayerCompressionUnsupportedError struct {
+	error
+}
+
+func IsLayerCompressionUnsupportedError(err error) bool {
+	_, ok := err.(LayerCompressionUnsupportedError)
+	return ok
+}
+
+// estargzBuildError means that the estargz blob cannot be built and the image should be pushed with gzip layers
+type estargzBuildError struct {
+	error
+}
+
+func isEstargzBuildError(err error) bool {
+	_, ok := err.(estargzBuildError)
+	return ok
+}
+
+// estargzBuildFunc is replaced in tests
+var estargzBuildFunc = func(tarReader *io.SectionReader) (*estargz.Blob, error) {
+	return estargz.Build(tarReader)
+}
+
+// recompressImageLayers builds OCI image with the same config and the layers recompressed with the specified compression.
+// Compressed layers are taken from the cache or compressed and stored into the cache.
+func recompressImageLayers(img v1.Image, compression LayerCompression, cache *CompressedLayersCache) (v1.Image, error) {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image config: %s", err)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image layers: %s", err)
+	}
+
+	var addendums []mutate.Addendum
+	for _, layer := range layers {
+		newLayer, err := cache.GetOrCreateLayer(layer, compression)
+		if isEstargzBuildError(err) {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to compress layer with %s: %s", compression, err)
+		}
+
+		addendums = append(addendums, mutate.Addendum{
+			Layer:       newLayer,
+			Annotations: newLayer.annotations,
+			MediaType:   newLayer.mediaType,
+		})
+	}
+
+	newImg, err := mutate.Append(mutate.MediaType(empty.Image, types.OCIManifestSchema1), addendums...)
+	if err != nil {
+		return nil, err
+	}
+
+	newImgCfg, err := newImg.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// eStargz changes the uncompressed layer content, so the diff ids are taken from the new layers
+	cfg = cfg.DeepCopy()
+	cfg.RootFS = newImgCfg.RootFS
+
+	newImg, err = mutate.ConfigFile(newImg, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ociImage{Image: newImg}, nil
+}
+
+func compressLayer(layer v1.Layer, compression LayerCompression, dir string) (*compressedLayer, error) {
+	switch compression {
+	case ZstdLayerCompression:
+		return newZstdLayer(layer, dir)
+	case EstargzLayerCompression:
+		return newEstargzLayer(layer, dir)
+	default:
+		return nil, fmt.Errorf("unexpected layer compression %q", compression)
+	}
+}
+
+func newZstdLayer(layer v1.Layer, tmpDir string) (*compressedLayer, error) {
+	diffID, err := layer.DiffID()
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return writeCompressedLayer(tmpDir, "zst", func(w io.Writer) error {
+		enc, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(enc, rc); err != nil {
+			enc.Close()
+			return err
+		}
+
+		return enc.Close()
+	}, newCompressedLayer(ZstdLayerCompression, diffID))
+}
+
+func newEstargzLayer(layer v1.Layer, tmpDir string) (*compressedLayer, error) {
+	tarFile, err := ioutil.TempFile(tmpDir, "layer-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tarFile.Name())
+	defer tarFile.Close()
+
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	tarSize, err := io.Copy(tarFile, rc)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := buildEstargz(io.NewSectionReader(tarFile, 0, tarSize))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := writeCompressedLayer(tmpDir, "tar.gz", func(w io.Writer) error {
+		_, err := io.Copy(w, blob)
+		return err
+	}, newCompressedLayer(EstargzLayerCompression, v1.Hash{}))
+
+	// the blob diff id is only available after close
+	closeErr := blob.Close()
+	if err != nil {
+		return nil, err
+	} else if closeErr != nil {
+		return nil, closeErr
+	}
+
+	if res.diffID, err = v1.NewHash(blob.DiffID().String()); err != nil {
+		return nil, err
+	}
+
+	res.annotations = map[string]string{
+		estargz.TOCJSONDigestAnnotation:         blob.TOCDigest().String(),
+		estargz.StoreUncompressedSizeAnnotation: strconv.FormatInt(tarSize, 10),
+	}
+
+	return res, nil
+}
+
+// buildEstargz converts the estargz footer size check panic into the error:
+// the footer is built with the gzip stored block, which size depends on the go toolchain version
+func buildEstargz(tarReader *io.SectionReader) (blob *estargz.Blob, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = estargzBuildError{fmt.Errorf("unable to build estargz blob: %v", r)}
+		}
+	}()
+
+	if blob, err = estargzBuildFunc(tarReader); err != nil {
+		return nil, estargzBuildError{fmt.Errorf("unable to build estargz blob: %s", err)}
+	}
+
+	return blob, nil
+}
+
+func newZstdReadCloser(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.IOReadCloser(), nil
+}
+
+func writeCompressedLayer(tmpDir, ext string, compress func(w io.Writer) error, layer *compressedLayer) (*compressedLayer, error) {
+	f, err := ioutil.TempFile(tmpDir, "layer-*."+ext)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if err := compress(io.MultiWriter(f, hasher)); err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	layer.path = f.Name()
+	layer.size = stat.Size()
+	layer.digest = v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(hasher.Sum(nil))}
+
+	return layer, nil
+}
+
+func newCompressedLayer(compression LayerCompression, diffID v1.Hash) *compressedLayer {
+	layer := &compressedLayer{diffID: diffID}
+
+	switch compression {
+	case ZstdLayerCompression:
+		layer.mediaType = OCIZstdLayerMediaType
+		layer.decompressor = newZstdReadCloser
+	default:
+		layer.mediaType = types.OCILayer
+		layer.decompressor = func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	}
+
+	return layer
+}
+
+// compressedLayer is the layer stored in the file
+type compressedLayer struct {
+	path         string
+	digest       v1.Hash
+	diffID       v1.Hash
+	size         int64
+	mediaType    types.MediaType
+	annotations  map[string]string
+	decompressor func(r io.Reader) (io.ReadCloser, error)
+}
+
+func (l *compressedLayer) Digest() (v1.Hash, error) {
+	return l.digest, nil
+}
+
+func (l *compressedLayer) DiffID() (v1.Hash, error) {
+	return l.diffID, nil
+}
+
+func (l *compressedLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+func (l *compressedLayer) Uncompressed() (io.ReadCloser, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := l.decompressor(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return readCloser{Reader: rc, close: func() error {
+		rc.Close()
+		return f.Close()
+	}}, nil
+}
+
+func (l *compressedLayer) Size() (int64, error) {
+	return l.size, nil
+}
+
+func (l *compressedLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (rc readCloser) Close() error {
+	return rc.close()
+}
+
+// ociImage sets OCI config media type, which cannot be changed with mutate package
+type ociImage struct {
+	v1.Image
+}
+
+func (img *ociImage) MediaType() (types.MediaType, error) {
+	return types.OCIManifestSchema1, nil
+}
+
+func (img *ociImage) Manifest() (*v1.Manifest, error) {
+	manifest, err := img.Image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest = manifest.DeepCopy()
+	manifest.MediaType = types.OCIManifestSchema1
+	manifest.Config.MediaType = types.OCIConfigJSON
+
+	return manifest, nil
+}
+
+func (img *ociImage) RawManifest() ([]byte, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(manifest)
+}
+
+func (img *ociImage) Digest() (v1.Hash, error) {
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	digest, _, err := v1.SHA256(bytes.NewReader(rawManifest))
+	return digest, err
+}
@@ -0,0 +1,274 @@
+package docker_registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containerd/stargz-snapshotter/estargz"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+var _ = Describe("layer compression", func() {
+	var tmpDir string
+	var cache *CompressedLayersCache
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "werf-layer-compression-test")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werf.Init(filepath.Join(tmpDir, "tmp"), filepath.Join(tmpDir, "home"))).Should(Succeed())
+
+		cache = NewCompressedLayersCache(filepath.Join(tmpDir, "compressed_layers"))
+	})
+
+	AfterEach(func() {
+		Ω(os.RemoveAll(tmpDir)).Should(Succeed())
+	})
+
+	It("should push OCI image with zstd layers", func() {
+		server := httptest.NewServer(registry.New())
+		defer server.Close()
+
+		img, err := random.Image(1024, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		compressedImg, err := recompressImageLayers(img, ZstdLayerCompression, cache)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ref, err := name.ParseReference(strings.TrimPrefix(server.URL, "http://")+"/repo:stage", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(ref, compressedImg)).Should(Succeed())
+
+		pushedImg, err := remote.Image(ref)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		manifest, err := pushedImg.Manifest()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(manifest.MediaType).Should(Equal(types.OCIManifestSchema1))
+		Ω(manifest.Config.MediaType).Should(Equal(types.OCIConfigJSON))
+		Ω(manifest.Layers).Should(HaveLen(2))
+
+		cfg, err := pushedImg.ConfigFile()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		srcCfg, err := img.ConfigFile()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.RootFS.DiffIDs).Should(Equal(srcCfg.RootFS.DiffIDs))
+
+		for i, layer := range manifest.Layers {
+			Ω(layer.MediaType).Should(Equal(OCIZstdLayerMediaType))
+
+			compressedLayer, err := compressedImg.LayerByDigest(layer.Digest)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			diffID, err := compressedLayer.DiffID()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.RootFS.DiffIDs[i]).Should(Equal(diffID))
+		}
+	})
+
+	It("should push image with estargz layers and new diff ids", func() {
+		if _, err := buildEstargz(io.NewSectionReader(bytes.NewReader(make([]byte, 1024)), 0, 1024)); err != nil {
+			Fail(fmt.Sprintf("estargz is not supported with the current go toolchain, the layers are pushed with gzip: %s", err))
+		}
+
+		server := httptest.NewServer(registry.New())
+		defer server.Close()
+
+		img, err := random.Image(1024, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ref, err := name.ParseReference(strings.TrimPrefix(server.URL, "http://")+"/repo:stage", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		Ω(api.pushCompressedImage(context.Background(), ref, img, EstargzLayerCompression, cache)).Should(Succeed())
+
+		pushedImg, err := remote.Image(ref)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		manifest, err := pushedImg.Manifest()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(manifest.MediaType).Should(Equal(types.OCIManifestSchema1))
+		Ω(manifest.Layers).Should(HaveLen(2))
+
+		cfg, err := pushedImg.ConfigFile()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		srcCfg, err := img.ConfigFile()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		for i, layer := range manifest.Layers {
+			Ω(layer.MediaType).Should(Equal(types.OCILayer))
+			Ω(layer.Annotations).Should(HaveKey(estargz.TOCJSONDigestAnnotation))
+			Ω(cfg.RootFS.DiffIDs[i]).ShouldNot(Equal(srcCfg.RootFS.DiffIDs[i]))
+		}
+	})
+
+	It("should push image with gzip layers if estargz blob cannot be built", func() {
+		savedEstargzBuildFunc := estargzBuildFunc
+		defer func() { estargzBuildFunc = savedEstargzBuildFunc }()
+		estargzBuildFunc = func(_ *io.SectionReader) (*estargz.Blob, error) {
+			panic("footer buffer = 48, not 51")
+		}
+
+		server := httptest.NewServer(registry.New())
+		defer server.Close()
+
+		img, err := random.Image(1024, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ref, err := name.ParseReference(strings.TrimPrefix(server.URL, "http://")+"/repo:stage", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		Ω(api.pushCompressedImage(context.Background(), ref, img, EstargzLayerCompression, cache)).Should(Succeed())
+
+		pushedImg, err := remote.Image(ref)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		manifest, err := pushedImg.Manifest()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(manifest.Layers).Should(HaveLen(2))
+
+		cfg, err := pushedImg.ConfigFile()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		srcCfg, err := img.ConfigFile()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.RootFS.DiffIDs).Should(Equal(srcCfg.RootFS.DiffIDs))
+
+		for _, layer := range manifest.Layers {
+			Ω(layer.Annotations).ShouldNot(HaveKey(estargz.TOCJSONDigestAnnotation))
+		}
+	})
+
+	It("should reuse cached compressed layers", func() {
+		img, err := random.Image(1024, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		compressedImg, err := recompressImageLayers(img, ZstdLayerCompression, cache)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		layers, err := img.Layers()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var unreadableLayers []v1.Layer
+		for _, layer := range layers {
+			unreadableLayers = append(unreadableLayers, unreadableLayer{Layer: layer})
+		}
+
+		unreadableImg, err := mutate.AppendLayers(empty.Image, unreadableLayers...)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		cachedImg, err := recompressImageLayers(unreadableImg, ZstdLayerCompression, cache)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		cachedLayers, err := cachedImg.Layers()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cachedLayers).Should(HaveLen(2))
+
+		compressedLayers, err := compressedImg.Layers()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		for i := range cachedLayers {
+			cachedLayerDigest, err := cachedLayers[i].Digest()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			compressedLayerDigest, err := compressedLayers[i].Digest()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(cachedLayerDigest).Should(Equal(compressedLayerDigest))
+		}
+
+		_, err = recompressImageLayers(unreadableImg, EstargzLayerCompression, cache)
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("should return LayerCompressionUnsupportedError if the registry rejects OCI media types", func() {
+		registryHandler := registry.New()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/manifests/") && r.Header.Get("Content-Type") == string(types.OCIManifestSchema1) {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+
+			registryHandler.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		img, err := random.Image(1024, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ref, err := name.ParseReference(strings.TrimPrefix(server.URL, "http://")+"/repo:stage", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		api := newAPI(apiOptions{InsecureRegistry: true})
+		err = api.pushCompressedImage(context.Background(), ref, img, ZstdLayerCompression, cache)
+		Ω(IsLayerCompressionUnsupportedError(err)).Should(BeTrue())
+	})
+
+	It("should remove unused compressed layers on GC", func() {
+		img, err := random.Image(1024, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = recompressImageLayers(img, ZstdLayerCompression, cache)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		layers, err := img.Layers()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		unusedDiffID, err := layers[0].DiffID()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		usedDiffID, err := layers[1].DiffID()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		unusedEntryDir := filepath.Join(cache.CacheDir, string(ZstdLayerCompression), unusedDiffID.Hex)
+		usedEntryDir := filepath.Join(cache.CacheDir, string(ZstdLayerCompression), usedDiffID.Hex)
+
+		lastUseTime := time.Now().Add(-CompressedLayersCacheTTL - time.Hour)
+		Ω(os.Chtimes(filepath.Join(unusedEntryDir, compressedLayersCacheRecordFile), lastUseTime, lastUseTime)).Should(Succeed())
+
+		Ω(cache.GC(context.Background(), true)).Should(Succeed())
+		Ω(unusedEntryDir).Should(BeADirectory())
+
+		Ω(cache.GC(context.Background(), false)).Should(Succeed())
+		Ω(unusedEntryDir).ShouldNot(BeAnExistingFile())
+		Ω(usedEntryDir).Should(BeADirectory())
+	})
+})
+
+// unreadableLayer fails if the layer content is read, so the compressed layer could only be taken from the cache
+type unreadableLayer struct {
+	v1.Layer
+}
+
+func (l unreadableLayer) Uncompressed() (io.ReadCloser, error) {
+	return nil, errors.New("unexpected layer read")
+}
+
+func (l unreadableLayer) Compressed() (io.ReadCloser, error) {
+	return nil, errors.New("unexpected layer read")
+}
@@ -3,6 +3,7 @@ package docker_registry
 import (
 	"context"
 	"io/ioutil"
+	"net/http"
 	"os"
 
 	"github.com/google/go-containerregistry/pkg/logs"
@@ -12,7 +13,10 @@ import (
 
 var generic *api
 
-func Init(ctx context.Context, insecureRegistry, skipTlsVerifyRegistry bool) error {
+// defaultHttpTransport is saved before the go-containerregistry hack that substitutes http.DefaultTransport.
+var defaultHttpTransport = http.DefaultTransport
+
+func Init(ctx context.Context, insecureRegistry, skipTlsVerifyRegistry bool, requestsPolicyOptions RequestsPolicyOptions, credentialProviderOptions CredentialProviderOptions) error {
 	if logboek.Context(ctx).Debug().IsAccepted() {
 		logs.Progress.SetOutput(logboek.Context(ctx).ProxyOutStream())
 		logs.Warn.SetOutput(logboek.Context(ctx).ProxyErrStream())
@@ -28,6 +32,9 @@ func Init(ctx context.Context, insecureRegistry, skipTlsVerifyRegistry bool) err
 		logs.Debug.SetOutput(ioutil.Discard)
 	}
 
+	requestsPolicy = newRequestsPolicyState(requestsPolicyOptions)
+	credentialProvider = NewDefaultCredentialProvider(credentialProviderOptions)
+
 	generic = newAPI(apiOptions{
 		InsecureRegistry:      insecureRegistry,
 		SkipTlsVerifyRegistry: skipTlsVerifyRegistry,
@@ -0,0 +1,151 @@
+package docker_registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+const NexusImplementationName = "nexus"
+
+type NexusNotFoundError apiError
+
+type NexusUnauthorizedError apiError
+
+var nexusPatterns = []string{"^nexus\\..*"}
+
+type nexus struct {
+	*defaultImplementation
+	nexusApi
+	credentials Credentials
+	repository  string
+}
+
+type nexusOptions struct {
+	defaultImplementationOptions
+	credentials Credentials
+	repository  string
+}
+
+func newNexus(options nexusOptions) (*nexus, error) {
+	d, err := newDefaultImplementation(options.defaultImplementationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	nexus := &nexus{
+		defaultImplementation: d,
+		nexusApi:              newNexusApi(),
+		credentials:           options.credentials,
+		repository:            options.repository,
+	}
+
+	return nexus, nil
+}
+
+// CreateRepo does nothing: the image namespace is created in the nexus docker repository on the first push.
+func (r *nexus) CreateRepo(_ context.Context, _ string) error {
+	return nil
+}
+
+func (r *nexus) DeleteRepo(ctx context.Context, reference string) error {
+	hostname, imageName, err := r.parseReference(reference)
+	if err != nil {
+		return err
+	}
+
+	creds, err := resolveCredentials(ctx, hostname, r.credentials)
+	if err != nil {
+		return err
+	}
+
+	components, resp, err := r.nexusApi.searchComponents(ctx, hostname, r.repository, imageName, "", creds.Username, creds.Password)
+	if err != nil {
+		return r.processError(resp, err)
+	}
+
+	for _, component := range components {
+		if err := r.deleteComponent(ctx, hostname, component); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteRepoImage removes the component through the Nexus REST API.
+// Nexus does not support the manifest DELETE of the registry API unless the delete is explicitly allowed for the repository.
+func (r *nexus) DeleteRepoImage(ctx context.Context, repoImage *image.Info) error {
+	hostname, imageName, err := r.parseReference(repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	creds, err := resolveCredentials(ctx, hostname, r.credentials)
+	if err != nil {
+		return err
+	}
+
+	components, resp, err := r.nexusApi.searchComponents(ctx, hostname, r.repository, imageName, repoImage.Tag, creds.Username, creds.Password)
+	if err != nil {
+		return r.processError(resp, err)
+	}
+
+	switch {
+	case len(components) == 0:
+		return NexusNotFoundError{error: fmt.Errorf("nexus component %s:%s not found", imageName, repoImage.Tag)}
+	case len(components) > 1 && r.repository == "":
+		var repositories []string
+		for _, component := range components {
+			repositories = append(repositories, component.Repository)
+		}
+
+		return fmt.Errorf("nexus component %s:%s found in several repositories (%s): nexus repository should be specified explicitly", imageName, repoImage.Tag, strings.Join(repositories, ", "))
+	}
+
+	return r.deleteComponent(ctx, hostname, components[0])
+}
+
+func (r *nexus) deleteComponent(ctx context.Context, hostname string, component nexusComponent) error {
+	creds, err := resolveCredentials(ctx, hostname, r.credentials)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.nexusApi.deleteComponent(ctx, hostname, component.Id, creds.Username, creds.Password)
+	if err != nil {
+		return r.processError(resp, err)
+	}
+
+	return nil
+}
+
+func (r *nexus) processError(resp *http.Response, err error) error {
+	if resp != nil {
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return NexusUnauthorizedError{error: err}
+		} else if resp.StatusCode == http.StatusNotFound {
+			return NexusNotFoundError{error: err}
+		}
+	}
+
+	return err
+}
+
+func (r *nexus) String() string {
+	return NexusImplementationName
+}
+
+func (r *nexus) parseReference(reference string) (string, string, error) {
+	parsedReference, err := name.NewRepository(reference)
+	if err != nil {
+		return "", "", err
+	}
+
+	return parsedReference.RegistryStr(), parsedReference.RepositoryStr(), nil
+}
@@ -0,0 +1,114 @@
+package docker_registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"path"
+)
+
+type nexusApi struct{}
+
+type nexusComponent struct {
+	Id         string `json:"id"`
+	Repository string `json:"repository"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+}
+
+func newNexusApi() nexusApi {
+	return nexusApi{}
+}
+
+func (api *nexusApi) searchComponents(ctx context.Context, hostname, repository, imageName, tag, username, password string) ([]nexusComponent, *http.Response, error) {
+	var components []nexusComponent
+	var continuationToken string
+
+	for {
+		u, err := api.url(hostname, "search")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		query := u.Query()
+		query.Set("format", "docker")
+		query.Set("name", imageName)
+		if repository != "" {
+			query.Set("repository", repository)
+		}
+		if tag != "" {
+			query.Set("version", tag)
+		}
+		if continuationToken != "" {
+			query.Set("continuationToken", continuationToken)
+		}
+		u.RawQuery = query.Encode()
+
+		resp, respBody, err := doRequest(ctx, http.MethodGet, u.String(), nil, doRequestOptions{
+			Headers: map[string]string{
+				"Accept": "application/json",
+			},
+			BasicAuth: doRequestBasicAuth{
+				username: username,
+				password: password,
+			},
+			AcceptedCodes: []int{http.StatusOK},
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+
+		respJson := struct {
+			Items             []nexusComponent `json:"items"`
+			ContinuationToken string           `json:"continuationToken"`
+		}{}
+
+		if err := json.Unmarshal(respBody, &respJson); err != nil {
+			return nil, resp, fmt.Errorf("unexpected nexus api response body: %s", string(respBody))
+		}
+
+		for _, item := range respJson.Items {
+			// the search is not strict and can return components with similar names
+			if item.Name == imageName && (tag == "" || item.Version == tag) {
+				components = append(components, item)
+			}
+		}
+
+		if respJson.ContinuationToken == "" {
+			return components, resp, nil
+		}
+
+		continuationToken = respJson.ContinuationToken
+	}
+}
+
+func (api *nexusApi) deleteComponent(ctx context.Context, hostname, id, username, password string) (*http.Response, error) {
+	u, err := api.url(hostname, "components", id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, _, err := doRequest(ctx, http.MethodDelete, u.String(), nil, doRequestOptions{
+		BasicAuth: doRequestBasicAuth{
+			username: username,
+			password: password,
+		},
+		AcceptedCodes: []int{http.StatusOK, http.StatusNoContent},
+	})
+
+	return resp, err
+}
+
+// url returns the Nexus REST API url on the registry host and port.
+func (api *nexusApi) url(hostname string, pathParts ...string) (*neturl.URL, error) {
+	u, err := neturl.Parse("https://" + hostname + "/service/rest/v1")
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = path.Join(append([]string{u.Path}, pathParts...)...)
+
+	return u, nil
+}
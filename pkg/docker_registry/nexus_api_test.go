@@ -0,0 +1,16 @@
+package docker_registry
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("nexus api url", func(hostname, expectedURL string) {
+	api := newNexusApi()
+	u, err := api.url(hostname, "components", "id")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(u.String()).Should(Equal(expectedURL))
+},
+	Entry("without port", "nexus.example.com", "https://nexus.example.com/service/rest/v1/components/id"),
+	Entry("with port", "nexus.example.com:8443", "https://nexus.example.com:8443/service/rest/v1/components/id"),
+)
@@ -19,16 +19,12 @@ var quayPatterns = []string{"^quay\\.io", "^quay\\..*\\.com"}
 type quay struct {
 	*defaultImplementation
 	quayApi
-	quayCredentials
+	credentials Credentials
 }
 
 type quayOptions struct {
 	defaultImplementationOptions
-	quayCredentials
-}
-
-type quayCredentials struct {
-	token string
+	credentials Credentials
 }
 
 func newQuay(options quayOptions) (*quay, error) {
@@ -40,7 +36,7 @@ func newQuay(options quayOptions) (*quay, error) {
 	quay := &quay{
 		defaultImplementation: d,
 		quayApi:               newQuayApi(),
-		quayCredentials:       options.quayCredentials,
+		credentials:           options.credentials,
 	}
 
 	return quay, nil
@@ -56,7 +52,12 @@ func (r *quay) deleteRepo(ctx context.Context, reference string) error {
 		return err
 	}
 
-	resp, err := r.quayApi.DeleteRepository(ctx, hostname, namespace, repository, r.quayCredentials.token)
+	creds, err := resolveCredentials(ctx, hostname, r.credentials)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.quayApi.DeleteRepository(ctx, hostname, namespace, repository, creds.Token)
 	if resp != nil && resp.StatusCode == http.StatusNotFound {
 		return QuayNotFoundError{error: err}
 	}
@@ -0,0 +1,99 @@
+package docker_registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// RegistryMirrors maps canonical registries to mirrors, e.g. docker.io → mirror.internal/dockerhub.
+// The mirrors are used only to pull base images and to get base images info, images keep canonical names everywhere else.
+type RegistryMirrors map[string]string
+
+func NewRegistryMirrors(mirrors map[string]string) (RegistryMirrors, error) {
+	res := RegistryMirrors{}
+	for registry, mirror := range mirrors {
+		if err := res.Add(registry, mirror); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// ParseRegistryMirrors parses mirrors in the REGISTRY=MIRROR format.
+func ParseRegistryMirrors(specs []string) (RegistryMirrors, error) {
+	res := RegistryMirrors{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad registry mirror %q: expected REGISTRY=MIRROR", spec)
+		}
+
+		if err := res.Add(parts[0], parts[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (m RegistryMirrors) Add(registry, mirror string) error {
+	registry = strings.TrimSpace(registry)
+	mirror = strings.TrimSuffix(strings.TrimSpace(mirror), "/")
+	if registry == "" || mirror == "" {
+		return fmt.Errorf("bad registry mirror %q → %q: registry and mirror cannot be empty", registry, mirror)
+	}
+
+	parsedRegistry, err := name.NewRegistry(registry, name.WeakValidation)
+	if err != nil {
+		return fmt.Errorf("bad registry mirror %q → %q: %s", registry, mirror, err)
+	}
+
+	if _, err := name.NewRepository(mirror, name.WeakValidation); err != nil {
+		return fmt.Errorf("bad registry mirror %q → %q: %s", registry, mirror, err)
+	}
+
+	m[parsedRegistry.RegistryStr()] = mirror
+
+	return nil
+}
+
+// Merge returns new mirrors, the other mirrors take precedence.
+func (m RegistryMirrors) Merge(other RegistryMirrors) RegistryMirrors {
+	res := RegistryMirrors{}
+	for registry, mirror := range m {
+		res[registry] = mirror
+	}
+
+	for registry, mirror := range other {
+		res[registry] = mirror
+	}
+
+	return res
+}
+
+// MirrorReference returns the reference in the mirror or an empty string if there is no mirror for the reference registry.
+func (m RegistryMirrors) MirrorReference(reference string) string {
+	if len(m) == 0 {
+		return ""
+	}
+
+	ref, err := name.ParseReference(reference, name.WeakValidation)
+	if err != nil {
+		return ""
+	}
+
+	mirror, ok := m[ref.Context().RegistryStr()]
+	if !ok {
+		return ""
+	}
+
+	separator := ":"
+	if _, isDigest := ref.(name.Digest); isDigest {
+		separator = "@"
+	}
+
+	return fmt.Sprintf("%s/%s%s%s", mirror, ref.Context().RepositoryStr(), separator, ref.Identifier())
+}
@@ -0,0 +1,18 @@
+package docker_registry
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("registry mirror reference", func(mirrors map[string]string, reference, expectedMirrorReference string) {
+	registryMirrors, err := NewRegistryMirrors(mirrors)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(registryMirrors.MirrorReference(reference)).Should(Equal(expectedMirrorReference))
+},
+	Entry("docker hub official image", map[string]string{"docker.io": "mirror.example.com/dockerhub"}, "alpine:3.12", "mirror.example.com/dockerhub/library/alpine:3.12"),
+	Entry("docker hub image without tag", map[string]string{"docker.io": "mirror.example.com/dockerhub"}, "werf/werf", "mirror.example.com/dockerhub/werf/werf:latest"),
+	Entry("image with digest", map[string]string{"quay.io": "mirror.example.com/quay/"}, "quay.io/org/repo@sha256:4bf6d4ff1ef8e4a7cc9bd6da0ae0a95b8b7e0a3fcb67a3a24a79d6a2a0b8e0ab", "mirror.example.com/quay/org/repo@sha256:4bf6d4ff1ef8e4a7cc9bd6da0ae0a95b8b7e0a3fcb67a3a24a79d6a2a0b8e0ab"),
+	Entry("registry without mirror", map[string]string{"docker.io": "mirror.example.com/dockerhub"}, "quay.io/org/repo:1", ""),
+	Entry("no mirrors", map[string]string{}, "alpine", ""),
+)
@@ -18,6 +18,10 @@ var _ = DescribeTable("resolve implementation name", func(entry entry) {
 
 	Ω(resolvedImplementation).Should(Equal(entry.expectation))
 },
+	Entry("artifactory", entry{
+		imagesRepoAddress: "company.jfrog.io/docker-local/repo",
+		expectation:       "artifactory",
+	}),
 	Entry("ecr", entry{
 		imagesRepoAddress: "123456789012.dkr.ecr.test.amazonaws.com/repo",
 		expectation:       "ecr",
@@ -46,6 +50,10 @@ var _ = DescribeTable("resolve implementation name", func(entry entry) {
 		imagesRepoAddress: "harbor.company.com/project/repo",
 		expectation:       "harbor",
 	}),
+	Entry("nexus", entry{
+		imagesRepoAddress: "nexus.company.com:8083/repo",
+		expectation:       "nexus",
+	}),
 	Entry("quay", entry{
 		imagesRepoAddress: "quay.io/account/repo",
 		expectation:       "quay",
@@ -0,0 +1,214 @@
+package docker_registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/werf/logboek"
+)
+
+const (
+	DefaultRequestsMaxRetries = 5
+
+	retryInitialInterval = time.Second
+	retryMaxInterval     = time.Minute
+)
+
+// RequestsPolicyOptions is the client-side policy of requests to registries.
+// The policy is shared by all registry API calls: the Docker Registry API calls and the native REST API calls of implementations.
+type RequestsPolicyOptions struct {
+	// RateLimit is the number of requests per second for a registry host, 0 means unlimited.
+	RateLimit int
+	// MaxRetries is the number of retries of an idempotent request that failed with 429 or 5xx code, or with a temporary network error.
+	MaxRetries int
+}
+
+var requestsPolicy = newRequestsPolicyState(RequestsPolicyOptions{MaxRetries: DefaultRequestsMaxRetries})
+
+type requestsPolicyState struct {
+	RequestsPolicyOptions
+
+	limiters      map[string]*rate.Limiter
+	limitersMutex sync.Mutex
+}
+
+func newRequestsPolicyState(options RequestsPolicyOptions) *requestsPolicyState {
+	return &requestsPolicyState{
+		RequestsPolicyOptions: options,
+		limiters:              map[string]*rate.Limiter{},
+	}
+}
+
+func (p *requestsPolicyState) wait(ctx context.Context, host string) error {
+	if p.RateLimit <= 0 {
+		return nil
+	}
+
+	p.limitersMutex.Lock()
+	limiter, ok := p.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(p.RateLimit), p.RateLimit)
+		p.limiters[host] = limiter
+	}
+	p.limitersMutex.Unlock()
+
+	return limiter.Wait(ctx)
+}
+
+// retryDelay returns the Retry-After value limited by the max backoff delay if the registry sent one or exponential backoff delay with jitter otherwise.
+func (p *requestsPolicyState) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if delay > retryMaxInterval {
+				return retryMaxInterval
+			}
+
+			return delay
+		}
+	}
+
+	delay := retryInitialInterval << uint(attempt-1)
+	if delay <= 0 || delay > retryMaxInterval {
+		delay = retryMaxInterval
+	}
+
+	return delay + time.Duration(rand.Int63n(int64(retryInitialInterval)))
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay, true
+		}
+
+		return 0, true
+	}
+
+	return 0, false
+}
+
+type requestsRetriesDisabledKey struct{}
+
+// withoutRequestsRetries disables retries of the requests made with the context, the caller retries the whole operation instead.
+func withoutRequestsRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestsRetriesDisabledKey{}, true)
+}
+
+func isRequestsRetriesDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(requestsRetriesDisabledKey{}).(bool)
+	return disabled
+}
+
+// isRetryableRequest returns true for the requests which can be safely repeated: GET and HEAD requests, and PUT requests of blobs and manifests,
+// which are addressed by the digest or the tag and do not change anything when repeated (chunk uploads with PATCH, POST and DELETE requests are not retried).
+func isRetryableRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPut:
+		return req.Body == nil || req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+func isRetryableResponse(resp *http.Response) bool {
+	return isRetryableStatusCode(resp.StatusCode)
+}
+
+func isRetryableStatusCode(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func isRetryableError(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && (netErr.Timeout() || netErr.Temporary())
+}
+
+// retryTransport applies the requests policy to each request of the underlying transport.
+type retryTransport struct {
+	underlying http.RoundTripper
+}
+
+func newRetryTransport(underlying http.RoundTripper) http.RoundTripper {
+	return &retryTransport{underlying: underlying}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if err := requestsPolicy.wait(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq = req.Clone(ctx)
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := t.underlying.RoundTrip(attemptReq)
+
+		if !isRetryableRequest(req) {
+			return resp, err
+		}
+
+		var reason string
+		switch {
+		case err != nil && isRetryableError(err):
+			reason = err.Error()
+		case err == nil && isRetryableResponse(resp):
+			reason = resp.Status
+		default:
+			return resp, err
+		}
+
+		if attempt > requestsPolicy.MaxRetries || isRequestsRetriesDisabled(ctx) {
+			return resp, err
+		}
+
+		delay := requestsPolicy.retryDelay(attempt, resp)
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		logboek.Context(ctx).Warn().LogF("Retrying %s %s in %s (%d/%d): %s\n", req.Method, req.URL.String(), delay.Round(time.Millisecond), attempt, requestsPolicy.MaxRetries, reason)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL.String(), ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
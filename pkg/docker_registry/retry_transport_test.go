@@ -0,0 +1,98 @@
+package docker_registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("parse Retry-After header", func(value string, expectedDelay time.Duration, expectedOk bool) {
+	delay, ok := parseRetryAfter(value)
+	Ω(ok).Should(Equal(expectedOk))
+	Ω(delay).Should(Equal(expectedDelay))
+},
+	Entry("empty", "", time.Duration(0), false),
+	Entry("seconds", "3", 3*time.Second, true),
+	Entry("date in the past", "Wed, 21 Oct 2015 07:28:00 GMT", time.Duration(0), true),
+	Entry("invalid", "soon", time.Duration(0), false),
+)
+
+var _ = Describe("retry transport", func() {
+	var savedRequestsPolicy *requestsPolicyState
+
+	BeforeEach(func() {
+		savedRequestsPolicy = requestsPolicy
+		requestsPolicy = newRequestsPolicyState(RequestsPolicyOptions{MaxRetries: 2})
+	})
+
+	AfterEach(func() {
+		requestsPolicy = savedRequestsPolicy
+	})
+
+	It("should retry throttled requests honouring Retry-After", func() {
+		var requestsCount int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if atomic.AddInt32(&requestsCount, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		resp, err := (&http.Client{Transport: newRetryTransport(http.DefaultTransport)}).Get(server.URL)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp.StatusCode).Should(Equal(http.StatusOK))
+		Ω(atomic.LoadInt32(&requestsCount)).Should(Equal(int32(2)))
+	})
+
+	It("should return the last response when retries are exhausted", func() {
+		var requestsCount int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&requestsCount, 1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		resp, err := (&http.Client{Transport: newRetryTransport(http.DefaultTransport)}).Get(server.URL)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(resp.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+		Ω(atomic.LoadInt32(&requestsCount)).Should(Equal(int32(3)))
+	})
+
+	It("should not retry non-idempotent requests", func() {
+		var requestsCount int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&requestsCount, 1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := &http.Client{Transport: newRetryTransport(http.DefaultTransport)}
+		for _, method := range []string{http.MethodPost, http.MethodPatch, http.MethodDelete} {
+			req, err := http.NewRequest(method, server.URL, strings.NewReader("data"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			resp, err := client.Do(req)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+		}
+
+		Ω(atomic.LoadInt32(&requestsCount)).Should(Equal(int32(3)))
+	})
+
+	It("should limit Retry-After by the max backoff delay", func() {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"3600"}}}
+		Ω(requestsPolicy.retryDelay(1, resp)).Should(Equal(retryMaxInterval))
+	})
+})
@@ -0,0 +1,261 @@
+package git_repo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// GCMinAge protects the recently accessed caches, which can be in use without holding the locks (e.g. reading the remote clone during the build)
+const GCMinAge = time.Hour
+
+type GCOptions struct {
+	// MaxAge and MaxSize are the limits enforced by GC, zero value means no limit
+	MaxAge  time.Duration
+	MaxSize int64
+	DryRun  bool
+}
+
+// gcEntry is the remote clone with its work tree cache or the work tree cache of the local repo
+type gcEntry struct {
+	repoID           string
+	clonePath        string
+	workTreeCacheDir string
+	size             int64
+	lastAccess       time.Time
+}
+
+func (entry *gcEntry) String() string {
+	var paths []string
+	for _, path := range []string{entry.clonePath, entry.workTreeCacheDir} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return strings.Join(paths, ", ")
+}
+
+// GC removes remote git clones and work tree caches, which were not accessed within MaxAge, and the least recently accessed ones until the total size fits MaxSize
+func GC(ctx context.Context, options GCOptions) error {
+	if options.MaxAge == 0 && options.MaxSize == 0 {
+		return nil
+	}
+
+	entries, err := listGCEntries()
+	if err != nil {
+		return err
+	}
+
+	var totalSize int64
+	for _, entry := range entries {
+		totalSize += entry.size
+	}
+
+	logboek.Context(ctx).LogF("Git cache: %d entries, %s (limits: %s)\n", len(entries), units.BytesSize(float64(totalSize)), gcLimitsString(options))
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastAccess.Before(entries[j].lastAccess)
+	})
+
+	now := time.Now()
+	var freedSize int64
+	var freedEntries int
+	for _, entry := range entries {
+		age := now.Sub(entry.lastAccess)
+		isExpired := options.MaxAge > 0 && age > options.MaxAge
+		isOverSize := options.MaxSize > 0 && totalSize > options.MaxSize
+		if !isExpired && !isOverSize {
+			continue
+		}
+
+		if age < GCMinAge {
+			logboek.Context(ctx).Debug().LogF("Ignore git cache %s accessed %s ago\n", entry.String(), age.Round(time.Second))
+			continue
+		}
+
+		if removed, err := removeGCEntry(ctx, entry, options.DryRun); err != nil {
+			return err
+		} else if !removed {
+			continue
+		}
+
+		totalSize -= entry.size
+		freedSize += entry.size
+		freedEntries++
+	}
+
+	if freedEntries > 0 {
+		logboek.Context(ctx).LogF("Git cache: removed %d entries, %s\n", freedEntries, units.BytesSize(float64(freedSize)))
+	}
+
+	if options.MaxSize > 0 && totalSize > options.MaxSize {
+		logboek.Context(ctx).Warn().LogF("WARNING: Git cache size %s still exceeds %s: the rest of entries are in use\n", units.BytesSize(float64(totalSize)), units.BytesSize(float64(options.MaxSize)))
+	}
+
+	return nil
+}
+
+func gcLimitsString(options GCOptions) string {
+	maxAge, maxSize := "unlimited age", "unlimited size"
+	if options.MaxAge > 0 {
+		maxAge = fmt.Sprintf("max age %s", options.MaxAge)
+	}
+	if options.MaxSize > 0 {
+		maxSize = units.BytesSize(float64(options.MaxSize))
+	}
+
+	return fmt.Sprintf("%s, %s", maxAge, maxSize)
+}
+
+func removeGCEntry(ctx context.Context, entry *gcEntry, dryRun bool) (bool, error) {
+	if entry.clonePath != "" {
+		lockName := remoteRepoCloneLockName(entry.repoID)
+		isAcquired, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{NonBlocking: true})
+		if err != nil {
+			return false, fmt.Errorf("cannot acquire %s host lock: %s", lockName, err)
+		}
+
+		if !isAcquired {
+			logboek.Context(ctx).Default().LogFDetails("Ignore git clone %s used by another process\n", entry.clonePath)
+			return false, nil
+		}
+		defer werf.ReleaseHostLock(lock)
+	}
+
+	// the work tree of the remote clone refers to the clone, so the work tree cache is removed first
+	if entry.workTreeCacheDir != "" {
+		if removed, err := true_git.TryRemoveWorkTreeCache(ctx, entry.workTreeCacheDir, dryRun); err != nil {
+			return false, err
+		} else if !removed {
+			return false, nil
+		}
+	}
+
+	logboek.Context(ctx).LogLn(entry.String())
+
+	if dryRun || entry.clonePath == "" {
+		return true, nil
+	}
+
+	if err := os.RemoveAll(entry.clonePath); err != nil {
+		return false, fmt.Errorf("unable to remove %s: %s", entry.clonePath, err)
+	}
+
+	return true, nil
+}
+
+func listGCEntries() ([]*gcEntry, error) {
+	entryByRepoID := map[string]*gcEntry{}
+
+	remoteWorkTreeCacheDir := filepath.Join(GetWorkTreeCacheDir(), "remote")
+	if err := forEachCacheDir(GetGitRepoCacheDir(), func(repoID string, path string, info os.FileInfo) error {
+		// Clone uses <clone path>.tmp dir, which is removed on failure
+		if strings.HasSuffix(repoID, ".tmp") {
+			return nil
+		}
+
+		entryByRepoID[repoID] = &gcEntry{repoID: repoID, clonePath: path, lastAccess: info.ModTime()}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := forEachCacheDir(remoteWorkTreeCacheDir, func(repoID string, path string, info os.FileInfo) error {
+		entry, ok := entryByRepoID[repoID]
+		if !ok {
+			entry = &gcEntry{repoID: repoID, lastAccess: info.ModTime()}
+			entryByRepoID[repoID] = entry
+		} else if info.ModTime().After(entry.lastAccess) {
+			entry.lastAccess = info.ModTime()
+		}
+		entry.workTreeCacheDir = path
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var res []*gcEntry
+	for _, entry := range entryByRepoID {
+		res = append(res, entry)
+	}
+
+	if err := forEachCacheDir(filepath.Join(GetWorkTreeCacheDir(), "local"), func(repoID string, path string, info os.FileInfo) error {
+		res = append(res, &gcEntry{workTreeCacheDir: path, lastAccess: info.ModTime()})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range res {
+		for _, path := range []string{entry.clonePath, entry.workTreeCacheDir} {
+			if path == "" {
+				continue
+			}
+
+			size, err := dirSize(path)
+			if err != nil {
+				return nil, err
+			}
+			entry.size += size
+		}
+	}
+
+	return res, nil
+}
+
+func forEachCacheDir(dir string, f func(name string, path string, info os.FileInfo) error) error {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error reading dir %s: %s", dir, err)
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+
+		if err := f(info.Name(), filepath.Join(dir, info.Name()), info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to calculate size of %s: %s", dir, err)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,127 @@
+package git_repo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/werf/lockgate"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+func initGCTest(t *testing.T) func() {
+	tmpDir, err := ioutil.TempDir("", "werf-git-cache-gc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := werf.Init(filepath.Join(tmpDir, "tmp"), filepath.Join(tmpDir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() { os.RemoveAll(tmpDir) }
+}
+
+// newGCTestCacheDir creates the cache dir with the data file of the size and sets the last access time
+func newGCTestCacheDir(t *testing.T, dir string, size int, lastAccess time.Time) string {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "data"), make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(dir, lastAccess, lastAccess); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func assertPathsExistence(t *testing.T, paths map[string]bool) {
+	for path, shouldExist := range paths {
+		_, err := os.Stat(path)
+		if exists := err == nil; exists != shouldExist {
+			t.Errorf("%s:\n[EXPECTED]: exists %v\n[GOT]: exists %v (%v)", path, shouldExist, exists, err)
+		}
+	}
+}
+
+func TestGCByMaxAge(t *testing.T) {
+	defer initGCTest(t)()
+
+	now := time.Now()
+	expiredClone := newGCTestCacheDir(t, filepath.Join(GetGitRepoCacheDir(), "expired"), 10, now.Add(-48*time.Hour))
+	expiredCloneWorkTree := newGCTestCacheDir(t, filepath.Join(GetWorkTreeCacheDir(), "remote", "expired"), 10, now.Add(-48*time.Hour))
+	cloneWithRecentWorkTree := newGCTestCacheDir(t, filepath.Join(GetGitRepoCacheDir(), "recent"), 10, now.Add(-48*time.Hour))
+	recentWorkTree := newGCTestCacheDir(t, filepath.Join(GetWorkTreeCacheDir(), "remote", "recent"), 10, now.Add(-2*time.Hour))
+	expiredLocalWorkTree := newGCTestCacheDir(t, filepath.Join(GetWorkTreeCacheDir(), "local", "expired"), 10, now.Add(-48*time.Hour))
+	cloneInProgress := newGCTestCacheDir(t, filepath.Join(GetGitRepoCacheDir(), "cloning.tmp"), 10, now.Add(-48*time.Hour))
+
+	if err := GC(context.Background(), GCOptions{MaxAge: 24 * time.Hour, DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPathsExistence(t, map[string]bool{expiredClone: true, expiredCloneWorkTree: true, expiredLocalWorkTree: true})
+
+	if err := GC(context.Background(), GCOptions{MaxAge: 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPathsExistence(t, map[string]bool{
+		expiredClone:         false,
+		expiredCloneWorkTree: false,
+		expiredLocalWorkTree: false,
+		// the clone is accessed with its work tree
+		cloneWithRecentWorkTree: true,
+		recentWorkTree:          true,
+		cloneInProgress:         true,
+	})
+}
+
+func TestGCByMaxSize(t *testing.T) {
+	defer initGCTest(t)()
+
+	now := time.Now()
+	oldestClone := newGCTestCacheDir(t, filepath.Join(GetGitRepoCacheDir(), "oldest"), 100, now.Add(-4*time.Hour))
+	oldClone := newGCTestCacheDir(t, filepath.Join(GetGitRepoCacheDir(), "old"), 100, now.Add(-3*time.Hour))
+	newClone := newGCTestCacheDir(t, filepath.Join(GetGitRepoCacheDir(), "new"), 100, now.Add(-2*time.Hour))
+	// the recently accessed caches could be in use without the locks
+	recentClone := newGCTestCacheDir(t, filepath.Join(GetGitRepoCacheDir(), "recent"), 100, now)
+
+	if err := GC(context.Background(), GCOptions{MaxSize: 250}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPathsExistence(t, map[string]bool{oldestClone: false, oldClone: false, newClone: true, recentClone: true})
+}
+
+func TestGCSkipsLockedClone(t *testing.T) {
+	defer initGCTest(t)()
+
+	lockedClone := newGCTestCacheDir(t, filepath.Join(GetGitRepoCacheDir(), "locked"), 10, time.Now().Add(-48*time.Hour))
+
+	lockAcquired := make(chan struct{})
+	gcDone := make(chan struct{})
+	go func() {
+		_ = werf.WithHostLock(context.Background(), remoteRepoCloneLockName("locked"), lockgate.AcquireOptions{}, func() error {
+			close(lockAcquired)
+			<-gcDone
+			return nil
+		})
+	}()
+	<-lockAcquired
+
+	err := GC(context.Background(), GCOptions{MaxAge: 24 * time.Hour})
+	close(gcDone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPathsExistence(t, map[string]bool{lockedClone: true})
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 
 	"github.com/werf/logboek"
 
@@ -224,6 +225,21 @@ func (repo *Local) CheckAndReadCommitSymlink(ctx context.Context, path string, c
 	return repo.checkAndReadSymlink(ctx, repo.getRepoWorkTreeCacheDir(repo.getRepoID()), repo.Path, repo.GitDir, commit, path)
 }
 
+// ResolveCommit returns the commit hash of the specified revision (commit, branch, tag or expression such as HEAD~1).
+func (repo *Local) ResolveCommit(_ context.Context, revision string) (string, error) {
+	repository, err := git.PlainOpenWithOptions(repo.Path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return "", fmt.Errorf("cannot open repo %q: %s", repo.Path, err)
+	}
+
+	hash, err := repository.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve revision %q: %s", revision, err)
+	}
+
+	return hash.String(), nil
+}
+
 func (repo *Local) IsCommitExists(ctx context.Context, commit string) (bool, error) {
 	return repo.isCommitExists(ctx, repo.Path, repo.GitDir, commit)
 }
@@ -85,11 +85,20 @@ func (repo *Remote) CloneAndFetch(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if isCloned {
+
+	if !isCloned {
+		if err := repo.Fetch(ctx); err != nil {
+			return err
+		}
+	}
+
+	if repo.IsDryRun {
 		return nil
 	}
 
-	return repo.Fetch(ctx)
+	return repo.withRemoteRepoLock(ctx, func() error {
+		return touchClonePath(repo.GetClonePath())
+	})
 }
 
 func (repo *Remote) isCloneExists() (bool, error) {
@@ -306,9 +315,26 @@ func (repo *Remote) getWorkTreeCacheDir(repoID string) string {
 	return filepath.Join(GetWorkTreeCacheDir(), "remote", repoID)
 }
 
+// withRemoteRepoLock also takes the clone lock, which is bound to the clone path rather than to the repo name, so host cleanup can lock the clone
 func (repo *Remote) withRemoteRepoLock(ctx context.Context, f func() error) error {
 	lockName := fmt.Sprintf("remote_git_mapping.%s", repo.Name)
-	return werf.WithHostLock(ctx, lockName, lockgate.AcquireOptions{Timeout: 600 * time.Second}, f)
+	return werf.WithHostLock(ctx, lockName, lockgate.AcquireOptions{Timeout: 600 * time.Second}, func() error {
+		return werf.WithHostLock(ctx, remoteRepoCloneLockName(repo.getRepoID()), lockgate.AcquireOptions{Timeout: 600 * time.Second}, f)
+	})
+}
+
+func remoteRepoCloneLockName(repoID string) string {
+	return fmt.Sprintf("remote_git_clone.%s", repoID)
+}
+
+// touchClonePath updates the modification time of the clone dir, which is used as the last access time by host cleanup
+func touchClonePath(clonePath string) error {
+	now := time.Now()
+	if err := os.Chtimes(clonePath, now, now); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to update access time of %s: %s", clonePath, err)
+	}
+
+	return nil
 }
 
 func (repo *Remote) TagsList(_ context.Context) ([]string, error) {
@@ -91,7 +91,43 @@ func (d dockerfile) IsUncommittedDockerignoreAccepted(path string) (bool, error)
 }
 
 type helm struct {
-	AllowUncommittedFiles []string `json:"allowUncommittedFiles"`
+	AllowUncommittedFiles     []string         `json:"allowUncommittedFiles"`
+	AllowSecretValueProviders []string         `json:"allowSecretValueProviders"`
+	PlaintextSecrets          plaintextSecrets `json:"plaintextSecrets"`
+}
+
+func (h helm) IsSecretValueProviderAccepted(name string) bool {
+	for _, provider := range h.AllowSecretValueProviders {
+		if provider == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+const (
+	PlaintextSecretsReportWarning = "warning"
+	PlaintextSecretsReportError   = "error"
+	PlaintextSecretsReportIgnore  = "ignore"
+)
+
+type plaintextSecrets struct {
+	Report         string   `json:"report"`
+	AllowLocations []string `json:"allowLocations"`
+}
+
+// GetReport returns the way of reporting plaintext secrets (the inspection is disabled by default).
+func (s plaintextSecrets) GetReport() string {
+	if s.Report == "" {
+		return PlaintextSecretsReportIgnore
+	}
+
+	return s.Report
+}
+
+func (s plaintextSecrets) IsLocationAccepted(location string) (bool, error) {
+	return isPathMatched(s.AllowLocations, location, true)
 }
 
 func isPathMatched(patterns []string, path string, withGlobs bool) (bool, error) {
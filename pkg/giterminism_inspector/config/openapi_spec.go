@@ -105,6 +105,24 @@ definitions:
         type: array
         items:
           type: string
+      allowSecretValueProviders:
+        type: array
+        items:
+          type: string
+          enum: ["vault", "file", "env"]
+      plaintextSecrets:
+        $ref: '#/definitions/HelmPlaintextSecrets'
+  HelmPlaintextSecrets:
+    type: object
+    additionalProperties: {}
+    properties:
+      report:
+        type: string
+        enum: ["warning", "error", "ignore"]
+      allowLocations:
+        type: array
+        items:
+          type: string
 `
 )
 
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/werf/logboek"
 	"github.com/werf/werf/pkg/giterminism_inspector/config"
+	"github.com/werf/werf/pkg/util/secretvalues"
 )
 
 const giterminismDocPageURL = "https://werf.io/v1.2-alpha/documentation/advanced/configuration/giterminism.html"
@@ -18,7 +20,8 @@ var (
 	ReportedUncommittedPaths []string
 	ReportedUntrackedPaths   []string
 
-	giterminismConfig config.GiterminismConfig
+	giterminismConfig         config.GiterminismConfig
+	isGiterminismConfigLoaded bool
 )
 
 type InspectionOptions struct {
@@ -36,11 +39,17 @@ func Init(projectPath string, opts InspectionOptions) error {
 		return err
 	} else {
 		giterminismConfig = c
+		isGiterminismConfigLoaded = true
 	}
 
 	return nil
 }
 
+// IsGiterminismConfigLoaded returns false for the commands which do not inspect giterminism (e.g. werf helm commands).
+func IsGiterminismConfigLoaded() bool {
+	return isGiterminismConfigLoaded
+}
+
 func IsUncommittedConfigGoTemplateRenderingFileAccepted(path string) (bool, error) {
 	return giterminismConfig.Config.GoTemplateRendering.IsUncommittedFileAccepted(path)
 }
@@ -153,6 +162,56 @@ func ReportConfigGoTemplateRenderingEnv(_ context.Context, envName string) error
 	return fmt.Errorf("env name %s is forbidden due to enabled giterminism mode (more info %s)", envName, giterminismDocPageURL)
 }
 
+func ReportHelmSecretValueProvider(_ context.Context, providerName string) error {
+	if giterminismConfig.Helm.IsSecretValueProviderAccepted(providerName) {
+		return nil
+	}
+
+	return fmt.Errorf("secret value provider %s is forbidden due to enabled giterminism mode (more info %s)", providerName, giterminismDocPageURL)
+}
+
+// IsHelmPlaintextSecretsInspectionEnabled returns false if the inspection is not configured in the werf-giterminism.yaml (including werf helm commands).
+func IsHelmPlaintextSecretsInspectionEnabled() bool {
+	if !IsGiterminismConfigLoaded() {
+		return false
+	}
+
+	return giterminismConfig.Helm.PlaintextSecrets.GetReport() != config.PlaintextSecretsReportIgnore
+}
+
+func ReportHelmPlaintextSecrets(ctx context.Context, secrets []secretvalues.PlaintextSecret) error {
+	var reportedSecrets []secretvalues.PlaintextSecret
+	for _, secret := range secrets {
+		if isAccepted, err := giterminismConfig.Helm.PlaintextSecrets.IsLocationAccepted(secret.Location); err != nil {
+			return err
+		} else if !isAccepted {
+			reportedSecrets = append(reportedSecrets, secret)
+		}
+	}
+
+	if len(reportedSecrets) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, secret := range reportedSecrets {
+		lines = append(lines, fmt.Sprintf(" - %s", secret))
+	}
+
+	switch giterminismConfig.Helm.PlaintextSecrets.GetReport() {
+	case config.PlaintextSecretsReportIgnore:
+		return nil
+	case config.PlaintextSecretsReportError:
+		return fmt.Errorf("found plaintext secrets not from the secret values (use werf secret values or allow the locations in the werf-giterminism.yaml, more info %s):\n%s", giterminismDocPageURL, strings.Join(lines, "\n"))
+	default:
+		logboek.Context(ctx).Warn().LogF("WARNING: Found plaintext secrets not from the secret values (more info %s):\n", giterminismDocPageURL)
+		for _, line := range lines {
+			logboek.Context(ctx).Warn().LogF("%s\n", line)
+		}
+		return nil
+	}
+}
+
 func PrintInspectionDebrief(ctx context.Context) {
 	headerPrinted := false
 	printHeader := func() {
@@ -15,12 +15,17 @@ import (
 	"github.com/werf/logboek"
 
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/tmp_manager"
 )
 
 type HostCleanupOptions struct {
-	DryRun bool
+	// AllowedDockerStorageVolumeUsagePercentage enables the removal of local stages in the least recently used order, nil disables
+	AllowedDockerStorageVolumeUsagePercentage *float64
+	GitCacheMaxAge                            time.Duration
+	GitCacheMaxSize                           int64
+	DryRun                                    bool
 }
 
 func HostCleanup(ctx context.Context, options HostCleanupOptions) error {
@@ -44,11 +49,31 @@ func HostCleanup(ctx context.Context, options HostCleanupOptions) error {
 			return nil
 		}
 
+		if options.AllowedDockerStorageVolumeUsagePercentage != nil {
+			if err := logboek.Context(ctx).LogProcess("Running cleanup for least recently used local stages by docker storage volume usage").DoError(func() error {
+				return safeLocalStagesCleanupByVolumeUsage(ctx, *options.AllowedDockerStorageVolumeUsagePercentage, commonOptions)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err := logboek.Context(ctx).LogProcess("Running GC for local stages last use cache").DoError(func() error {
+			return lastUseCacheGC(ctx, commonOptions.DryRun)
+		}); err != nil {
+			return fmt.Errorf("last use cache gc failed: %s", err)
+		}
+
 		return werf.WithHostLock(ctx, "gc", lockgate.AcquireOptions{}, func() error {
 			if err := tmp_manager.GC(ctx, commonOptions.DryRun); err != nil {
 				return fmt.Errorf("tmp files gc failed: %s", err)
 			}
 
+			if err := logboek.Context(ctx).LogProcess("Running GC for git cache").DoError(func() error {
+				return git_repo.GC(ctx, git_repo.GCOptions{MaxAge: options.GitCacheMaxAge, MaxSize: options.GitCacheMaxSize, DryRun: commonOptions.DryRun})
+			}); err != nil {
+				return fmt.Errorf("git cache gc failed: %s", err)
+			}
+
 			return nil
 		})
 	})
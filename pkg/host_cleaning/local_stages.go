@@ -0,0 +1,182 @@
+package host_cleaning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/go-units"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// safeLocalStagesCleanupByVolumeUsage removes local stages in the least recently used order until the docker storage volume usage is below the allowed percentage
+func safeLocalStagesCleanupByVolumeUsage(ctx context.Context, allowedVolumeUsagePercentage float64, options CommonOptions) error {
+	usage, dockerRootDir, err := getDockerStorageVolumeUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	logboek.Context(ctx).Default().LogF("Docker storage volume usage (%s): %s, allowed %.2f%%\n", dockerRootDir, usage.String(), allowedVolumeUsagePercentage)
+	if usage.Percentage() <= allowedVolumeUsagePercentage {
+		logboek.Context(ctx).Default().LogLn("Cleanup is not needed")
+		return nil
+	}
+
+	images, err := localStagesByLastUse(ctx)
+	if err != nil {
+		return err
+	}
+
+	images, err = processUsedImages(ctx, images, options)
+	if err != nil {
+		return err
+	}
+
+	for _, img := range images {
+		if usage.Percentage() <= allowedVolumeUsagePercentage {
+			break
+		}
+
+		removed, err := safeLocalStageRemove(ctx, img, options)
+		if err != nil {
+			return err
+		} else if !removed {
+			continue
+		}
+
+		if options.DryRun {
+			// shared layers are not taken into account, so the estimation is optimistic
+			usage.UsedBytes -= minUint64(usage.UsedBytes, uint64(img.Size))
+		} else if usage, _, err = getDockerStorageVolumeUsage(ctx); err != nil {
+			return err
+		}
+	}
+
+	if usage.Percentage() > allowedVolumeUsagePercentage {
+		logboek.Context(ctx).Warn().LogF("WARNING: Docker storage volume usage %s still exceeds allowed %.2f%%: there are no more local stages that can be removed\n", usage.String(), allowedVolumeUsagePercentage)
+	} else {
+		logboek.Context(ctx).Default().LogF("Docker storage volume usage: %s\n", usage.String())
+	}
+
+	return nil
+}
+
+// localStagesByLastUse returns local stages sorted by the last use
+func localStagesByLastUse(ctx context.Context) ([]types.ImageSummary, error) {
+	images, err := localStages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accessTimestamps, err := image.CommonLastUseCache.GetAccessTimestamps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sortImagesByLastUse(images, accessTimestamps)
+
+	for _, img := range images {
+		logboek.Context(ctx).Debug().LogF("Local stage %s last use %s size %s\n", logImageName(img), time.Unix(imageLastUse(img, accessTimestamps), 0).String(), units.BytesSize(float64(img.Size)))
+	}
+
+	return images, nil
+}
+
+func localStages(ctx context.Context) ([]types.ImageSummary, error) {
+	filterSet := filters.NewArgs()
+	filterSet.Add("label", image.WerfStageDigestLabel)
+	images, err := werfImagesByFilterSet(ctx, filterSet)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get local stages: %s", err)
+	}
+
+	return images, nil
+}
+
+// sortImagesByLastUse sorts images from the least recently used, the creation time is used for the images without last use records
+func sortImagesByLastUse(images []types.ImageSummary, accessTimestamps map[string]int64) {
+	sort.SliceStable(images, func(i, j int) bool {
+		return imageLastUse(images[i], accessTimestamps) < imageLastUse(images[j], accessTimestamps)
+	})
+}
+
+func imageLastUse(img types.ImageSummary, accessTimestamps map[string]int64) int64 {
+	res := img.Created
+	for _, repoTag := range img.RepoTags {
+		if timestamp, ok := accessTimestamps[repoTag]; ok && timestamp > res {
+			res = timestamp
+		}
+	}
+	return res
+}
+
+// lastUseCacheGC removes the last use records of the local stages which have been removed not by werf
+func lastUseCacheGC(ctx context.Context, dryRun bool) error {
+	images, err := localStages(ctx)
+	if err != nil {
+		return err
+	}
+
+	localImages := map[string]bool{}
+	for _, img := range images {
+		for _, repoTag := range img.RepoTags {
+			localImages[repoTag] = true
+		}
+	}
+
+	return image.CommonLastUseCache.GC(ctx, localImages, dryRun)
+}
+
+func safeLocalStageRemove(ctx context.Context, img types.ImageSummary, options CommonOptions) (bool, error) {
+	var locks []lockgate.LockHandle
+	defer func() {
+		for _, lock := range locks {
+			werf.ReleaseHostLock(lock)
+		}
+	}()
+
+	for _, repoTag := range img.RepoTags {
+		imageLockName := container_runtime.ImageLockName(repoTag)
+		isLocked, lock, err := werf.AcquireHostLock(ctx, imageLockName, lockgate.AcquireOptions{NonBlocking: true})
+		if err != nil {
+			return false, fmt.Errorf("failed to lock %s for image %s: %s", imageLockName, repoTag, err)
+		}
+
+		if !isLocked {
+			logboek.Context(ctx).Default().LogFDetails("Ignore image %s used by another process\n", repoTag)
+			return false, nil
+		}
+
+		locks = append(locks, lock)
+	}
+
+	if err := imagesRemove(ctx, []types.ImageSummary{img}, options); err != nil {
+		return false, err
+	}
+
+	if !options.DryRun {
+		for _, repoTag := range img.RepoTags {
+			if err := image.CommonLastUseCache.Remove(ctx, repoTag); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,38 @@
+package host_cleaning
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestSortImagesByLastUse(t *testing.T) {
+	images := []types.ImageSummary{
+		{ID: "used-recently", Created: 100, RepoTags: []string{"project:used-recently"}},
+		{ID: "recreated", Created: 200, RepoTags: []string{"project:recreated"}},
+		{ID: "used-long-ago", Created: 50, RepoTags: []string{"project:used-long-ago"}},
+		{ID: "used-by-another-tag", Created: 10, RepoTags: []string{"project:old-tag", "project:new-tag"}},
+	}
+
+	accessTimestamps := map[string]int64{
+		"project:used-recently": 400,
+		"project:used-long-ago": 150,
+		"project:old-tag":       20,
+		"project:new-tag":       300,
+		// the last use of the previous image with the same tag is ignored
+		"project:recreated": 10,
+	}
+
+	sortImagesByLastUse(images, accessTimestamps)
+
+	var ids []string
+	for _, img := range images {
+		ids = append(ids, img.ID)
+	}
+
+	expected := []string{"used-long-ago", "recreated", "used-by-another-tag", "used-recently"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", expected, ids)
+	}
+}
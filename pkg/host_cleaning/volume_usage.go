@@ -0,0 +1,41 @@
+package host_cleaning
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/go-units"
+
+	"github.com/werf/werf/pkg/docker"
+)
+
+type VolumeUsage struct {
+	UsedBytes  uint64
+	TotalBytes uint64
+}
+
+func (usage VolumeUsage) Percentage() float64 {
+	if usage.TotalBytes == 0 {
+		return 0
+	}
+	return float64(usage.UsedBytes) / float64(usage.TotalBytes) * 100
+}
+
+func (usage VolumeUsage) String() string {
+	return fmt.Sprintf("%.2f%% (%s of %s)", usage.Percentage(), units.BytesSize(float64(usage.UsedBytes)), units.BytesSize(float64(usage.TotalBytes)))
+}
+
+// getDockerStorageVolumeUsage returns the usage of the volume containing docker root dir, docker server should be running on the same host
+func getDockerStorageVolumeUsage(ctx context.Context) (VolumeUsage, string, error) {
+	info, err := docker.Info(ctx)
+	if err != nil {
+		return VolumeUsage{}, "", fmt.Errorf("unable to get docker info: %s", err)
+	}
+
+	usage, err := getVolumeUsageByPath(info.DockerRootDir)
+	if err != nil {
+		return VolumeUsage{}, "", fmt.Errorf("unable to get volume usage of docker root dir %s (docker server should be running on the same host): %s", info.DockerRootDir, err)
+	}
+
+	return usage, info.DockerRootDir, nil
+}
@@ -0,0 +1,23 @@
+package host_cleaning
+
+import "testing"
+
+func TestVolumeUsage(t *testing.T) {
+	for _, tt := range []struct {
+		usage              VolumeUsage
+		expectedPercentage float64
+		expectedString     string
+	}{
+		{usage: VolumeUsage{UsedBytes: 750, TotalBytes: 1000}, expectedPercentage: 75, expectedString: "75.00% (750B of 1000B)"},
+		{usage: VolumeUsage{UsedBytes: 0, TotalBytes: 1024 * 1024 * 1024}, expectedPercentage: 0, expectedString: "0.00% (0B of 1GiB)"},
+		{usage: VolumeUsage{}, expectedPercentage: 0, expectedString: "0.00% (0B of 0B)"},
+	} {
+		if percentage := tt.usage.Percentage(); percentage != tt.expectedPercentage {
+			t.Errorf("\n[EXPECTED]: %v\n[GOT]: %v", tt.expectedPercentage, percentage)
+		}
+
+		if s := tt.usage.String(); s != tt.expectedString {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", tt.expectedString, s)
+		}
+	}
+}
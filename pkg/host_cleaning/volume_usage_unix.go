@@ -0,0 +1,20 @@
+// +build linux darwin
+
+package host_cleaning
+
+import "syscall"
+
+func getVolumeUsageByPath(path string) (VolumeUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return VolumeUsage{}, err
+	}
+
+	totalBytes := stat.Blocks * uint64(stat.Bsize)
+	freeBytes := stat.Bfree * uint64(stat.Bsize)
+
+	return VolumeUsage{
+		UsedBytes:  totalBytes - freeBytes,
+		TotalBytes: totalBytes,
+	}, nil
+}
@@ -0,0 +1,30 @@
+// +build linux darwin
+
+package host_cleaning
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestGetVolumeUsageByPath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-volume-usage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	usage, err := getVolumeUsageByPath(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if usage.TotalBytes == 0 || usage.UsedBytes > usage.TotalBytes {
+		t.Errorf("unexpected volume usage %+v", usage)
+	}
+
+	if _, err := getVolumeUsageByPath(tmpDir + "/nonexistent"); err == nil {
+		t.Errorf("Expected error for nonexistent path")
+	}
+}
@@ -0,0 +1,9 @@
+// +build windows
+
+package host_cleaning
+
+import "fmt"
+
+func getVolumeUsageByPath(_ string) (VolumeUsage, error) {
+	return VolumeUsage{}, fmt.Errorf("not supported on windows")
+}
@@ -19,10 +19,17 @@ type Info struct {
 	ID                string            `json:"ID"`
 	ParentID          string            `json:"parentID"`
 	Labels            map[string]string `json:"labels"`
+	Annotations       map[string]string `json:"annotations"`
 	Size              int64             `json:"size"`
+	Layers            []LayerInfo       `json:"layers"`
 	CreatedAtUnixNano int64             `json:"createdAtUnixNano"`
 }
 
+type LayerInfo struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
 func (info *Info) SetCreatedAtUnix(seconds int64) {
 	info.CreatedAtUnixNano = seconds * 1000_000_000
 }
@@ -1,14 +1,42 @@
 package image
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
+
+	"github.com/docker/go-units"
 
 	"github.com/werf/werf/pkg/werf"
 )
 
-var CommonManifestCache *ManifestCache
+var (
+	CommonManifestCache *ManifestCache
+	CommonTagsCache     *TagsCache
+	CommonLastUseCache  *LastUseCache
+)
 
 func Init() error {
 	CommonManifestCache = NewManifestCache(filepath.Join(werf.GetLocalCacheDir(), "manifests", ManifestCacheVersion))
+	CommonTagsCache = NewTagsCache(filepath.Join(werf.GetLocalCacheDir(), "tags", TagsCacheVersion))
+	CommonLastUseCache = NewLastUseCache(filepath.Join(werf.GetLocalCacheDir(), "last_use", LastUseCacheVersion))
+
+	if v := os.Getenv("WERF_MANIFEST_CACHE_MAX_SIZE"); v != "" {
+		maxSize, err := units.RAMInBytes(v)
+		if err != nil {
+			return fmt.Errorf("bad WERF_MANIFEST_CACHE_MAX_SIZE value %q: %s", v, err)
+		}
+		CommonManifestCache.MaxSize = maxSize
+	}
+
+	if v := os.Getenv("WERF_MANIFEST_CACHE_MAX_ENTRIES"); v != "" {
+		maxEntries, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("bad WERF_MANIFEST_CACHE_MAX_ENTRIES value %q: %s", v, err)
+		}
+		CommonManifestCache.MaxEntries = maxEntries
+	}
+
 	return nil
 }
@@ -0,0 +1,219 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/slug"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	LastUseCacheVersion = "1"
+
+	// the record could be written before the image is pulled or stored locally, so the fresh records are kept by the GC
+	lastUseCacheGCGracePeriod = time.Hour
+)
+
+// LastUseCache stores the time when the local image was last used (built or selected as a cache hit) to cleanup the host in the least recently used order
+type LastUseCache struct {
+	CacheDir string
+}
+
+type LastUseCacheRecord struct {
+	AccessTimestamp int64
+	ImageName       string
+}
+
+func NewLastUseCache(cacheDir string) *LastUseCache {
+	return &LastUseCache{CacheDir: cacheDir}
+}
+
+func (cache *LastUseCache) Touch(ctx context.Context, imageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- LastUseCache.Touch %s\n", imageName)
+
+	if lock, err := cache.lock(ctx, imageName); err != nil {
+		return err
+	} else {
+		defer cache.unlock(lock)
+	}
+
+	return cache.writeRecord(&LastUseCacheRecord{
+		AccessTimestamp: time.Now().Unix(),
+		ImageName:       imageName,
+	})
+}
+
+func (cache *LastUseCache) Remove(ctx context.Context, imageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- LastUseCache.Remove %s\n", imageName)
+
+	if lock, err := cache.lock(ctx, imageName); err != nil {
+		return err
+	} else {
+		defer cache.unlock(lock)
+	}
+
+	filePath := cache.constructFilePathForImage(imageName)
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing %s: %s", filePath, err)
+	}
+
+	return nil
+}
+
+// GetAccessTimestamps returns the last use unix timestamp by image name
+func (cache *LastUseCache) GetAccessTimestamps(ctx context.Context) (map[string]int64, error) {
+	res := map[string]int64{}
+
+	fileInfos, err := ioutil.ReadDir(cache.CacheDir)
+	if os.IsNotExist(err) {
+		return res, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading dir %s: %s", cache.CacheDir, err)
+	}
+
+	for _, fileInfo := range fileInfos {
+		filePath := filepath.Join(cache.CacheDir, fileInfo.Name())
+
+		dataBytes, err := ioutil.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading %s: %s", filePath, err)
+		}
+
+		record := &LastUseCacheRecord{}
+		if err := json.Unmarshal(dataBytes, record); err != nil {
+			logboek.Context(ctx).Error().LogF("WARNING: invalid last use cache json record in file %s: %s: ignoring record\n", filePath, err)
+			continue
+		}
+
+		res[record.ImageName] = record.AccessTimestamp
+	}
+
+	return res, nil
+}
+
+// GC removes the records of the images which do not exist locally anymore
+func (cache *LastUseCache) GC(ctx context.Context, localImages map[string]bool, dryRun bool) error {
+	fileInfos, err := ioutil.ReadDir(cache.CacheDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error reading dir %s: %s", cache.CacheDir, err)
+	}
+
+	now := time.Now()
+	var removedRecords int
+	for _, fileInfo := range fileInfos {
+		filePath := filepath.Join(cache.CacheDir, fileInfo.Name())
+
+		dataBytes, err := ioutil.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("error reading %s: %s", filePath, err)
+		}
+
+		record := &LastUseCacheRecord{}
+		if err := json.Unmarshal(dataBytes, record); err == nil {
+			if localImages[record.ImageName] || now.Sub(time.Unix(record.AccessTimestamp, 0)) <= lastUseCacheGCGracePeriod {
+				continue
+			}
+		} else if now.Sub(fileInfo.ModTime()) <= lastUseCacheGCGracePeriod {
+			continue
+		}
+
+		if removed, err := cache.removeRecordFile(ctx, record.ImageName, filePath, dryRun); err != nil {
+			return err
+		} else if removed {
+			removedRecords++
+		}
+	}
+
+	if removedRecords > 0 {
+		logboek.Context(ctx).LogF("Last use cache: removed %d records of non-existing local images\n", removedRecords)
+	}
+
+	return nil
+}
+
+func (cache *LastUseCache) removeRecordFile(ctx context.Context, imageName, filePath string, dryRun bool) (bool, error) {
+	// invalid records have no image name, so there is nothing to lock
+	if imageName != "" {
+		lockName := cache.lockName(imageName)
+		isAcquired, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{NonBlocking: true})
+		if err != nil {
+			return false, fmt.Errorf("cannot acquire %s host lock: %s", lockName, err)
+		}
+
+		if !isAcquired {
+			logboek.Context(ctx).Debug().LogF("Ignore last use cache record %s used by another process\n", filePath)
+			return false, nil
+		}
+		defer cache.unlock(lock)
+	}
+
+	logboek.Context(ctx).LogLn(filePath)
+
+	if dryRun {
+		return true, nil
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("unable to remove %s: %s", filePath, err)
+	}
+
+	return true, nil
+}
+
+func (cache *LastUseCache) writeRecord(record *LastUseCacheRecord) error {
+	filePath := cache.constructFilePathForImage(record.ImageName)
+
+	dirPath := filepath.Dir(filePath)
+	if err := os.MkdirAll(dirPath, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating dir %s: %s", dirPath, err)
+	}
+
+	if dataBytes, err := json.Marshal(record); err != nil {
+		return fmt.Errorf("error marshalling json: %s", err)
+	} else {
+		if err := ioutil.WriteFile(filePath, append(dataBytes, []byte("\n")...), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %s", filePath, err)
+		}
+		return nil
+	}
+}
+
+func (cache *LastUseCache) constructFilePathForImage(imageName string) string {
+	return filepath.Join(cache.CacheDir, util.Sha256Hash(imageName))
+}
+
+func (cache *LastUseCache) lockName(imageName string) string {
+	return fmt.Sprintf("last_use_cache.%s", slug.Slug(imageName))
+}
+
+func (cache *LastUseCache) lock(ctx context.Context, imageName string) (lockgate.LockHandle, error) {
+	lockName := cache.lockName(imageName)
+	if _, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{}); err != nil {
+		return lockgate.LockHandle{}, fmt.Errorf("cannot acquire %s host lock: %s", lockName, err)
+	} else {
+		return lock, nil
+	}
+}
+
+func (cache *LastUseCache) unlock(lock lockgate.LockHandle) error {
+	if err := werf.ReleaseHostLock(lock); err != nil {
+		return fmt.Errorf("cannot release %s host lock: %s", lock.LockName, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package image
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+var _ = Describe("last use cache", func() {
+	var tmpDir string
+	var cache *LastUseCache
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "werf-last-use-cache-test")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werf.Init(filepath.Join(tmpDir, "tmp"), filepath.Join(tmpDir, "home"))).Should(Succeed())
+
+		cache = NewLastUseCache(filepath.Join(tmpDir, "last_use"))
+	})
+
+	AfterEach(func() {
+		Ω(os.RemoveAll(tmpDir)).Should(Succeed())
+	})
+
+	It("should return the last use of the touched images", func() {
+		Ω(cache.Touch(context.Background(), "project:stage1")).Should(Succeed())
+		Ω(cache.Touch(context.Background(), "project:stage2")).Should(Succeed())
+		Ω(cache.Remove(context.Background(), "project:stage2")).Should(Succeed())
+
+		accessTimestamps, err := cache.GetAccessTimestamps(context.Background())
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(accessTimestamps).Should(HaveLen(1))
+		Ω(accessTimestamps).Should(HaveKey("project:stage1"))
+	})
+
+	It("should remove the records of the removed images", func() {
+		oldAccessTimestamp := time.Now().Add(-lastUseCacheGCGracePeriod - time.Hour).Unix()
+		Ω(cache.writeRecord(&LastUseCacheRecord{ImageName: "project:removed", AccessTimestamp: oldAccessTimestamp})).Should(Succeed())
+		Ω(cache.writeRecord(&LastUseCacheRecord{ImageName: "project:local", AccessTimestamp: oldAccessTimestamp})).Should(Succeed())
+		Ω(cache.Touch(context.Background(), "project:pulling")).Should(Succeed())
+
+		localImages := map[string]bool{"project:local": true}
+
+		Ω(cache.GC(context.Background(), localImages, true)).Should(Succeed())
+		Ω(cache.constructFilePathForImage("project:removed")).Should(BeAnExistingFile())
+
+		Ω(cache.GC(context.Background(), localImages, false)).Should(Succeed())
+		Ω(cache.constructFilePathForImage("project:removed")).ShouldNot(BeAnExistingFile())
+		Ω(cache.constructFilePathForImage("project:local")).Should(BeAnExistingFile())
+		Ω(cache.constructFilePathForImage("project:pulling")).Should(BeAnExistingFile())
+	})
+})
@@ -7,8 +7,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/werf/lockgate"
 	"github.com/werf/logboek"
 
@@ -18,11 +20,18 @@ import (
 )
 
 const (
-	ManifestCacheVersion = "4"
+	ManifestCacheVersion = "5"
+
+	DefaultManifestCacheMaxSize    = 100 * 1024 * 1024
+	DefaultManifestCacheMaxEntries = 20000
 )
 
 type ManifestCache struct {
 	CacheDir string
+
+	// MaxSize and MaxEntries are the limits enforced by GC, zero value means no limit
+	MaxSize    int64
+	MaxEntries int
 }
 
 type ManifestCacheRecord struct {
@@ -31,7 +40,7 @@ type ManifestCacheRecord struct {
 }
 
 func NewManifestCache(cacheDir string) *ManifestCache {
-	return &ManifestCache{CacheDir: cacheDir}
+	return &ManifestCache{CacheDir: cacheDir, MaxSize: DefaultManifestCacheMaxSize, MaxEntries: DefaultManifestCacheMaxEntries}
 }
 
 func (cache *ManifestCache) GetImageInfo(ctx context.Context, storageName, imageName string) (*Info, error) {
@@ -136,3 +145,169 @@ func (cache *ManifestCache) unlock(lock lockgate.LockHandle) error {
 	}
 	return nil
 }
+
+type ManifestCacheUsage struct {
+	Size    int64
+	Entries int
+}
+
+type manifestCacheEntry struct {
+	filePath        string
+	storageDirName  string
+	imageName       string
+	size            int64
+	accessTimestamp int64
+}
+
+// GC removes the least recently used records until the cache fits MaxSize and MaxEntries limits.
+// Records locked by other processes are skipped.
+func (cache *ManifestCache) GC(ctx context.Context, dryRun bool) error {
+	entries, err := cache.listEntries()
+	if err != nil {
+		return err
+	}
+
+	usage := manifestCacheUsage(entries)
+	logboek.Context(ctx).LogF("Manifest cache %s: %d entries, %s (limits: %s)\n", cache.CacheDir, usage.Entries, units.BytesSize(float64(usage.Size)), cache.limitsString())
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].accessTimestamp < entries[j].accessTimestamp
+	})
+
+	var freedSize int64
+	var freedEntries int
+	for _, entry := range entries {
+		if !cache.isOverLimits(usage) {
+			break
+		}
+
+		if removed, err := cache.removeEntry(ctx, entry, dryRun); err != nil {
+			return err
+		} else if !removed {
+			continue
+		}
+
+		usage.Size -= entry.size
+		usage.Entries--
+		freedSize += entry.size
+		freedEntries++
+	}
+
+	if freedEntries > 0 {
+		logboek.Context(ctx).LogF("Manifest cache: removed %d least recently used entries, %s\n", freedEntries, units.BytesSize(float64(freedSize)))
+	}
+
+	return nil
+}
+
+func (cache *ManifestCache) isOverLimits(usage ManifestCacheUsage) bool {
+	return (cache.MaxSize > 0 && usage.Size > cache.MaxSize) || (cache.MaxEntries > 0 && usage.Entries > cache.MaxEntries)
+}
+
+func (cache *ManifestCache) limitsString() string {
+	maxSize, maxEntries := "unlimited size", "unlimited entries"
+	if cache.MaxSize > 0 {
+		maxSize = units.BytesSize(float64(cache.MaxSize))
+	}
+	if cache.MaxEntries > 0 {
+		maxEntries = fmt.Sprintf("%d entries", cache.MaxEntries)
+	}
+
+	return fmt.Sprintf("%s, %s", maxEntries, maxSize)
+}
+
+func (cache *ManifestCache) removeEntry(ctx context.Context, entry *manifestCacheEntry, dryRun bool) (bool, error) {
+	// invalid records have no image name and are reset on the next access anyway, so there is nothing to lock
+	if entry.imageName != "" {
+		lockName := fmt.Sprintf("manifest_cache.%s.%s", entry.storageDirName, entry.imageName)
+		isAcquired, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{NonBlocking: true})
+		if err != nil {
+			return false, fmt.Errorf("cannot acquire %s host lock: %s", lockName, err)
+		}
+
+		if !isAcquired {
+			logboek.Context(ctx).Debug().LogF("Ignore manifest cache record %s used by another process\n", entry.filePath)
+			return false, nil
+		}
+		defer cache.unlock(lock)
+	}
+
+	logboek.Context(ctx).LogLn(entry.filePath)
+
+	if dryRun {
+		return true, nil
+	}
+
+	if err := os.Remove(entry.filePath); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("unable to remove %s: %s", entry.filePath, err)
+	}
+
+	return true, nil
+}
+
+func (cache *ManifestCache) listEntries() ([]*manifestCacheEntry, error) {
+	if _, err := os.Stat(cache.CacheDir); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error accessing %s: %s", cache.CacheDir, err)
+	}
+
+	storageDirs, err := ioutil.ReadDir(cache.CacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading dir %s: %s", cache.CacheDir, err)
+	}
+
+	var res []*manifestCacheEntry
+	for _, storageDir := range storageDirs {
+		if !storageDir.IsDir() {
+			continue
+		}
+
+		storageDirPath := filepath.Join(cache.CacheDir, storageDir.Name())
+		files, err := ioutil.ReadDir(storageDirPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading dir %s: %s", storageDirPath, err)
+		}
+
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+
+			entry := &manifestCacheEntry{
+				filePath:        filepath.Join(storageDirPath, file.Name()),
+				storageDirName:  storageDir.Name(),
+				size:            file.Size(),
+				accessTimestamp: file.ModTime().Unix(),
+			}
+
+			if dataBytes, err := ioutil.ReadFile(entry.filePath); os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("error reading %s: %s", entry.filePath, err)
+			} else {
+				record := &ManifestCacheRecord{}
+				if err := json.Unmarshal(dataBytes, record); err == nil && record.Info != nil {
+					entry.imageName = record.Info.Name
+					entry.accessTimestamp = record.AccessTimestamp
+				} else {
+					// invalid records are removed first
+					entry.accessTimestamp = 0
+				}
+			}
+
+			res = append(res, entry)
+		}
+	}
+
+	return res, nil
+}
+
+func manifestCacheUsage(entries []*manifestCacheEntry) ManifestCacheUsage {
+	usage := ManifestCacheUsage{Entries: len(entries)}
+	for _, entry := range entries {
+		usage.Size += entry.size
+	}
+
+	return usage
+}
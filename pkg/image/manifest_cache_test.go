@@ -0,0 +1,108 @@
+package image
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+var _ = Describe("manifest cache GC", func() {
+	var tmpDir string
+	var cache *ManifestCache
+
+	storeRecord := func(imageName string, accessTimestamp int64) {
+		Ω(cache.writeRecord("repo", &ManifestCacheRecord{AccessTimestamp: accessTimestamp, Info: &Info{Name: imageName}})).Should(Succeed())
+	}
+
+	recordExists := func(imageName string) bool {
+		_, err := os.Stat(cache.constructFilePathForImage("repo", imageName))
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "werf-manifest-cache-test")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werf.Init(filepath.Join(tmpDir, "tmp"), filepath.Join(tmpDir, "home"))).Should(Succeed())
+
+		cache = NewManifestCache(filepath.Join(tmpDir, "manifests"))
+		cache.MaxSize, cache.MaxEntries = 0, 0
+
+		storeRecord("old", 100)
+		storeRecord("middle", 200)
+		storeRecord("new", 300)
+	})
+
+	AfterEach(func() {
+		Ω(os.RemoveAll(tmpDir)).Should(Succeed())
+	})
+
+	It("should keep all the records within the limits", func() {
+		cache.MaxEntries = 3
+
+		Ω(cache.GC(context.Background(), false)).Should(Succeed())
+		Ω(recordExists("old")).Should(BeTrue())
+		Ω(recordExists("middle")).Should(BeTrue())
+		Ω(recordExists("new")).Should(BeTrue())
+	})
+
+	It("should remove the least recently used records over the entries limit", func() {
+		cache.MaxEntries = 1
+
+		Ω(cache.GC(context.Background(), false)).Should(Succeed())
+		Ω(recordExists("old")).Should(BeFalse())
+		Ω(recordExists("middle")).Should(BeFalse())
+		Ω(recordExists("new")).Should(BeTrue())
+	})
+
+	It("should remove the least recently used records over the size limit", func() {
+		entries, err := cache.listEntries()
+		Ω(err).ShouldNot(HaveOccurred())
+		usage := manifestCacheUsage(entries)
+		cache.MaxSize = usage.Size - 1
+
+		Ω(cache.GC(context.Background(), false)).Should(Succeed())
+		Ω(recordExists("old")).Should(BeFalse())
+		Ω(recordExists("middle")).Should(BeTrue())
+		Ω(recordExists("new")).Should(BeTrue())
+	})
+
+	It("should keep the record accessed recently", func() {
+		cache.MaxEntries = 2
+
+		info, err := cache.GetImageInfo(context.Background(), "repo", "old")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(info.Name).Should(Equal("old"))
+
+		Ω(cache.GC(context.Background(), false)).Should(Succeed())
+		Ω(recordExists("old")).Should(BeTrue())
+		Ω(recordExists("middle")).Should(BeFalse())
+		Ω(recordExists("new")).Should(BeTrue())
+	})
+
+	It("should remove invalid records first", func() {
+		cache.MaxEntries = 3
+
+		invalidRecordPath := filepath.Join(cache.CacheDir, "repo", "invalid")
+		Ω(ioutil.WriteFile(invalidRecordPath, []byte("{"), 0644)).Should(Succeed())
+
+		Ω(cache.GC(context.Background(), false)).Should(Succeed())
+		_, err := os.Stat(invalidRecordPath)
+		Ω(os.IsNotExist(err)).Should(BeTrue())
+		Ω(recordExists("old")).Should(BeTrue())
+	})
+
+	It("should not remove anything in dry run mode", func() {
+		cache.MaxEntries = 1
+
+		Ω(cache.GC(context.Background(), true)).Should(Succeed())
+		Ω(recordExists("old")).Should(BeTrue())
+		Ω(recordExists("middle")).Should(BeTrue())
+	})
+})
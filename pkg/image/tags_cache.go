@@ -0,0 +1,237 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/slug"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	TagsCacheVersion = "1"
+
+	// TagsCacheTTL is the time since the last access after which the record is removed by GC
+	TagsCacheTTL = 7 * 24 * time.Hour
+	// The access timestamp of the unchanged record is updated not more often than once in the period to avoid writing on every listing
+	tagsCacheAccessTimestampUpdatePeriod = time.Hour
+)
+
+// TagsCache stores the pages of the repo tags list with ETag and Last-Modified headers to revalidate the pages with conditional requests
+type TagsCache struct {
+	CacheDir string
+}
+
+type TagsCacheRecord struct {
+	AccessTimestamp int64
+	Repository      string
+	Pages           []*TagsCachePage
+}
+
+type TagsCachePage struct {
+	URL          string
+	NextURL      string
+	ETag         string
+	LastModified string
+	Tags         []string
+}
+
+func NewTagsCache(cacheDir string) *TagsCache {
+	return &TagsCache{CacheDir: cacheDir}
+}
+
+func (cache *TagsCache) GetPages(ctx context.Context, repository string) ([]*TagsCachePage, error) {
+	logProcess := logboek.Context(ctx).Debug().LogProcess("-- TagsCache.GetPages %s", repository)
+	logProcess.Start()
+	defer logProcess.End()
+
+	if lock, err := cache.lock(ctx, repository); err != nil {
+		return nil, err
+	} else {
+		defer cache.unlock(lock)
+	}
+
+	if record, err := cache.readRecord(ctx, repository); err != nil {
+		return nil, err
+	} else if record != nil {
+		return record.Pages, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func (cache *TagsCache) StorePages(ctx context.Context, repository string, pages []*TagsCachePage) error {
+	logProcess := logboek.Context(ctx).Debug().LogProcess("-- TagsCache.StorePages %s", repository)
+	logProcess.Start()
+	defer logProcess.End()
+
+	if lock, err := cache.lock(ctx, repository); err != nil {
+		return err
+	} else {
+		defer cache.unlock(lock)
+	}
+
+	oldRecord, err := cache.readRecord(ctx, repository)
+	if err != nil {
+		return err
+	}
+
+	if oldRecord != nil && reflect.DeepEqual(oldRecord.Pages, pages) && time.Since(time.Unix(oldRecord.AccessTimestamp, 0)) < tagsCacheAccessTimestampUpdatePeriod {
+		return nil
+	}
+
+	record := &TagsCacheRecord{
+		AccessTimestamp: time.Now().Unix(),
+		Repository:      repository,
+		Pages:           pages,
+	}
+	return cache.writeRecord(record)
+}
+
+// GC removes the records which have not been accessed for TagsCacheTTL, invalid records are removed as well.
+// Records locked by other processes are skipped.
+func (cache *TagsCache) GC(ctx context.Context, dryRun bool) error {
+	if _, err := os.Stat(cache.CacheDir); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error accessing %s: %s", cache.CacheDir, err)
+	}
+
+	files, err := ioutil.ReadDir(cache.CacheDir)
+	if err != nil {
+		return fmt.Errorf("error reading dir %s: %s", cache.CacheDir, err)
+	}
+
+	var removedRecords int
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		filePath := filepath.Join(cache.CacheDir, file.Name())
+
+		record := &TagsCacheRecord{}
+		if dataBytes, err := ioutil.ReadFile(filePath); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("error reading %s: %s", filePath, err)
+		} else if err := json.Unmarshal(dataBytes, record); err != nil || record.Repository == "" {
+			// invalid records are reset on the next access anyway, so there is nothing to lock
+			record = nil
+		} else if time.Since(time.Unix(record.AccessTimestamp, 0)) < TagsCacheTTL {
+			continue
+		}
+
+		if record != nil {
+			lockName := fmt.Sprintf("tags_cache.%s", slug.Slug(record.Repository))
+			isAcquired, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{NonBlocking: true})
+			if err != nil {
+				return fmt.Errorf("cannot acquire %s host lock: %s", lockName, err)
+			}
+
+			if !isAcquired {
+				logboek.Context(ctx).Debug().LogF("Ignore tags cache record %s used by another process\n", filePath)
+				continue
+			}
+
+			err = cache.removeRecordFile(ctx, filePath, dryRun)
+			_ = cache.unlock(lock)
+			if err != nil {
+				return err
+			}
+		} else if err := cache.removeRecordFile(ctx, filePath, dryRun); err != nil {
+			return err
+		}
+
+		removedRecords++
+	}
+
+	if removedRecords > 0 {
+		logboek.Context(ctx).LogF("Tags cache: removed %d records not used for %s\n", removedRecords, TagsCacheTTL)
+	}
+
+	return nil
+}
+
+func (cache *TagsCache) removeRecordFile(ctx context.Context, filePath string, dryRun bool) error {
+	logboek.Context(ctx).LogLn(filePath)
+
+	if dryRun {
+		return nil
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove %s: %s", filePath, err)
+	}
+
+	return nil
+}
+
+func (cache *TagsCache) readRecord(ctx context.Context, repository string) (*TagsCacheRecord, error) {
+	filePath := cache.constructFilePathForRepository(repository)
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error accessing %s: %s", filePath, err)
+	}
+
+	if dataBytes, err := ioutil.ReadFile(filePath); err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", filePath, err)
+	} else {
+		record := &TagsCacheRecord{}
+		if err := json.Unmarshal(dataBytes, record); err != nil {
+			logboek.Context(ctx).Error().LogF("WARNING: invalid tags cache json record in file %s: %s: resetting record\n", filePath, err)
+			return nil, nil
+		}
+		return record, nil
+	}
+}
+
+func (cache *TagsCache) writeRecord(record *TagsCacheRecord) error {
+	filePath := cache.constructFilePathForRepository(record.Repository)
+
+	dirPath := filepath.Dir(filePath)
+	if err := os.MkdirAll(dirPath, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating dir %s: %s", dirPath, err)
+	}
+
+	if dataBytes, err := json.Marshal(record); err != nil {
+		return fmt.Errorf("error marshalling json: %s", err)
+	} else {
+		if err := ioutil.WriteFile(filePath, append(dataBytes, []byte("\n")...), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %s", filePath, err)
+		}
+		return nil
+	}
+}
+
+func (cache *TagsCache) constructFilePathForRepository(repository string) string {
+	return filepath.Join(cache.CacheDir, util.Sha256Hash(repository))
+}
+
+func (cache *TagsCache) lock(ctx context.Context, repository string) (lockgate.LockHandle, error) {
+	lockName := fmt.Sprintf("tags_cache.%s", slug.Slug(repository))
+	if _, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{}); err != nil {
+		return lockgate.LockHandle{}, fmt.Errorf("cannot acquire %s host lock: %s", lockName, err)
+	} else {
+		return lock, nil
+	}
+}
+
+func (cache *TagsCache) unlock(lock lockgate.LockHandle) error {
+	if err := werf.ReleaseHostLock(lock); err != nil {
+		return fmt.Errorf("cannot release %s host lock: %s", lock.LockName, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package image
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+var _ = Describe("tags cache", func() {
+	var tmpDir string
+	var cache *TagsCache
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "werf-tags-cache-test")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werf.Init(filepath.Join(tmpDir, "tmp"), filepath.Join(tmpDir, "home"))).Should(Succeed())
+
+		cache = NewTagsCache(filepath.Join(tmpDir, "tags"))
+	})
+
+	AfterEach(func() {
+		Ω(os.RemoveAll(tmpDir)).Should(Succeed())
+	})
+
+	It("should not rewrite the unchanged record", func() {
+		pages := []*TagsCachePage{{URL: "url", Tags: []string{"a"}}}
+		Ω(cache.StorePages(context.Background(), "repo", pages)).Should(Succeed())
+
+		filePath := cache.constructFilePathForRepository("repo")
+		oldTime := time.Now().Add(-time.Minute)
+		Ω(os.Chtimes(filePath, oldTime, oldTime)).Should(Succeed())
+
+		_, err := cache.GetPages(context.Background(), "repo")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cache.StorePages(context.Background(), "repo", pages)).Should(Succeed())
+
+		stat, err := os.Stat(filePath)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(stat.ModTime().Unix()).Should(Equal(oldTime.Unix()))
+	})
+
+	It("should remove the records not used for TTL", func() {
+		Ω(cache.writeRecord(&TagsCacheRecord{Repository: "old", AccessTimestamp: time.Now().Add(-TagsCacheTTL - time.Hour).Unix()})).Should(Succeed())
+		Ω(cache.writeRecord(&TagsCacheRecord{Repository: "new", AccessTimestamp: time.Now().Unix()})).Should(Succeed())
+
+		Ω(cache.GC(context.Background(), false)).Should(Succeed())
+
+		_, err := os.Stat(cache.constructFilePathForRepository("old"))
+		Ω(os.IsNotExist(err)).Should(BeTrue())
+		_, err = os.Stat(cache.constructFilePathForRepository("new"))
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,186 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// AesGcmFormatHeader prefixes every value encrypted with AesGcmSecret: a marker byte that never starts
+// the legacy AES-CBC format (its first byte is the iv size 0x10) followed by the format version.
+var AesGcmFormatHeader = []byte{0xff, 0x02}
+
+// AesGcmNamedKeyFormatHeader prefixes values encrypted with a named key (e.g. the environment key),
+// the header is followed by the key name length byte and the key name.
+var AesGcmNamedKeyFormatHeader = []byte{0xff, 0x03}
+
+type AesGcmSecret struct {
+	AEAD    cipher.AEAD
+	KeyName string
+}
+
+func NewAesGcmSecret(key []byte) (*AesGcmSecret, error) {
+	key, err := hexToBinary(key)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AesGcmSecret{AEAD: aead}, nil
+}
+
+func NewNamedAesGcmSecret(key []byte, keyName string) (*AesGcmSecret, error) {
+	if len(keyName) == 0 || len(keyName) > 255 {
+		return nil, fmt.Errorf("key name length should be from 1 to 255 bytes")
+	}
+
+	s, err := NewAesGcmSecret(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.KeyName = keyName
+
+	return s, nil
+}
+
+func (s *AesGcmSecret) header() []byte {
+	if s.KeyName == "" {
+		return AesGcmFormatHeader
+	}
+
+	var header []byte
+	header = append(header, AesGcmNamedKeyFormatHeader...)
+	header = append(header, byte(len(s.KeyName)))
+	header = append(header, s.KeyName...)
+
+	return header
+}
+
+func (s *AesGcmSecret) Encrypt(data []byte) ([]byte, error) {
+	nonce := make([]byte, s.AEAD.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	header := s.header()
+
+	var args []byte
+	args = append(args, header...)
+	args = append(args, nonce...)
+	args = s.AEAD.Seal(args, nonce, data, header)
+
+	result := make([]byte, hex.EncodedLen(len(args)))
+	hex.Encode(result, args)
+
+	return result, nil
+}
+
+func (s *AesGcmSecret) Decrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	dataToExtract, err := hexToBinary(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyName, ok := getAesGcmKeyName(dataToExtract); ok && keyName != s.KeyName {
+		if keyName == "" {
+			return nil, fmt.Errorf("data is encrypted with the common key")
+		}
+
+		return nil, fmt.Errorf("data is encrypted with the %q key", keyName)
+	}
+
+	header := s.header()
+	headerSize := len(header)
+	nonceSize := s.AEAD.NonceSize()
+	minimalDataBinarySize := headerSize + nonceSize + s.AEAD.Overhead()
+	minimalDataSize := minimalDataBinarySize * 2
+	if len(dataToExtract) < minimalDataBinarySize {
+		return nil, fmt.Errorf("minimum required data length: '%v'", minimalDataSize)
+	}
+
+	if !bytes.Equal(dataToExtract[:headerSize], header) {
+		return nil, fmt.Errorf("unsupported data format")
+	}
+
+	nonce := dataToExtract[headerSize : headerSize+nonceSize]
+	cipherText := dataToExtract[headerSize+nonceSize:]
+
+	result, err := s.AEAD.Open(nil, nonce, cipherText, header)
+	if err != nil {
+		return nil, fmt.Errorf("data authentication failed: data is corrupted or encrypted with another key")
+	}
+
+	return result, nil
+}
+
+// IsAesGcmEncryptedData reports whether hex encoded data has one of the AesGcmSecret format headers.
+func IsAesGcmEncryptedData(data []byte) bool {
+	headerSize := hex.EncodedLen(len(AesGcmFormatHeader))
+	if len(data) < headerSize {
+		return false
+	}
+
+	header := string(data[:headerSize])
+	return header == hex.EncodeToString(AesGcmFormatHeader) || header == hex.EncodeToString(AesGcmNamedKeyFormatHeader)
+}
+
+// GetEncryptedDataKeyName returns the name of the key hex encoded data is encrypted with or an empty string for data encrypted with the common key.
+func GetEncryptedDataKeyName(data []byte) string {
+	if !IsAesGcmEncryptedData(data) {
+		return ""
+	}
+
+	// only the header and the key name are decoded
+	prefixSize := hex.EncodedLen(len(AesGcmNamedKeyFormatHeader) + 1 + 255)
+	if len(data) < prefixSize {
+		prefixSize = len(data) &^ 1
+	}
+
+	prefix, err := hexToBinary(data[:prefixSize])
+	if err != nil {
+		return ""
+	}
+
+	keyName, _ := getAesGcmKeyName(prefix)
+	return keyName
+}
+
+func getAesGcmKeyName(data []byte) (string, bool) {
+	headerSize := len(AesGcmNamedKeyFormatHeader)
+	if len(data) < headerSize {
+		return "", false
+	}
+
+	if bytes.Equal(data[:headerSize], AesGcmFormatHeader) {
+		return "", true
+	}
+
+	if !bytes.Equal(data[:headerSize], AesGcmNamedKeyFormatHeader) || len(data) < headerSize+1 {
+		return "", false
+	}
+
+	keyNameSize := int(data[headerSize])
+	if len(data) < headerSize+1+keyNameSize {
+		return "", false
+	}
+
+	return string(data[headerSize+1 : headerSize+1+keyNameSize]), true
+}
@@ -68,6 +68,16 @@ func (s *AesSecret) Encrypt(data []byte) ([]byte, error) {
 }
 
 func (s *AesSecret) Decrypt(data []byte) ([]byte, error) {
+	return s.decrypt(data, unpad)
+}
+
+// DecryptStrict checks the padding completely, so that data decrypted with a wrong key is rejected in most cases.
+// Only the authenticated format reliably detects a wrong key, so the check is used when the legacy data is rewritten.
+func (s *AesSecret) DecryptStrict(data []byte) ([]byte, error) {
+	return s.decrypt(data, unpadStrict)
+}
+
+func (s *AesSecret) decrypt(data []byte, unpadFunc func(data []byte) ([]byte, error)) ([]byte, error) {
 	if len(data) == 0 {
 		return data, nil
 	}
@@ -96,7 +106,7 @@ func (s *AesSecret) Decrypt(data []byte) ([]byte, error) {
 	mode := cipher.NewCBCDecrypter(s.CipherBlock, iv)
 	mode.CryptBlocks(cipherText, cipherText)
 
-	result, err := unpad(cipherText)
+	result, err := unpadFunc(cipherText)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +131,23 @@ func unpad(data []byte) ([]byte, error) {
 	return data[:(length - unpadding)], nil
 }
 
+func unpadStrict(data []byte) ([]byte, error) {
+	length := len(data)
+	unpadding := int(data[length-1])
+
+	if unpadding == 0 || unpadding > aes.BlockSize || unpadding > length {
+		return nil, fmt.Errorf("inconsistent data, unpad failed")
+	}
+
+	for _, b := range data[length-unpadding:] {
+		if int(b) != unpadding {
+			return nil, fmt.Errorf("inconsistent data, unpad failed")
+		}
+	}
+
+	return data[:(length - unpadding)], nil
+}
+
 func hexToBinary(data []byte) ([]byte, error) {
 	result := make([]byte, hex.DecodedLen(len(data)))
 	if _, err := hex.Decode(result, data); err != nil {
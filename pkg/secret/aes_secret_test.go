@@ -1,6 +1,8 @@
 package secret
 
 import (
+	"bytes"
+	"crypto/aes"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
@@ -181,3 +183,309 @@ func TestAesSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestUnpadStrict(t *testing.T) {
+	block := bytes.Repeat([]byte("a"), aes.BlockSize)
+
+	for _, test := range []struct {
+		name          string
+		data          []byte
+		expected      []byte
+		expectedError bool
+	}{
+		{name: "one byte padding", data: append(bytes.Repeat([]byte("a"), aes.BlockSize-1), 1), expected: bytes.Repeat([]byte("a"), aes.BlockSize-1)},
+		{name: "full block padding", data: append(block, bytes.Repeat([]byte{aes.BlockSize}, aes.BlockSize)...), expected: block},
+		{name: "zero padding", data: append(bytes.Repeat([]byte("a"), aes.BlockSize-1), 0), expectedError: true},
+		{name: "padding longer than block", data: append(block, bytes.Repeat([]byte{aes.BlockSize + 1}, aes.BlockSize)...), expectedError: true},
+		{name: "inconsistent padding bytes", data: append(bytes.Repeat([]byte("a"), aes.BlockSize-2), 1, 2), expectedError: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := unpadStrict(test.data)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("Expected error, got '%s'", result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(result, test.expected) {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestAesGcmSecret(t *testing.T) {
+	tests := []string{"", "value"}
+
+	for _, size := range supportedKeySizes {
+		randomBinary := make([]byte, size)
+		if _, err := io.ReadFull(rand.Reader, randomBinary); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		key := []byte(hex.EncodeToString(randomBinary))
+
+		s, err := NewAesGcmSecret(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run(fmt.Sprintf("%v|%v", size, string(key)), func(t *testing.T) {
+			for _, test := range tests {
+				t.Run(test, func(t *testing.T) {
+					encodedData, err := s.Encrypt([]byte(test))
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if !IsAesGcmEncryptedData(encodedData) {
+						t.Errorf("Got data without format header '%s'", encodedData)
+					}
+
+					result, err := s.Decrypt(encodedData)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if test != string(result) {
+						t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test, result)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestAesGcmSecret_Extract_negative(t *testing.T) {
+	s, err := NewAesGcmSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := s.Encrypt([]byte("flant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamperedData := []byte(string(encodedData))
+	if tamperedData[len(tamperedData)-1] == '0' {
+		tamperedData[len(tamperedData)-1] = '1'
+	} else {
+		tamperedData[len(tamperedData)-1] = '0'
+	}
+
+	tests := []struct {
+		name         string
+		encodedData  []byte
+		errorMessage string
+	}{
+		{
+			name:         "minimum required data length",
+			encodedData:  []byte("ff02"),
+			errorMessage: "minimum required data length: '60'",
+		},
+		{
+			name:         "unsupported data format",
+			encodedData:  []byte("10000f13a718d019612ab8ad30d9bec8e2c09df0f2d168c179bef954e78371bf6a5a"),
+			errorMessage: "unsupported data format",
+		},
+		{
+			name:         "tampered data",
+			encodedData:  tamperedData,
+			errorMessage: "data authentication failed: data is corrupted or encrypted with another key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err = s.Decrypt(test.encodedData)
+			if err == nil {
+				t.Errorf("Expected error: %s", test.errorMessage)
+			} else if err.Error() != test.errorMessage {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.errorMessage, err.Error())
+			}
+		})
+	}
+}
+
+func TestVersionedSecret(t *testing.T) {
+	legacyData := []byte("10000f13a718d019612ab8ad30d9bec8e2c09df0f2d168c179bef954e78371bf6a5a")
+
+	for _, test := range []struct {
+		name                 string
+		encryptWithCurrent   bool
+		rejectLegacy         bool
+		expectAesGcmData     bool
+		expectLegacyRejected bool
+	}{
+		{name: "default", expectAesGcmData: false},
+		{name: "authenticated format", encryptWithCurrent: true, expectAesGcmData: true},
+		{name: "strict mode", encryptWithCurrent: true, rejectLegacy: true, expectAesGcmData: true, expectLegacyRejected: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewVersionedSecret(AesSecretKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.EncryptWithCurrent = test.encryptWithCurrent
+			s.RejectLegacy = test.rejectLegacy
+
+			encodedData, err := s.Encrypt([]byte("value"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if IsAesGcmEncryptedData(encodedData) != test.expectAesGcmData {
+				t.Errorf("Got data in unexpected format '%s'", encodedData)
+			}
+
+			if result, err := s.Decrypt(encodedData); err != nil {
+				t.Fatal(err)
+			} else if string(result) != "value" {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "value", result)
+			}
+
+			_, err = s.Decrypt(legacyData)
+			if test.expectLegacyRejected && !IsLegacyFormatError(err) {
+				t.Errorf("Expected legacy format error, got: %v", err)
+			} else if !test.expectLegacyRejected && err != nil {
+				t.Error(err)
+			}
+
+			if _, changed, err := s.Migrate(legacyData); err != nil || !changed {
+				t.Errorf("Expected legacy data to be migrated, got: changed %v, %v", changed, err)
+			}
+		})
+	}
+}
+
+func TestVersionedSecret_Migrate(t *testing.T) {
+	s, err := NewVersionedSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyData := []byte("10000f13a718d019612ab8ad30d9bec8e2c09df0f2d168c179bef954e78371bf6a5a")
+	expectedData, err := s.Decrypt(legacyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migratedData, changed, err := s.Migrate(legacyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !changed || !IsAesGcmEncryptedData(migratedData) {
+		t.Fatalf("Expected legacy data to be migrated, got '%s'", migratedData)
+	}
+
+	result, err := s.Decrypt(migratedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(expectedData) != string(result) {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expectedData, result)
+	}
+
+	remigratedData, changed, err := s.Migrate(migratedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed || string(remigratedData) != string(migratedData) {
+		t.Errorf("Expected migrated data to be left as is, got '%s'", remigratedData)
+	}
+}
+
+func TestNamedAesGcmSecret(t *testing.T) {
+	s, err := NewNamedAesGcmSecret(AesSecretKey, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := s.Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keyName := GetEncryptedDataKeyName(encodedData); keyName != "production" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "production", keyName)
+	}
+
+	result, err := s.Decrypt(encodedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "value" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "value", result)
+	}
+
+	commonSecret, err := NewAesGcmSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := commonSecret.Decrypt(encodedData); err == nil || err.Error() != `data is encrypted with the "production" key` {
+		t.Errorf("Expected error: data is encrypted with the \"production\" key, got: %v", err)
+	}
+
+	commonEncodedData, err := commonSecret.Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keyName := GetEncryptedDataKeyName(commonEncodedData); keyName != "" {
+		t.Errorf("Expected no key name, got '%s'", keyName)
+	}
+
+	if _, err := s.Decrypt(commonEncodedData); err == nil || err.Error() != "data is encrypted with the common key" {
+		t.Errorf("Expected error: data is encrypted with the common key, got: %v", err)
+	}
+}
+
+func TestEnvSecret(t *testing.T) {
+	commonSecret, err := NewSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envSecret, err := NewNamedVersionedSecret([]byte("5c6f3a3d1c2b4e9d8a7f6e5d4c3b2a19"), "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commonData, err := commonSecret.Encrypt([]byte("common"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &EnvSecret{Common: commonSecret, Env: envSecret, EnvName: "production", EncryptWithEnv: true}
+
+	envData, err := s.Encrypt([]byte("env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keyName := GetEncryptedDataKeyName(envData); keyName != "production" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "production", keyName)
+	}
+
+	for data, expected := range map[string]string{string(commonData): "common", string(envData): "env"} {
+		result, err := s.Decrypt([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(result) != expected {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expected, result)
+		}
+	}
+}
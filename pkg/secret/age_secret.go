@@ -0,0 +1,80 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// AgeSecret encrypts data to the list of age recipients (public keys), so that any of the corresponding identities (private keys) can decrypt it.
+// Encrypted data is an ASCII armored age file that can also be processed with the age cli.
+type AgeSecret struct {
+	Recipients []age.Recipient
+	Identities []age.Identity
+}
+
+func NewAgeSecret(recipients []age.Recipient, identities []age.Identity) *AgeSecret {
+	return &AgeSecret{Recipients: recipients, Identities: identities}
+}
+
+func ParseAgeRecipients(data []byte) ([]age.Recipient, error) {
+	return age.ParseRecipients(bytes.NewReader(data))
+}
+
+func ParseAgeIdentities(data []byte) ([]age.Identity, error) {
+	return age.ParseIdentities(bytes.NewReader(data))
+}
+
+func (s *AgeSecret) Encrypt(data []byte) ([]byte, error) {
+	if len(s.Recipients) == 0 {
+		return nil, fmt.Errorf("no age recipients specified")
+	}
+
+	buf := bytes.NewBuffer(nil)
+	armorWriter := armor.NewWriter(buf)
+
+	w, err := age.Encrypt(armorWriter, s.Recipients...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := armorWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *AgeSecret) Decrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	if len(s.Identities) == 0 {
+		return nil, fmt.Errorf("no age identities specified")
+	}
+
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(strings.TrimSpace(string(data))+"\n")), s.Identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
+
+// IsAgeEncryptedData reports whether data is an ASCII armored age file.
+func IsAgeEncryptedData(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header))
+}
@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestAgeSecret(t *testing.T) {
+	firstIdentity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondIdentity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipients, err := ParseAgeRecipients([]byte("# team\n" + firstIdentity.Recipient().String() + "\n\n" + secondIdentity.Recipient().String() + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := NewAgeSecret(recipients, nil).Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsAgeEncryptedData(encodedData) {
+		t.Fatalf("Got data without age header '%s'", encodedData)
+	}
+
+	for _, identity := range []age.Identity{firstIdentity, secondIdentity} {
+		result, err := NewAgeSecret(nil, []age.Identity{identity}).Decrypt(encodedData)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(result) != "value" {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "value", result)
+		}
+	}
+
+	strangerIdentity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAgeSecret(nil, []age.Identity{strangerIdentity}).Decrypt(encodedData); err == nil {
+		t.Errorf("Expected error for identity that is not in recipients")
+	}
+
+	if _, err := NewAgeSecret(recipients, nil).Decrypt(encodedData); err == nil || err.Error() != "no age identities specified" {
+		t.Errorf("Expected error: no age identities specified, got: %v", err)
+	}
+}
+
+func TestMultiBackendSecret(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	symmetricSecret, err := NewSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ageSecret := NewAgeSecret([]age.Recipient{identity.Recipient()}, []age.Identity{identity})
+
+	symmetricData, err := symmetricSecret.Encrypt([]byte("symmetric"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &MultiBackendSecret{Symmetric: symmetricSecret, Age: ageSecret, EncryptWithAge: true}
+
+	ageData, err := s.Encrypt([]byte("age"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsAgeEncryptedData(ageData) {
+		t.Fatalf("Got data without age header '%s'", ageData)
+	}
+
+	for data, expected := range map[string]string{string(symmetricData): "symmetric", string(ageData): "age"} {
+		result, err := s.Decrypt([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(result) != expected {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expected, result)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package secret
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// KeyRingSecret encrypts data with the first key of the ring and decrypts data with the first key that fits,
+// which allows to switch to the new key gradually while the data encrypted with the old keys is still in use.
+// Only the authenticated format reliably detects a wrong key, so the legacy AES-CBC data is decrypted only with the last (the oldest) key of the ring.
+type KeyRingSecret struct {
+	Secrets []*VersionedSecret
+}
+
+func NewKeyRingSecret(keys [][]byte) (*KeyRingSecret, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("key ring should contain at least one key")
+	}
+
+	s := &KeyRingSecret{}
+	for ind, key := range keys {
+		ss, err := NewVersionedSecret(key)
+		if err != nil {
+			return nil, fmt.Errorf("key #%d: %s", ind+1, err)
+		}
+		// the ring relies on the authentication to find the key that fits
+		ss.EncryptWithCurrent = true
+
+		s.Secrets = append(s.Secrets, ss)
+	}
+
+	return s, nil
+}
+
+func (s *KeyRingSecret) Encrypt(data []byte) ([]byte, error) {
+	return s.Secrets[0].Encrypt(data)
+}
+
+func (s *KeyRingSecret) Decrypt(data []byte) ([]byte, error) {
+	result, _, err := s.DecryptWithKeyIndex(data)
+	return result, err
+}
+
+// DecryptWithKeyIndex decrypts data and returns the index of the key in the ring that has been used.
+func (s *KeyRingSecret) DecryptWithKeyIndex(data []byte) ([]byte, int, error) {
+	if len(data) == 0 {
+		return data, 0, nil
+	}
+
+	if !IsAesGcmEncryptedData(data) {
+		lastKeyIndex := len(s.Secrets) - 1
+		result, err := s.Secrets[lastKeyIndex].Decrypt(data)
+		if err != nil {
+			return nil, -1, err
+		}
+
+		return result, lastKeyIndex, nil
+	}
+
+	var firstErr error
+	for ind, ss := range s.Secrets {
+		result, err := ss.Decrypt(data)
+		if err == nil {
+			return result, ind, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if len(s.Secrets) == 1 {
+		return nil, -1, firstErr
+	}
+
+	return nil, -1, fmt.Errorf("data cannot be decrypted with any of %d keys: %s", len(s.Secrets), firstErr)
+}
+
+// Migrate re-encrypts legacy data decrypted with the last key of the ring with the current format and the first key.
+func (s *KeyRingSecret) Migrate(data []byte) ([]byte, bool, error) {
+	return migrateLegacyData(data, s.Secrets[len(s.Secrets)-1].Legacy, s.Secrets[0].Current)
+}
+
+// KeyFingerprint returns the short fingerprint of the key that allows to identify the key without revealing it.
+func KeyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])[:8]
+}
@@ -0,0 +1,109 @@
+package secret
+
+import (
+	"testing"
+)
+
+func TestKeyRingSecret(t *testing.T) {
+	newKey := []byte("a5b3f0e7d9c1b2a4f6e8d0c2b4a6f8e0")
+
+	oldSecret, err := NewVersionedSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSecret.EncryptWithCurrent = true
+
+	oldData, err := oldSecret.Encrypt([]byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring, err := NewKeyRingSecret([][]byte{newKey, AesSecretKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newData, err := ring.Encrypt([]byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		data             []byte
+		expected         string
+		expectedKeyIndex int
+	}{
+		{data: newData, expected: "new", expectedKeyIndex: 0},
+		{data: oldData, expected: "old", expectedKeyIndex: 1},
+	} {
+		result, keyIndex, err := ring.DecryptWithKeyIndex(tt.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(result) != tt.expected {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", tt.expected, result)
+		}
+
+		if keyIndex != tt.expectedKeyIndex {
+			t.Errorf("\n[EXPECTED KEY INDEX]: %d\n[GOT]: %d", tt.expectedKeyIndex, keyIndex)
+		}
+	}
+
+	if _, err := oldSecret.Decrypt(newData); err == nil {
+		t.Errorf("Expected error for data encrypted with the first key of the ring")
+	}
+
+	strangerRing, err := NewKeyRingSecret([][]byte{newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := strangerRing.Decrypt(oldData); err == nil {
+		t.Errorf("Expected error for data encrypted with the key that is not in the ring")
+	}
+
+	legacySecret, err := NewAesSecret(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyData, err := legacySecret.Encrypt([]byte("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the legacy data is decrypted only with the last key of the ring
+	result, keyIndex, err := ring.DecryptWithKeyIndex(legacyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "legacy" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "legacy", result)
+	}
+
+	if keyIndex != 1 {
+		t.Errorf("\n[EXPECTED KEY INDEX]: %d\n[GOT]: %d", 1, keyIndex)
+	}
+
+	reversedRing, err := NewKeyRingSecret([][]byte{AesSecretKey, newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := reversedRing.Decrypt(legacyData); err == nil && string(result) == "legacy" {
+		t.Errorf("Expected legacy data not to be decrypted with the first key of the ring")
+	}
+
+	singleKeyRing, err := NewKeyRingSecret([][]byte{AesSecretKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := singleKeyRing.Decrypt(legacyData); err != nil {
+		t.Fatal(err)
+	} else if string(result) != "legacy" {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", "legacy", result)
+	}
+}
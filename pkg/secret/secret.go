@@ -1,15 +1,200 @@
 package secret
 
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
 type Secret interface {
 	Encrypt(data []byte) ([]byte, error)
 	Decrypt(encodedData []byte) ([]byte, error)
 }
 
 func NewSecret(key []byte) (Secret, error) {
-	s, err := NewAesSecret(key)
+	s, err := NewVersionedSecret(key)
 	if err != nil {
 		return nil, err
 	}
 
 	return s, nil
 }
+
+// VersionedSecret decrypts both the authenticated AES-GCM and the legacy AES-CBC formats.
+// The legacy format is still used to encrypt data by default, so that the data can be decrypted by the older werf versions,
+// EncryptWithCurrent switches to the authenticated format and RejectLegacy rejects the legacy unauthenticated data.
+type VersionedSecret struct {
+	Current *AesGcmSecret
+	Legacy  *AesSecret
+
+	EncryptWithCurrent bool
+	RejectLegacy       bool
+}
+
+// IsAuthenticatedFormatEnabled returns true if the authenticated format should be used to encrypt data ($WERF_SECRET_AUTHENTICATED_FORMAT=1 or the strict mode).
+func IsAuthenticatedFormatEnabled() bool {
+	return os.Getenv("WERF_SECRET_AUTHENTICATED_FORMAT") == "1" || IsStrictFormatEnabled()
+}
+
+// IsStrictFormatEnabled returns true if the legacy unauthenticated data should be rejected ($WERF_SECRET_STRICT_FORMAT=1).
+func IsStrictFormatEnabled() bool {
+	return os.Getenv("WERF_SECRET_STRICT_FORMAT") == "1"
+}
+
+func NewVersionedSecret(key []byte) (*VersionedSecret, error) {
+	current, err := NewAesGcmSecret(key)
+	if err != nil {
+		return nil, err
+	}
+
+	legacy, err := NewAesSecret(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VersionedSecret{
+		Current:            current,
+		Legacy:             legacy,
+		EncryptWithCurrent: IsAuthenticatedFormatEnabled(),
+		RejectLegacy:       IsStrictFormatEnabled(),
+	}, nil
+}
+
+// NewNamedVersionedSecret creates VersionedSecret that tags encrypted data with the key name and only decrypts data tagged with the same name.
+// Only the authenticated format can be tagged, so it is always used to encrypt data.
+func NewNamedVersionedSecret(key []byte, keyName string) (*VersionedSecret, error) {
+	current, err := NewNamedAesGcmSecret(key, keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	legacy, err := NewAesSecret(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VersionedSecret{
+		Current:            current,
+		Legacy:             legacy,
+		EncryptWithCurrent: true,
+		RejectLegacy:       IsStrictFormatEnabled(),
+	}, nil
+}
+
+func (s *VersionedSecret) Encrypt(data []byte) ([]byte, error) {
+	if s.EncryptWithCurrent {
+		return s.Current.Encrypt(data)
+	}
+
+	return s.Legacy.Encrypt(data)
+}
+
+func (s *VersionedSecret) Decrypt(data []byte) ([]byte, error) {
+	if IsAesGcmEncryptedData(data) {
+		return s.Current.Decrypt(data)
+	}
+
+	if s.RejectLegacy && len(data) != 0 {
+		return nil, NewLegacyFormatError("the strict mode is enabled ($WERF_SECRET_STRICT_FORMAT)")
+	}
+
+	return s.Legacy.Decrypt(data)
+}
+
+// Migrate re-encrypts legacy data with the current format, data that already has the current format or is encrypted with age is returned as is.
+func (s *VersionedSecret) Migrate(data []byte) ([]byte, bool, error) {
+	return migrateLegacyData(data, s.Legacy, s.Current)
+}
+
+// migrateLegacyData decrypts legacy data with the strict padding check and encrypts it with the current format.
+// The result is decrypted back and compared with the source data before it is returned.
+func migrateLegacyData(data []byte, legacy *AesSecret, current *AesGcmSecret) ([]byte, bool, error) {
+	if len(data) == 0 || IsAesGcmEncryptedData(data) || IsAgeEncryptedData(data) {
+		return data, false, nil
+	}
+
+	decryptedData, err := legacy.DecryptStrict(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	encryptedData, err := current.Encrypt(decryptedData)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if checkData, err := current.Decrypt(encryptedData); err != nil {
+		return nil, false, fmt.Errorf("unable to decrypt migrated data: %s", err)
+	} else if !bytes.Equal(checkData, decryptedData) {
+		return nil, false, fmt.Errorf("migrated data does not match the source data")
+	}
+
+	return encryptedData, true, nil
+}
+
+// LegacyFormatError is returned when the legacy unauthenticated AES-CBC data cannot be used and should be migrated first
+type LegacyFormatError struct {
+	reason string
+}
+
+func NewLegacyFormatError(reason string) *LegacyFormatError {
+	return &LegacyFormatError{reason: reason}
+}
+
+func (err *LegacyFormatError) Error() string {
+	return fmt.Sprintf("legacy unauthenticated AES-CBC data is rejected (%s): migrate secrets to the authenticated format with werf helm secret migrate command first", err.reason)
+}
+
+func IsLegacyFormatError(err error) bool {
+	_, ok := err.(*LegacyFormatError)
+	return ok
+}
+
+// MultiBackendSecret encrypts data with the age backend when EncryptWithAge is set and with the symmetric key backend otherwise,
+// the backend to decrypt data is picked by the data header.
+type MultiBackendSecret struct {
+	Symmetric      Secret
+	Age            Secret
+	EncryptWithAge bool
+}
+
+func (s *MultiBackendSecret) Encrypt(data []byte) ([]byte, error) {
+	if s.EncryptWithAge {
+		return s.Age.Encrypt(data)
+	}
+
+	return s.Symmetric.Encrypt(data)
+}
+
+func (s *MultiBackendSecret) Decrypt(data []byte) ([]byte, error) {
+	if IsAgeEncryptedData(data) {
+		return s.Age.Decrypt(data)
+	}
+
+	return s.Symmetric.Decrypt(data)
+}
+
+// EnvSecret encrypts data with the environment secret when EncryptWithEnv is set and with the common secret otherwise,
+// data tagged with the environment name is decrypted with the environment secret and other data with the common secret.
+type EnvSecret struct {
+	Common         Secret
+	Env            Secret
+	EnvName        string
+	EncryptWithEnv bool
+}
+
+func (s *EnvSecret) Encrypt(data []byte) ([]byte, error) {
+	if s.EncryptWithEnv {
+		return s.Env.Encrypt(data)
+	}
+
+	return s.Common.Encrypt(data)
+}
+
+func (s *EnvSecret) Decrypt(data []byte) ([]byte, error) {
+	if GetEncryptedDataKeyName(data) == s.EnvName {
+		return s.Env.Decrypt(data)
+	}
+
+	return s.Common.Decrypt(data)
+}
@@ -2,6 +2,8 @@ package storage
 
 type DeleteImageOptions struct {
 	RmiForce bool
+	// Quarantine moves the stage into the quarantine instead of deletion, only the repo stages storage supports it
+	Quarantine bool
 }
 
 type FilterStagesAndProcessRelatedDataOptions struct {
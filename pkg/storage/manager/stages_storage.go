@@ -231,29 +231,45 @@ func (m *StagesStorageManager) GetStagesByDigestFromStagesStorage(ctx context.Co
 }
 
 func (m *StagesStorageManager) CopySuitableByDigestStage(ctx context.Context, stageDesc *image.StageDescription, sourceStagesStorage, destinationStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime) (*image.StageDescription, error) {
+	newImageName := destinationStagesStorage.ConstructStageImageName(m.ProjectName, stageDesc.StageID.Digest, stageDesc.StageID.UniqueID)
+
+	_, isSourceRepo := sourceStagesStorage.(*storage.RepoStagesStorage)
+	destinationRepoStagesStorage, isDestinationRepo := destinationStagesStorage.(*storage.RepoStagesStorage)
+	if isSourceRepo && isDestinationRepo {
+		logboek.Context(ctx).Info().LogF("Copying %s to %s\n", stageDesc.Info.Name, newImageName)
+		if err := destinationRepoStagesStorage.CopyImage(ctx, stageDesc.Info.Name, newImageName); err != nil {
+			return nil, fmt.Errorf("unable to copy %s from %s to %s: %s", stageDesc.Info.Name, sourceStagesStorage.String(), destinationStagesStorage.String(), err)
+		}
+	} else if err := copyStageImageWithContainerRuntime(ctx, stageDesc, newImageName, sourceStagesStorage, destinationStagesStorage, containerRuntime); err != nil {
+		return nil, err
+	}
+
+	if destinationStageDesc, err := getStageDescription(ctx, m.ProjectName, *stageDesc.StageID, destinationStagesStorage, getStageDescriptionOptions{StageShouldExist: true, WithManifestCache: m.getWithManifestCacheOption()}); err != nil {
+		return nil, fmt.Errorf("unable to get stage %s description from %s: %s", stageDesc.StageID.String(), destinationStagesStorage.String(), err)
+	} else {
+		return destinationStageDesc, nil
+	}
+}
+
+func copyStageImageWithContainerRuntime(ctx context.Context, stageDesc *image.StageDescription, newImageName string, sourceStagesStorage, destinationStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime) error {
 	img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(*container_runtime.LocalDockerServerRuntime))
 
 	logboek.Context(ctx).Info().LogF("Fetching %s\n", img.Name())
 	if err := sourceStagesStorage.FetchImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-		return nil, fmt.Errorf("unable to fetch %s from %s: %s", stageDesc.Info.Name, sourceStagesStorage.String(), err)
+		return fmt.Errorf("unable to fetch %s from %s: %s", stageDesc.Info.Name, sourceStagesStorage.String(), err)
 	}
 
-	newImageName := destinationStagesStorage.ConstructStageImageName(m.ProjectName, stageDesc.StageID.Digest, stageDesc.StageID.UniqueID)
 	logboek.Context(ctx).Info().LogF("Renaming image %s to %s\n", img.Name(), newImageName)
 	if err := containerRuntime.RenameImage(ctx, &container_runtime.DockerImage{Image: img}, newImageName, false); err != nil {
-		return nil, err
+		return err
 	}
 
 	logboek.Context(ctx).Info().LogF("Storing %s\n", newImageName)
 	if err := destinationStagesStorage.StoreImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-		return nil, fmt.Errorf("unable to store %s to %s: %s", stageDesc.Info.Name, destinationStagesStorage.String(), err)
+		return fmt.Errorf("unable to store %s to %s: %s", stageDesc.Info.Name, destinationStagesStorage.String(), err)
 	}
 
-	if destinationStageDesc, err := getStageDescription(ctx, m.ProjectName, *stageDesc.StageID, destinationStagesStorage, getStageDescriptionOptions{StageShouldExist: true, WithManifestCache: m.getWithManifestCacheOption()}); err != nil {
-		return nil, fmt.Errorf("unable to get stage %s description from %s: %s", stageDesc.StageID.String(), destinationStagesStorage.String(), err)
-	} else {
-		return destinationStageDesc, nil
-	}
+	return nil
 }
 
 func (m *StagesStorageManager) getWithManifestCacheOption() bool {
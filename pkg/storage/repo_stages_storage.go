@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang/example/stringutil"
 
@@ -31,6 +32,14 @@ const (
 	RepoClientIDRecrod_ImageTagPrefix  = "client-id-"
 	RepoClientIDRecrod_ImageNameFormat = "%s:client-id-%s-%d"
 
+	RepoQuarantinedStage_ImageTagPrefix  = "quarantine-"
+	RepoQuarantinedStage_ImageNameFormat = "%s:quarantine-%s-%s"
+	RepoQuarantinedStage_DateFormat      = "20060102"
+
+	QuarantinedStageIDAnnotation = "werf.io/quarantined-stage-id"
+	QuarantinedAtAnnotation      = "werf.io/quarantined-at"
+	RestoredAtAnnotation         = "werf.io/restored-at"
+
 	UnexpectedTagFormatErrorPrefix = "unexpected tag format"
 )
 
@@ -48,6 +57,24 @@ func getDigestAndUniqueIDFromRepoStageImageTag(repoStageImageTag string) (string
 	}
 }
 
+// IsRepoServiceTag returns true for the stage tags and the werf service records tags (metadata, managed images, etc.)
+func IsRepoServiceTag(tag string) bool {
+	for _, prefix := range []string{
+		RepoManagedImageRecord_ImageTagPrefix,
+		RepoImageMetadataByCommitRecord_ImageTagPrefix,
+		RepoImportMetadata_ImageTagPrefix,
+		RepoClientIDRecrod_ImageTagPrefix,
+		RepoQuarantinedStage_ImageTagPrefix,
+	} {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+
+	_, _, err := getDigestAndUniqueIDFromRepoStageImageTag(tag)
+	return err == nil
+}
+
 func isUnexpectedTagFormatError(err error) bool {
 	return strings.HasPrefix(err.Error(), UnexpectedTagFormatErrorPrefix)
 }
@@ -56,11 +83,13 @@ type RepoStagesStorage struct {
 	RepoAddress      string
 	DockerRegistry   docker_registry.DockerRegistry
 	ContainerRuntime container_runtime.ContainerRuntime
+	LayerCompression docker_registry.LayerCompression
 }
 
 type RepoStagesStorageOptions struct {
 	docker_registry.DockerRegistryOptions
-	Implementation string
+	Implementation   string
+	LayerCompression docker_registry.LayerCompression
 }
 
 func NewRepoStagesStorage(repoAddress string, containerRuntime container_runtime.ContainerRuntime, options RepoStagesStorageOptions) (*RepoStagesStorage, error) {
@@ -71,10 +100,16 @@ func NewRepoStagesStorage(repoAddress string, containerRuntime container_runtime
 		return nil, fmt.Errorf("error creating docker registry accessor for repo %q: %s", repoAddress, err)
 	}
 
+	layerCompression := options.LayerCompression
+	if layerCompression == "" {
+		layerCompression = docker_registry.GzipLayerCompression
+	}
+
 	return &RepoStagesStorage{
 		RepoAddress:      repoAddress,
 		DockerRegistry:   dockerRegistry,
 		ContainerRuntime: containerRuntime,
+		LayerCompression: layerCompression,
 	}, nil
 }
 
@@ -91,7 +126,7 @@ func (storage *RepoStagesStorage) GetStagesIDs(ctx context.Context, projectName
 		logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetRepoImagesByDigest fetched tags for %q: %#v\n", storage.RepoAddress, tags)
 
 		for _, tag := range tags {
-			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageMetadataByCommitRecord_ImageTagPrefix) {
+			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageMetadataByCommitRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoQuarantinedStage_ImageTagPrefix) {
 				continue
 			}
 
@@ -112,10 +147,137 @@ func (storage *RepoStagesStorage) GetStagesIDs(ctx context.Context, projectName
 	}
 }
 
-func (storage *RepoStagesStorage) DeleteStage(ctx context.Context, stageDescription *image.StageDescription, _ DeleteImageOptions) error {
+func (storage *RepoStagesStorage) DeleteStage(ctx context.Context, stageDescription *image.StageDescription, options DeleteImageOptions) error {
+	if options.Quarantine {
+		return storage.QuarantineStage(ctx, stageDescription)
+	}
+
+	return storage.DockerRegistry.DeleteRepoImage(ctx, stageDescription.Info)
+}
+
+type QuarantinedStage struct {
+	StageID       string
+	QuarantinedAt time.Time
+	ImageName     string
+}
+
+// QuarantineStage retags the stage as quarantine-<date>-<stageID> and deletes the stage tag.
+// The quarantined image gets the manifest annotations to have another digest, so the stage manifest can be deleted.
+// The quarantine time annotation makes the manifest unique, so deleting the expired quarantined image of the restored
+// and quarantined again stage does not delete the newer quarantined image.
+func (storage *RepoStagesStorage) QuarantineStage(ctx context.Context, stageDescription *image.StageDescription) error {
+	now := time.Now().UTC()
+	quarantinedImageName := fmt.Sprintf(RepoQuarantinedStage_ImageNameFormat, storage.RepoAddress, now.Format(RepoQuarantinedStage_DateFormat), stageDescription.Info.Tag)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.QuarantineStage %s -> %s\n", stageDescription.Info.Name, quarantinedImageName)
+
+	annotations := map[string]string{
+		QuarantinedStageIDAnnotation: stageDescription.Info.Tag,
+		QuarantinedAtAnnotation:      now.Format(time.RFC3339Nano),
+	}
+	if err := storage.DockerRegistry.CopyImageWithAnnotations(ctx, stageDescription.Info.Name, quarantinedImageName, annotations); err != nil {
+		return fmt.Errorf("unable to copy %s to %s: %s", stageDescription.Info.Name, quarantinedImageName, err)
+	}
+
 	return storage.DockerRegistry.DeleteRepoImage(ctx, stageDescription.Info)
 }
 
+func (storage *RepoStagesStorage) GetQuarantinedStages(ctx context.Context) ([]*QuarantinedStage, error) {
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get repo %s tags: %s", storage.RepoAddress, err)
+	}
+
+	var res []*QuarantinedStage
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoQuarantinedStage_ImageTagPrefix) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(tag, RepoQuarantinedStage_ImageTagPrefix), "-", 2)
+		if len(parts) != 2 {
+			logboek.Context(ctx).Debug().LogF("%s %s\n", UnexpectedTagFormatErrorPrefix, tag)
+			continue
+		}
+
+		quarantinedAt, err := time.Parse(RepoQuarantinedStage_DateFormat, parts[0])
+		if err != nil {
+			logboek.Context(ctx).Debug().LogF("%s %s: unable to parse date %s: %s\n", UnexpectedTagFormatErrorPrefix, tag, parts[0], err)
+			continue
+		}
+
+		res = append(res, &QuarantinedStage{
+			StageID:       parts[1],
+			QuarantinedAt: quarantinedAt,
+			ImageName:     strings.Join([]string{storage.RepoAddress, tag}, ":"),
+		})
+	}
+
+	return res, nil
+}
+
+func (storage *RepoStagesStorage) DeleteQuarantinedStage(ctx context.Context, quarantinedStage *QuarantinedStage) error {
+	imgInfo, err := storage.DockerRegistry.TryGetRepoImage(ctx, quarantinedStage.ImageName)
+	if err != nil {
+		return fmt.Errorf("unable to get repo image %s: %s", quarantinedStage.ImageName, err)
+	} else if imgInfo == nil {
+		return nil
+	}
+
+	return storage.DockerRegistry.DeleteRepoImage(ctx, imgInfo)
+}
+
+// RestoreStage retags the latest quarantined image of the stage back and deletes the quarantined image.
+// The restored stage has the same image ID, but the manifest digest may differ from the original one.
+// The restored stage is marked with the werf.io/restored-at annotation, so that cleanup keeps it.
+func (storage *RepoStagesStorage) RestoreStage(ctx context.Context, projectName, stageID string) (*image.StageDescription, error) {
+	digest, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(stageID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stage ID %q: %s", stageID, err)
+	}
+
+	if stageDesc, err := storage.GetStageDescription(ctx, projectName, digest, uniqueID); err != nil {
+		return nil, err
+	} else if stageDesc != nil {
+		return nil, fmt.Errorf("stage %s already exists in the repo %s", stageID, storage.RepoAddress)
+	}
+
+	quarantinedStages, err := storage.GetQuarantinedStages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var quarantinedStage *QuarantinedStage
+	for _, s := range quarantinedStages {
+		if s.StageID == stageID && (quarantinedStage == nil || s.QuarantinedAt.After(quarantinedStage.QuarantinedAt)) {
+			quarantinedStage = s
+		}
+	}
+
+	if quarantinedStage == nil {
+		return nil, fmt.Errorf("stage %s is not found in the quarantine of the repo %s", stageID, storage.RepoAddress)
+	}
+
+	stageImageName := storage.ConstructStageImageName(projectName, digest, uniqueID)
+	if err := storage.DockerRegistry.CopyImageWithAnnotations(ctx, quarantinedStage.ImageName, stageImageName, map[string]string{
+		RestoredAtAnnotation: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return nil, fmt.Errorf("unable to copy %s to %s: %s", quarantinedStage.ImageName, stageImageName, err)
+	}
+
+	if err := storage.DeleteQuarantinedStage(ctx, quarantinedStage); err != nil {
+		return nil, fmt.Errorf("unable to delete quarantined image %s: %s", quarantinedStage.ImageName, err)
+	}
+
+	stageDesc, err := storage.GetStageDescription(ctx, projectName, digest, uniqueID)
+	if err != nil {
+		return nil, err
+	} else if stageDesc == nil {
+		return nil, fmt.Errorf("restored stage %s is not found in the repo %s", stageID, storage.RepoAddress)
+	}
+
+	return stageDesc, nil
+}
+
 func (storage *RepoStagesStorage) FilterStagesAndProcessRelatedData(_ context.Context, stageDescriptions []*image.StageDescription, _ FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error) {
 	return stageDescriptions, nil
 }
@@ -261,6 +423,23 @@ func (storage *RepoStagesStorage) StoreImage(ctx context.Context, img container_
 	case *container_runtime.LocalDockerServerRuntime:
 		dockerImage := img.(*container_runtime.DockerImage)
 
+		if storage.LayerCompression != docker_registry.GzipLayerCompression {
+			if err := containerRuntime.TagImageByName(ctx, img); err != nil {
+				return err
+			}
+
+			if err := logboek.Context(ctx).Info().LogProcess("Pushing %s with %s layers", dockerImage.Image.Name(), storage.LayerCompression).DoError(func() error {
+				return storage.DockerRegistry.PushDockerImage(ctx, dockerImage.Image.Name(), storage.LayerCompression)
+			}); err == nil {
+				return nil
+			} else if !docker_registry.IsLayerCompressionUnsupportedError(err) {
+				return err
+			} else {
+				logboek.Context(ctx).Warn().LogF("WARNING: %s: falling back to %s layers\n", err, docker_registry.GzipLayerCompression)
+				return containerRuntime.PushImage(ctx, img)
+			}
+		}
+
 		if dockerImage.Image.GetBuiltId() != "" {
 			return containerRuntime.PushBuiltImage(ctx, img)
 		} else {
@@ -273,6 +452,11 @@ func (storage *RepoStagesStorage) StoreImage(ctx context.Context, img container_
 	}
 }
 
+// CopyImage copies the image from another repo registry-to-registry without the container runtime
+func (storage *RepoStagesStorage) CopyImage(ctx context.Context, sourceImageName, imageName string) error {
+	return storage.DockerRegistry.CopyImage(ctx, sourceImageName, imageName)
+}
+
 func (storage *RepoStagesStorage) ShouldFetchImage(_ context.Context, img container_runtime.Image) (bool, error) {
 	switch storage.ContainerRuntime.(type) {
 	case *container_runtime.LocalDockerServerRuntime:
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+)
+
+// newRegistryServer runs the in-memory registry which deletes manifests by digest with all their tags and lists the tags
+func newRegistryServer() *httptest.Server {
+	registryHandler := registry.New()
+	deletedDigests := map[string]bool{}
+	tagsDigests := map[string]string{}
+	var mux sync.Mutex
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tags/list") {
+			mux.Lock()
+			defer mux.Unlock()
+
+			tags := []string{}
+			for tag, digest := range tagsDigests {
+				if !deletedDigests[digest] {
+					tags = append(tags, tag)
+				}
+			}
+			sort.Strings(tags)
+
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "repo", "tags": tags})
+			return
+		}
+
+		if !strings.Contains(r.URL.Path, "/manifests/") {
+			registryHandler.ServeHTTP(w, r)
+			return
+		}
+
+		mux.Lock()
+		defer mux.Unlock()
+
+		if r.Method == http.MethodDelete {
+			deletedDigests[path.Base(r.URL.Path)] = true
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		registryHandler.ServeHTTP(rec, r)
+
+		digest := rec.Header().Get("Docker-Content-Digest")
+		if r.Method == http.MethodPut {
+			delete(deletedDigests, digest)
+			if reference := path.Base(r.URL.Path); !strings.HasPrefix(reference, "sha256:") {
+				tagsDigests[reference] = digest
+			}
+		} else if deletedDigests[digest] {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+			return
+		}
+
+		for key, values := range rec.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+	}))
+}
+
+func TestQuarantineStageKeepsNewerQuarantinedImage(t *testing.T) {
+	server := newRegistryServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	repoAddress := strings.TrimPrefix(server.URL, "http://") + "/repo"
+	stageTag := "7e5bd2af-1611111111111"
+
+	dockerRegistry, err := docker_registry.NewDockerRegistry(repoAddress, docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &RepoStagesStorage{RepoAddress: repoAddress, DockerRegistry: dockerRegistry}
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageRef, err := name.ParseReference(fmt.Sprintf("%s:%s", repoAddress, stageTag), name.Insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(stageRef, img); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stage has been quarantined and restored before
+	expiredQuarantinedImageName := fmt.Sprintf(RepoQuarantinedStage_ImageNameFormat, repoAddress, "20200101", stageTag)
+	if err := dockerRegistry.CopyImageWithAnnotations(ctx, stageRef.String(), expiredQuarantinedImageName, map[string]string{
+		QuarantinedStageIDAnnotation: stageTag,
+		QuarantinedAtAnnotation:      "2020-01-01T00:00:00Z",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stageInfo, err := dockerRegistry.GetRepoImage(ctx, stageRef.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.QuarantineStage(ctx, &image.StageDescription{Info: stageInfo}); err != nil {
+		t.Fatal(err)
+	}
+
+	expiredQuarantinedStage := &QuarantinedStage{StageID: stageTag, ImageName: expiredQuarantinedImageName}
+	quarantinedStage := &QuarantinedStage{StageID: stageTag, ImageName: fmt.Sprintf(RepoQuarantinedStage_ImageNameFormat, repoAddress, time.Now().UTC().Format(RepoQuarantinedStage_DateFormat), stageTag)}
+
+	quarantinedManifest, err := dockerRegistry.GetRepoImageManifest(ctx, quarantinedStage.ImageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quarantinedManifest.Annotations[QuarantinedAtAnnotation] == "" {
+		t.Errorf("quarantined image %s has no %s annotation", quarantinedStage.ImageName, QuarantinedAtAnnotation)
+	}
+
+	if err := storage.DeleteQuarantinedStage(ctx, expiredQuarantinedStage); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := dockerRegistry.TryGetRepoImage(ctx, expiredQuarantinedStage.ImageName); err != nil {
+		t.Fatal(err)
+	} else if info != nil {
+		t.Errorf("expired quarantined image %s has not been deleted", expiredQuarantinedStage.ImageName)
+	}
+
+	if info, err := dockerRegistry.TryGetRepoImage(ctx, quarantinedStage.ImageName); err != nil {
+		t.Fatal(err)
+	} else if info == nil {
+		t.Errorf("quarantined image %s has been deleted with the expired one", quarantinedStage.ImageName)
+	}
+}
+
+func TestRestoreStageMarksRestoredStage(t *testing.T) {
+	server := newRegistryServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	repoAddress := strings.TrimPrefix(server.URL, "http://") + "/repo"
+	stageTag := "7e5bd2af-1611111111111"
+
+	dockerRegistry, err := docker_registry.NewDockerRegistry(repoAddress, docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &RepoStagesStorage{RepoAddress: repoAddress, DockerRegistry: dockerRegistry}
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageRef, err := name.ParseReference(fmt.Sprintf("%s:%s", repoAddress, stageTag), name.Insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(stageRef, img); err != nil {
+		t.Fatal(err)
+	}
+
+	stageInfo, err := dockerRegistry.GetRepoImage(ctx, stageRef.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.QuarantineStage(ctx, &image.StageDescription{Info: stageInfo}); err != nil {
+		t.Fatal(err)
+	}
+
+	stageDesc, err := storage.RestoreStage(ctx, "project", stageTag)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stageDesc.Info.ID != stageInfo.ID {
+		t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", stageInfo.ID, stageDesc.Info.ID)
+	}
+
+	if _, err := time.Parse(time.RFC3339, stageDesc.Info.Annotations[RestoredAtAnnotation]); err != nil {
+		t.Errorf("restored stage %s has no valid %s annotation: %v", stageDesc.Info.Name, RestoredAtAnnotation, stageDesc.Info.Annotations)
+	}
+
+	if quarantinedStages, err := storage.GetQuarantinedStages(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(quarantinedStages) != 0 {
+		t.Errorf("quarantined image %s has not been deleted", quarantinedStages[0].ImageName)
+	}
+}
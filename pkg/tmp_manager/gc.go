@@ -14,6 +14,8 @@ import (
 
 	"github.com/werf/logboek"
 
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/werf"
 )
@@ -142,6 +144,22 @@ func gc(ctx context.Context, dryRun bool) error {
 		}
 	}
 
+	if image.CommonManifestCache != nil {
+		if err := image.CommonManifestCache.GC(ctx, dryRun); err != nil {
+			removeErrors = append(removeErrors, fmt.Errorf("manifest cache gc failed: %s", err))
+		}
+	}
+
+	if image.CommonTagsCache != nil {
+		if err := image.CommonTagsCache.GC(ctx, dryRun); err != nil {
+			removeErrors = append(removeErrors, fmt.Errorf("tags cache gc failed: %s", err))
+		}
+	}
+
+	if err := docker_registry.GetCompressedLayersCache().GC(ctx, dryRun); err != nil {
+		removeErrors = append(removeErrors, fmt.Errorf("compressed layers cache gc failed: %s", err))
+	}
+
 	if len(removeErrors) > 0 {
 		msg := ""
 		for _, err := range removeErrors {
@@ -53,8 +53,72 @@ func WithWorkTree(ctx context.Context, gitDir, workTreeCacheDir string, commit s
 }
 
 func withWorkTreeCacheLock(ctx context.Context, workTreeCacheDir string, f func() error) error {
-	lockName := fmt.Sprintf("git_work_tree_cache %s", workTreeCacheDir)
-	return werf.WithHostLock(ctx, lockName, lockgate.AcquireOptions{Timeout: 600 * time.Second}, f)
+	return werf.WithHostLock(ctx, workTreeCacheLockName(workTreeCacheDir), lockgate.AcquireOptions{Timeout: 600 * time.Second}, func() error {
+		if err := touchWorkTreeCacheDir(workTreeCacheDir); err != nil {
+			return err
+		}
+
+		return f()
+	})
+}
+
+func workTreeCacheLockName(workTreeCacheDir string) string {
+	return fmt.Sprintf("git_work_tree_cache %s", workTreeCacheDir)
+}
+
+// touchWorkTreeCacheDir updates the modification time of the work tree cache dir, which is used as the last access time by host cleanup
+func touchWorkTreeCacheDir(workTreeCacheDir string) error {
+	if err := os.MkdirAll(workTreeCacheDir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create dir %s: %s", workTreeCacheDir, err)
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(workTreeCacheDir, now, now); err != nil {
+		return fmt.Errorf("unable to update access time of %s: %s", workTreeCacheDir, err)
+	}
+
+	return nil
+}
+
+// TryRemoveWorkTreeCache removes the work tree cache dir and prunes the work tree in the repo unless the cache is locked by another process
+func TryRemoveWorkTreeCache(ctx context.Context, workTreeCacheDir string, dryRun bool) (bool, error) {
+	lockName := workTreeCacheLockName(workTreeCacheDir)
+	isAcquired, lock, err := werf.AcquireHostLock(ctx, lockName, lockgate.AcquireOptions{NonBlocking: true})
+	if err != nil {
+		return false, fmt.Errorf("cannot acquire %s host lock: %s", lockName, err)
+	}
+
+	if !isAcquired {
+		logboek.Context(ctx).Default().LogFDetails("Ignore work tree cache %s used by another process\n", workTreeCacheDir)
+		return false, nil
+	}
+	defer werf.ReleaseHostLock(lock)
+
+	if dryRun {
+		return true, nil
+	}
+
+	var repoDir string
+	if data, err := ioutil.ReadFile(filepath.Join(workTreeCacheDir, "git_dir")); err == nil {
+		repoDir = strings.TrimSpace(string(data))
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("error reading %s: %s", filepath.Join(workTreeCacheDir, "git_dir"), err)
+	}
+
+	if err := os.RemoveAll(workTreeCacheDir); err != nil {
+		return false, fmt.Errorf("unable to remove %s: %s", workTreeCacheDir, err)
+	}
+
+	// the repo may be already removed, so the stale work tree is pruned on the best effort basis
+	if repoDir != "" {
+		if _, err := os.Stat(repoDir); err == nil {
+			if _, err := runGitCmd(ctx, []string{"worktree", "prune"}, repoDir, runGitCmdOptions{}); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: unable to prune work trees of repo %s: %s\n", repoDir, err)
+			}
+		}
+	}
+
+	return true, nil
 }
 
 func prepareWorkTree(ctx context.Context, repoDir, workTreeCacheDir string, commit string, withSubmodules bool) (string, error) {
@@ -0,0 +1,209 @@
+package secretvalues
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	highEntropyStringMinLength = 20
+	// Longer strings are mostly encoded certificates and binary data rather than tokens and passwords
+	highEntropyStringMaxLength = 128
+	// The entropy of a random string is close to log2 of its length (for lengths smaller than the alphabet size)
+	highEntropyStringRatio = 0.85
+)
+
+var knownSecretPatterns = []struct {
+	Description string
+	Regexp      *regexp.Regexp
+}{
+	{Description: "AWS access key ID", Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Description: "GitHub token", Regexp: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{Description: "GitLab token", Regexp: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{Description: "Slack token", Regexp: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{Description: "private key", Regexp: regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+}
+
+// PlaintextSecret is a string that looks like a secret but is not a secret value,
+// the location is the source followed by the path of the value, e.g. values.yaml:db.password.
+type PlaintextSecret struct {
+	Location    string
+	Description string
+	Value       string
+}
+
+func (s PlaintextSecret) String() string {
+	return fmt.Sprintf("%s: %s %s", s.Location, s.Description, maskPlaintextSecretValue(s.Value))
+}
+
+func maskPlaintextSecretValue(value string) string {
+	if len(value) <= 8 {
+		return "***"
+	}
+
+	return value[:4] + "***"
+}
+
+type PlaintextSecretsDetector struct {
+	secretValues []string
+}
+
+// NewPlaintextSecretsDetector creates the detector which ignores the strings contained in the passed secret values.
+func NewPlaintextSecretsDetector(secretValues []string) *PlaintextSecretsDetector {
+	return &PlaintextSecretsDetector{secretValues: secretValues}
+}
+
+// Detect walks through the maps and slices of the data and inspects all the scalar values,
+// the source is used as the location prefix of the found plaintext secrets.
+func (d *PlaintextSecretsDetector) Detect(source string, data interface{}) []PlaintextSecret {
+	var res []PlaintextSecret
+	d.detect(source, "", data, &res)
+	return res
+}
+
+func (d *PlaintextSecretsDetector) detect(source, path string, data interface{}, res *[]PlaintextSecret) {
+	if data == nil {
+		return
+	}
+
+	switch reflect.TypeOf(data).Kind() {
+	case reflect.Slice, reflect.Array:
+		elem := reflect.ValueOf(data)
+		for i := 0; i < elem.Len(); i++ {
+			d.detect(source, fmt.Sprintf("%s[%d]", path, i), elem.Index(i).Interface(), res)
+		}
+	case reflect.Map:
+		elem := reflect.ValueOf(data)
+
+		var keys []string
+		values := map[string]interface{}{}
+		for _, key := range elem.MapKeys() {
+			keyStr := fmt.Sprintf("%v", key.Interface())
+			keys = append(keys, keyStr)
+			values[keyStr] = elem.MapIndex(key).Interface()
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+
+			d.detect(source, keyPath, values[key], res)
+		}
+	case reflect.String:
+		for _, secret := range d.DetectInString(data.(string)) {
+			secret.Location = fmt.Sprintf("%s:%s", source, path)
+			*res = append(*res, secret)
+		}
+	}
+}
+
+// DetectInString returns plaintext secrets found in the string without location.
+func (d *PlaintextSecretsDetector) DetectInString(s string) []PlaintextSecret {
+	var res []PlaintextSecret
+
+	var isPatternMatched bool
+	for _, pattern := range knownSecretPatterns {
+		for _, match := range pattern.Regexp.FindAllString(s, -1) {
+			isPatternMatched = true
+
+			if d.isSecretValue(match) {
+				continue
+			}
+
+			res = append(res, PlaintextSecret{Description: pattern.Description, Value: match})
+		}
+	}
+
+	if isPatternMatched {
+		return res
+	}
+
+	for _, token := range strings.FieldsFunc(s, isNotTokenRune) {
+		if !IsHighEntropyString(token) || d.isSecretValue(token) {
+			continue
+		}
+
+		res = append(res, PlaintextSecret{Description: "high entropy string", Value: token})
+	}
+
+	return res
+}
+
+func (d *PlaintextSecretsDetector) isSecretValue(s string) bool {
+	for _, secretValue := range d.secretValues {
+		if strings.Contains(secretValue, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isNotTokenRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case r == '+', r == '/', r == '_', r == '-':
+		return false
+	}
+
+	return true
+}
+
+// IsHighEntropyString returns true for random looking strings (tokens, passwords, keys):
+// the string should contain upper and lower case letters and digits, should not be a hex string (e.g. digest or uuid) or a path,
+// and its Shannon entropy should be close to the maximum for the string length.
+func IsHighEntropyString(s string) bool {
+	if len(s) < highEntropyStringMinLength || len(s) > highEntropyStringMaxLength {
+		return false
+	}
+
+	if strings.HasPrefix(s, "/") || strings.Contains(s, "//") {
+		return false
+	}
+
+	var hasUpper, hasLower, hasDigit, isHex = false, false, false, true
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+
+		if !strings.ContainsRune("0123456789abcdefABCDEF-", r) {
+			isHex = false
+		}
+	}
+
+	if isHex || !hasUpper || !hasLower || !hasDigit {
+		return false
+	}
+
+	return shannonEntropy(s) >= highEntropyStringRatio*math.Log2(float64(len(s)))
+}
+
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
@@ -1,5 +1,7 @@
 package util
 
+import "strings"
+
 func UniqStrings(arr []string) []string {
 	res := []string{}
 
@@ -74,3 +76,17 @@ outerLoop:
 
 	return list
 }
+
+// SplitLines splits the string into lines keeping the line endings (the form expected by difflib).
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		lines = append(lines, line+"\n")
+	}
+
+	return lines
+}